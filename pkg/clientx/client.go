@@ -187,6 +187,11 @@ func (e *HTTPError) Error() string {
 	return fmt.Sprintf("%s %s failed: status %d, body: %q", e.Method, e.URL, e.StatusCode, e.Body)
 }
 
+// Unwrap returns the underlying error, so errors.Is/As can match timeouts and cancellations
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
 // Request core request function, supports retry, backoff, middleware, buffer pool
 // ctx: context, can be used for cancellation or timeout
 // method: HTTP method, such as GET, POST, PUT, etc.
@@ -276,9 +281,13 @@ func Request(ctx context.Context, method, urlStr string, body []byte, opts ...Op
 			}
 		}
 
-		// Record the last error
+		// Record the last error, keep the 5xx status code so callers can classify it
+		statusCode := 0
+		if resp != nil {
+			statusCode = resp.StatusCode
+		}
 		lastErr = &HTTPError{
-			StatusCode: 0,
+			StatusCode: statusCode,
 			Method:     method,
 			URL:        urlStr,
 			Body:       bodyBytes,
@@ -3,6 +3,7 @@ package htmlx
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -109,6 +110,7 @@ func ToFormFields(data interface{}) []FormField {
 
 // MapSet 将 map[string]any 中的值设置到任意 struct 上。
 // 支持字段类型：string、int、float、bool。
+// int、float、bool 字段同时兼容字符串形式的值（前端表单统一以字符串提交）。
 // 对于 string 类型字段，如果 map 中没有提供值且字段原始值为空，
 // 会读取字段 ui tag 中的 default 作为默认值。
 func MapSet(v any, maps map[string]any) error {
@@ -160,16 +162,31 @@ func MapSet(v any, maps map[string]any) error {
 				} else if i, ok := val.(int); ok {
 					fieldVal.SetInt(int64(i))
 					hasValue = true
+				} else if s, ok := val.(string); ok && s != "" { // 前端表单提交的均为字符串
+					if i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+						fieldVal.SetInt(i)
+						hasValue = true
+					}
 				}
 			case reflect.Bool:
 				if b, ok := val.(bool); ok {
 					fieldVal.SetBool(b)
 					hasValue = true
+				} else if s, ok := val.(string); ok && s != "" {
+					if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+						fieldVal.SetBool(b)
+						hasValue = true
+					}
 				}
 			case reflect.Float32, reflect.Float64:
 				if f, ok := val.(float64); ok {
 					fieldVal.SetFloat(f)
 					hasValue = true
+				} else if s, ok := val.(string); ok && s != "" {
+					if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+						fieldVal.SetFloat(f)
+						hasValue = true
+					}
 				}
 			}
 		}
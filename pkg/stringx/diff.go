@@ -0,0 +1,98 @@
+package stringx
+
+import "strings"
+
+// 行差异类型
+const (
+	DiffEqual  = "="
+	DiffInsert = "+"
+	DiffDelete = "-"
+)
+
+// diffMaxCells 最长公共子序列表格的最大单元格数，超过时按整体删除再新增输出，避免超长内容占用过多内存
+const diffMaxCells = 4 << 20
+
+// DiffLine 一行差异，Op 为 =、+、-
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffLines 按行比较 a 与 b，基于最长公共子序列输出由 a 变为 b 的逐行差异
+func DiffLines(a, b string) []DiffLine {
+	x, y := splitLines(a), splitLines(b)
+	// 去掉相同的首尾行，缩小比较范围
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+	lines := make([]DiffLine, 0, len(x)+len(y))
+	for _, text := range x[:prefix] {
+		lines = append(lines, DiffLine{Op: DiffEqual, Text: text})
+	}
+	lines = append(lines, diffMiddle(x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])...)
+	for _, text := range x[len(x)-suffix:] {
+		lines = append(lines, DiffLine{Op: DiffEqual, Text: text})
+	}
+	return lines
+}
+
+func diffMiddle(x, y []string) []DiffLine {
+	lines := make([]DiffLine, 0, len(x)+len(y))
+	if (len(x)+1)*(len(y)+1) > diffMaxCells {
+		for _, text := range x {
+			lines = append(lines, DiffLine{Op: DiffDelete, Text: text})
+		}
+		for _, text := range y {
+			lines = append(lines, DiffLine{Op: DiffInsert, Text: text})
+		}
+		return lines
+	}
+	// lcs[i][j] 为 x[i:] 与 y[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			lines = append(lines, DiffLine{Op: DiffEqual, Text: x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Op: DiffDelete, Text: x[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: DiffInsert, Text: y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		lines = append(lines, DiffLine{Op: DiffDelete, Text: x[i]})
+	}
+	for ; j < len(y); j++ {
+		lines = append(lines, DiffLine{Op: DiffInsert, Text: y[j]})
+	}
+	return lines
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
@@ -0,0 +1,68 @@
+package stringx
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	eq := func(text string) DiffLine { return DiffLine{Op: DiffEqual, Text: text} }
+	ins := func(text string) DiffLine { return DiffLine{Op: DiffInsert, Text: text} }
+	del := func(text string) DiffLine { return DiffLine{Op: DiffDelete, Text: text} }
+	tests := []struct {
+		name string
+		a, b string
+		want []DiffLine
+	}{
+		{"both empty", "", "", []DiffLine{}},
+		{"identical", "a\nb", "a\nb", []DiffLine{eq("a"), eq("b")}},
+		{"from empty", "", "a\nb", []DiffLine{ins("a"), ins("b")}},
+		{"to empty", "a\nb", "", []DiffLine{del("a"), del("b")}},
+		{"append", "a\nb", "a\nb\nc", []DiffLine{eq("a"), eq("b"), ins("c")}},
+		{"prepend", "b\nc", "a\nb\nc", []DiffLine{ins("a"), eq("b"), eq("c")}},
+		{"replace middle", "a\nb\nc", "a\nx\nc", []DiffLine{eq("a"), del("b"), ins("x"), eq("c")}},
+		{"delete middle", "a\nb\nc\nd", "a\nd", []DiffLine{eq("a"), del("b"), del("c"), eq("d")}},
+		{"keep common lines", "a\nb\nc\nd\ne", "x\nb\nd\ny", []DiffLine{del("a"), ins("x"), eq("b"), del("c"), eq("d"), del("e"), ins("y")}},
+		{"crlf", "a\r\nb", "a\nb", []DiffLine{eq("a"), eq("b")}},
+		{"trailing newline", "a", "a\n", []DiffLine{eq("a"), ins("")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffLines(tt.a, tt.b)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("DiffLines(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDiffLinesLarge 超出比较表格上限时按整体删除再新增输出，相同的首尾行仍保留
+func TestDiffLinesLarge(t *testing.T) {
+	const n = 2100
+	x, y := make([]string, n), make([]string, n)
+	for i := range n {
+		x[i], y[i] = fmt.Sprintf("a%d", i), fmt.Sprintf("b%d", i)
+	}
+	a := "head\n" + strings.Join(x, "\n") + "\ntail"
+	b := "head\n" + strings.Join(y, "\n") + "\ntail"
+	got := DiffLines(a, b)
+	if len(got) != 2*n+2 {
+		t.Fatalf("got %d lines, want %d", len(got), 2*n+2)
+	}
+	if got[0] != (DiffLine{Op: DiffEqual, Text: "head"}) || got[len(got)-1] != (DiffLine{Op: DiffEqual, Text: "tail"}) {
+		t.Errorf("head/tail = %v/%v", got[0], got[len(got)-1])
+	}
+	for i, line := range got[1 : len(got)-1] {
+		var want DiffLine
+		if i < n {
+			want = DiffLine{Op: DiffDelete, Text: x[i]}
+		} else {
+			want = DiffLine{Op: DiffInsert, Text: y[i-n]}
+		}
+		if line != want {
+			t.Fatalf("line %d = %v, want %v", i+1, line, want)
+		}
+	}
+}
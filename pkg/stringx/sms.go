@@ -0,0 +1,40 @@
+package stringx
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	smsSingleLength = 70 // 单条短信最大字数
+	smsLongLength   = 67 // 长短信拆分后每条的字数
+)
+
+// SmsSegments 按国内运营商规则计算短信字数与计费条数：
+// 字数含签名，中英文、数字与符号均按 1 个字计算；不超过 70 字为 1 条，超过时按每条 67 字拆分
+func SmsSegments(content string) (length int, segments int) {
+	length = utf8.RuneCountInString(content)
+	switch {
+	case length == 0:
+		return 0, 0
+	case length <= smsSingleLength:
+		return length, 1
+	}
+	return length, (length + smsLongLength - 1) / smsLongLength
+}
+
+// NormalizeMobile 去除手机号中的非数字字符及国内区号前缀（+86、0086、86），便于不同格式的号码精确比较
+func NormalizeMobile(mobile string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, mobile)
+	for _, prefix := range []string{"0086", "86"} {
+		if rest, ok := strings.CutPrefix(digits, prefix); ok && len(rest) == 11 {
+			return rest
+		}
+	}
+	return digits
+}
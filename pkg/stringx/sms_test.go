@@ -0,0 +1,24 @@
+package stringx
+
+import "testing"
+
+func TestNormalizeMobile(t *testing.T) {
+	tests := []struct {
+		mobile string
+		want   string
+	}{
+		{"13800000000", "13800000000"},
+		{"+8613800000000", "13800000000"},
+		{"8613800000000", "13800000000"},
+		{"008613800000000", "13800000000"},
+		{"+86 138-0000-0000", "13800000000"},
+		{"913800000000", "913800000000"},
+		{"+85261234567", "85261234567"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeMobile(tt.mobile); got != tt.want {
+			t.Errorf("NormalizeMobile(%q) = %q, want %q", tt.mobile, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package templatex
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"chihqiang/msgbox-go/pkg/timex"
+)
+
+type filterFunc func(v any, args []any) (any, error)
+
+// filterDef 过滤器及其参数个数范围
+type filterDef struct {
+	min, max int
+	fn       filterFunc
+}
+
+// filters 内置过滤器，仅做字符串与数值转换
+var filters = map[string]filterDef{
+	"default":  {1, 1, nil}, // 由 eval 处理，变量缺失或为空时使用参数
+	"upper":    {0, 0, stringFilter(strings.ToUpper)},
+	"lower":    {0, 0, stringFilter(strings.ToLower)},
+	"trim":     {0, 0, stringFilter(strings.TrimSpace)},
+	"escape":   {0, 0, stringFilter(html.EscapeString)},
+	"truncate": {1, 2, truncate},
+	"replace":  {2, 2, replace},
+	"date":     {0, 1, date},
+	"number":   {0, 2, number},
+	"join":     {0, 1, join},
+	"length":   {0, 0, length},
+}
+
+func stringFilter(fn func(string) string) filterFunc {
+	return func(v any, args []any) (any, error) {
+		return fn(toString(v)), nil
+	}
+}
+
+func intArg(args []any, i int, def int) (int, error) {
+	if i >= len(args) {
+		return def, nil
+	}
+	f, ok := toFloat(args[i])
+	if !ok || f != math.Trunc(f) {
+		return 0, fmt.Errorf("argument %d must be an integer", i+1)
+	}
+	return int(f), nil
+}
+
+func stringArg(args []any, i int, def string) string {
+	if i >= len(args) {
+		return def
+	}
+	return toString(args[i])
+}
+
+// truncate 按字符数截断，超出时追加后缀，默认后缀为 ...
+func truncate(v any, args []any) (any, error) {
+	n, err := intArg(args, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, errors.New("length must not be negative")
+	}
+	runes := []rune(toString(v))
+	if len(runes) <= n {
+		return string(runes), nil
+	}
+	return string(runes[:n]) + stringArg(args, 1, "..."), nil
+}
+
+func replace(v any, args []any) (any, error) {
+	return strings.ReplaceAll(toString(v), toString(args[0]), toString(args[1])), nil
+}
+
+// dateLayout 支持 YYYY-MM-DD HH:mm:ss 形式的格式，也可直接使用 Go 时间格式
+var dateLayout = strings.NewReplacer("YYYY", "2006", "MM", "01", "DD", "02", "HH", "15", "mm", "04", "ss", "05")
+
+// date 格式化时间，值可以是秒或毫秒时间戳、2006-01-02 15:04:05、2006-01-02 或 RFC3339 字符串
+func date(v any, args []any) (any, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return nil, err
+	}
+	return t.Format(dateLayout.Replace(stringArg(args, 0, timex.DateTimeLayout))), nil
+}
+
+func toTime(v any) (time.Time, error) {
+	if t, ok := v.(time.Time); ok {
+		return t, nil
+	}
+	if f, ok := toFloat(v); ok {
+		ts := int64(f)
+		if ts > 1e12 {
+			return time.UnixMilli(ts), nil
+		}
+		return time.Unix(ts, 0), nil
+	}
+	s := strings.TrimSpace(toString(v))
+	if t, err := timex.ParseDateTime(s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(timex.DateLayout, s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// number 格式化数字，参数为小数位数（默认保留原精度）与千分位分隔符（默认逗号）
+func number(v any, args []any) (any, error) {
+	f, ok := toFloat(v)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %q", toString(v))
+	}
+	decimals, err := intArg(args, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	if decimals > 10 {
+		return nil, errors.New("decimals must not exceed 10")
+	}
+	s := strconv.FormatFloat(f, 'f', decimals, 64)
+	sep := stringArg(args, 1, ",")
+	if sep == "" {
+		return s, nil
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	integer, fraction, hasFraction := strings.Cut(s, ".")
+	var b strings.Builder
+	for i, c := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteString(sep)
+		}
+		b.WriteRune(c)
+	}
+	if hasFraction {
+		return sign + b.String() + "." + fraction, nil
+	}
+	return sign + b.String(), nil
+}
+
+func join(v any, args []any) (any, error) {
+	list, err := toList(v)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]string, len(list))
+	for i, item := range list {
+		parts[i] = toString(item)
+	}
+	return strings.Join(parts, stringArg(args, 0, ",")), nil
+}
+
+// length 列表返回元素个数，其余按字符数计算
+func length(v any, args []any) (any, error) {
+	if _, ok := v.(string); !ok {
+		if list, err := toList(v); err == nil {
+			return len(list), nil
+		}
+	}
+	return len([]rune(toString(v))), nil
+}
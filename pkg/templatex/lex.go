@@ -0,0 +1,288 @@
+package templatex
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokString
+	tokNumber
+	tokPipe
+	tokColon
+	tokComma
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentPart(r rune) bool {
+	return r == '_' || r == '-' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isIdent(s string) bool {
+	for i, r := range s {
+		if (i == 0 && !isIdentStart(r)) || !isIdentPart(r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// lex 拆分标签内容，变量名支持中文、下划线、连字符与 . 路径
+func lex(s string, line int) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '|':
+			tokens = append(tokens, token{tokPipe, "|"})
+			i++
+		case r == ':':
+			tokens = append(tokens, token{tokColon, ":"})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{tokComma, ","})
+			i++
+		case r == '"' || r == '\'':
+			str, n, err := unquote(s[i:], line)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokString, str})
+			i += n
+		case r == '=' || r == '!' || r == '<' || r == '>':
+			op := string(r)
+			if i+1 < len(s) && s[i+1] == '=' {
+				op += "="
+			}
+			if op == "=" {
+				return nil, errorf(line, "unexpected \"=\", use \"==\" to compare")
+			}
+			tokens = append(tokens, token{tokOp, op})
+			i += len(op)
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9'):
+			j := i + 1
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.') {
+				j++
+			}
+			if _, err := strconv.ParseFloat(s[i:j], 64); err != nil {
+				return nil, errorf(line, "invalid number %q", s[i:j])
+			}
+			tokens = append(tokens, token{tokNumber, s[i:j]})
+			i = j
+		case isIdentStart(r):
+			j := i + size
+			for j < len(s) {
+				r, size := utf8.DecodeRuneInString(s[j:])
+				if !isIdentPart(r) {
+					break
+				}
+				j += size
+			}
+			tokens = append(tokens, token{tokIdent, s[i:j]})
+			i = j
+		default:
+			return nil, errorf(line, "unexpected character %q", r)
+		}
+	}
+	return tokens, nil
+}
+
+// unquote 读取引号包裹的字符串，支持 \" \' \\ \n \t 转义，返回字符串与消耗的字节数
+func unquote(s string, line int) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, errorf(line, "unclosed string %s", s)
+}
+
+type tokenStream struct {
+	tokens []token
+	pos    int
+	line   int
+}
+
+func (ts *tokenStream) done() bool {
+	return ts.pos >= len(ts.tokens)
+}
+
+func (ts *tokenStream) peek() token {
+	if ts.done() {
+		return token{kind: -1}
+	}
+	return ts.tokens[ts.pos]
+}
+
+func (ts *tokenStream) next() token {
+	t := ts.peek()
+	ts.pos++
+	return t
+}
+
+func (ts *tokenStream) isWord(word string) bool {
+	t := ts.peek()
+	return t.kind == tokIdent && t.val == word
+}
+
+// or := and ("or" and)*
+func (ts *tokenStream) or() (*cond, error) {
+	left, err := ts.and()
+	if err != nil {
+		return nil, err
+	}
+	for ts.isWord("or") {
+		ts.next()
+		right, err := ts.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &cond{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+// and := not ("and" not)*
+func (ts *tokenStream) and() (*cond, error) {
+	left, err := ts.not()
+	if err != nil {
+		return nil, err
+	}
+	for ts.isWord("and") {
+		ts.next()
+		right, err := ts.not()
+		if err != nil {
+			return nil, err
+		}
+		left = &cond{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+// not := ("not" | "!") not | compare
+func (ts *tokenStream) not() (*cond, error) {
+	if t := ts.peek(); ts.isWord("not") || (t.kind == tokOp && t.val == "!") {
+		ts.next()
+		c, err := ts.not()
+		if err != nil {
+			return nil, err
+		}
+		return &cond{op: "not", left: c}, nil
+	}
+	return ts.compare()
+}
+
+// compare := expr (op expr)?
+func (ts *tokenStream) compare() (*cond, error) {
+	x, err := ts.expr()
+	if err != nil {
+		return nil, err
+	}
+	t := ts.peek()
+	if t.kind != tokOp {
+		return &cond{x: x}, nil
+	}
+	if t.val == "!" {
+		return nil, errorf(ts.line, "unexpected \"!\"")
+	}
+	ts.next()
+	y, err := ts.expr()
+	if err != nil {
+		return nil, err
+	}
+	return &cond{op: t.val, x: x, y: y}, nil
+}
+
+// expr := operand ("|" filter (":" operand ("," operand)*)?)*
+func (ts *tokenStream) expr() (*expr, error) {
+	e, err := ts.operand()
+	if err != nil {
+		return nil, err
+	}
+	for ts.peek().kind == tokPipe {
+		ts.next()
+		t := ts.next()
+		if t.kind != tokIdent {
+			return nil, errorf(ts.line, "expected filter name after \"|\"")
+		}
+		def, ok := filters[t.val]
+		if !ok {
+			return nil, errorf(ts.line, "unknown filter %q", t.val)
+		}
+		call := &filterCall{name: t.val, def: def}
+		if ts.peek().kind == tokColon {
+			ts.next()
+			for {
+				arg, err := ts.operand()
+				if err != nil {
+					return nil, err
+				}
+				call.args = append(call.args, arg)
+				if ts.peek().kind != tokComma {
+					break
+				}
+				ts.next()
+			}
+		}
+		if len(call.args) < def.min || len(call.args) > def.max {
+			return nil, errorf(ts.line, "filter %q takes %d to %d arguments, got %d", t.val, def.min, def.max, len(call.args))
+		}
+		e.filters = append(e.filters, call)
+	}
+	return e, nil
+}
+
+// operand 变量、字符串或数字，true/false 为布尔字面量
+func (ts *tokenStream) operand() (*expr, error) {
+	t := ts.next()
+	switch t.kind {
+	case tokString:
+		return &expr{literal: t.val}, nil
+	case tokNumber:
+		f, _ := strconv.ParseFloat(t.val, 64)
+		return &expr{literal: f}, nil
+	case tokIdent:
+		switch t.val {
+		case "true", "false":
+			return &expr{literal: t.val == "true"}, nil
+		case "and", "or", "not":
+			return nil, errorf(ts.line, "unexpected %q", t.val)
+		}
+		return &expr{key: t.val, path: strings.Split(t.val, ".")}, nil
+	case -1:
+		return nil, errorf(ts.line, "unexpected end of expression")
+	}
+	return nil, errorf(ts.line, "unexpected %q", t.val)
+}
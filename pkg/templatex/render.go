@@ -0,0 +1,401 @@
+package templatex
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"chihqiang/msgbox-go/pkg/timex"
+)
+
+type renderer struct {
+	vars   map[string]any
+	scopes []map[string]any // 循环变量作用域，内层优先
+	out    strings.Builder
+	loops  int
+	escape func(string) string // 输出标签的转义函数，nil 时原样输出
+}
+
+func (r *renderer) render(nodes []node) error {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *textNode:
+			if err := r.write(n.text, 0); err != nil {
+				return err
+			}
+		case *outputNode:
+			if err := r.output(n); err != nil {
+				return err
+			}
+		case *ifNode:
+			if err := r.renderIf(n); err != nil {
+				return err
+			}
+		case *forNode:
+			if err := r.renderFor(n); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *renderer) write(s string, line int) error {
+	if r.out.Len()+len(s) > MaxOutput {
+		return errorf(line, "output exceeds %d bytes", MaxOutput)
+	}
+	r.out.WriteString(s)
+	return nil
+}
+
+// output 先按标签原文查找变量，兼容 ${1}、${a b} 等旧变量名
+func (r *renderer) output(n *outputNode) error {
+	if v, ok := r.lookupKey(n.raw); ok {
+		return r.writeValue(v, n.line)
+	}
+	if n.expr == nil {
+		return errorf(n.line, "variable %q is missing", n.raw)
+	}
+	v, found, err := r.eval(n.expr, n.line)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errorf(n.line, "variable %q is missing", n.expr.key)
+	}
+	return r.writeValue(v, n.line)
+}
+
+// writeValue 输出变量值，设置了 escape 时转义
+func (r *renderer) writeValue(v any, line int) error {
+	s := toString(v)
+	if r.escape != nil {
+		s = r.escape(s)
+	}
+	return r.write(s, line)
+}
+
+func (r *renderer) renderIf(n *ifNode) error {
+	for _, b := range n.branches {
+		ok, err := r.test(b.cond, b.line)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return r.render(b.body)
+		}
+	}
+	return r.render(n.elseBody)
+}
+
+// renderFor 循环内可使用 loop.index（从 1 开始）、loop.index0、loop.first、loop.last、loop.length
+func (r *renderer) renderFor(n *forNode) error {
+	v, _, err := r.eval(n.list, n.line)
+	if err != nil {
+		return err
+	}
+	list, err := toList(v)
+	if err != nil {
+		return errorf(n.line, "variable %q: %v", n.list.key, err)
+	}
+	if len(list) == 0 {
+		return r.render(n.elseBody)
+	}
+	scope := map[string]any{}
+	r.scopes = append(r.scopes, scope)
+	defer func() { r.scopes = r.scopes[:len(r.scopes)-1] }()
+	for i, item := range list {
+		if r.loops++; r.loops > MaxIterations {
+			return errorf(n.line, "loop iterations exceed %d", MaxIterations)
+		}
+		scope[n.name] = item
+		scope["loop"] = map[string]any{
+			"index":  i + 1,
+			"index0": i,
+			"first":  i == 0,
+			"last":   i == len(list)-1,
+			"length": len(list),
+		}
+		if err := r.render(n.body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *renderer) test(c *cond, line int) (bool, error) {
+	switch c.op {
+	case "and", "or":
+		left, err := r.test(c.left, line)
+		if err != nil {
+			return false, err
+		}
+		if (c.op == "and") != left {
+			return left, nil
+		}
+		return r.test(c.right, line)
+	case "not":
+		ok, err := r.test(c.left, line)
+		return !ok, err
+	}
+	x, _, err := r.eval(c.x, line)
+	if err != nil {
+		return false, err
+	}
+	if c.op == "" {
+		return truthy(x), nil
+	}
+	y, _, err := r.eval(c.y, line)
+	if err != nil {
+		return false, err
+	}
+	return compare(c.op, x, y), nil
+}
+
+// eval 计算表达式，变量缺失时 found 为 false，default 过滤器可补齐缺失值
+func (r *renderer) eval(e *expr, line int) (any, bool, error) {
+	v, found := e.literal, true
+	if e.path != nil {
+		v, found = r.lookup(e)
+	}
+	for _, f := range e.filters {
+		if f.name == "default" {
+			if !found || isEmpty(v) {
+				arg, _, err := r.arg(f, 0, line)
+				if err != nil {
+					return nil, false, err
+				}
+				v, found = arg, true
+			}
+			continue
+		}
+		if !found {
+			continue
+		}
+		args := make([]any, len(f.args))
+		for i := range f.args {
+			arg, _, err := r.arg(f, i, line)
+			if err != nil {
+				return nil, false, err
+			}
+			args[i] = arg
+		}
+		out, err := f.def.fn(v, args)
+		if err != nil {
+			return nil, false, errorf(line, "filter %q: %v", f.name, err)
+		}
+		v = out
+	}
+	return v, found, nil
+}
+
+// arg 过滤器参数引用的变量必须存在
+func (r *renderer) arg(f *filterCall, i int, line int) (any, bool, error) {
+	v, found, err := r.eval(f.args[i], line)
+	if err != nil {
+		return nil, false, err
+	}
+	if !found {
+		return nil, false, errorf(line, "filter %q argument %q is missing", f.name, f.args[i].key)
+	}
+	return v, true, nil
+}
+
+func (r *renderer) lookupKey(key string) (any, bool) {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if v, ok := r.scopes[i][key]; ok {
+			return v, true
+		}
+	}
+	v, ok := r.vars[key]
+	return v, ok
+}
+
+// lookup 优先按完整变量名查找，兼容名称中带 . 的旧变量，再按路径逐级读取
+func (r *renderer) lookup(e *expr) (any, bool) {
+	if v, ok := r.lookupKey(e.key); ok {
+		return v, true
+	}
+	v, ok := r.lookupKey(e.path[0])
+	for _, name := range e.path[1:] {
+		if !ok {
+			break
+		}
+		v, ok = field(v, name)
+	}
+	return v, ok
+}
+
+// field 读取 map 的键或列表的下标，不访问结构体字段与方法
+func field(v any, name string) (any, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		item := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !item.IsValid() {
+			return nil, false
+		}
+		return item.Interface(), true
+	case reflect.Slice, reflect.Array:
+		if name == "length" {
+			return rv.Len(), true
+		}
+		i, err := strconv.Atoi(name)
+		if err != nil || i < 0 || i >= rv.Len() {
+			return nil, false
+		}
+		return rv.Index(i).Interface(), true
+	}
+	return nil, false
+}
+
+// toList 列表变量，支持 JSON 数组字符串
+func toList(v any) ([]any, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		return v, nil
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return nil, nil
+		}
+		var list []any
+		if err := json.Unmarshal([]byte(v), &list); err != nil {
+			return nil, fmt.Errorf("is not a list")
+		}
+		return list, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("is not a list")
+	}
+	list := make([]any, rv.Len())
+	for i := range list {
+		list[i] = rv.Index(i).Interface()
+	}
+	return list, nil
+}
+
+// toString 输出变量，数字不使用科学计数法，列表以逗号连接，对象输出 JSON
+func toString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case time.Time:
+		return v.Format(timex.DateTimeLayout)
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = toString(item)
+		}
+		return strings.Join(parts, ",")
+	case []string:
+		return strings.Join(v, ",")
+	case map[string]any:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func isEmpty(v any) bool {
+	if v == nil {
+		return true
+	}
+	if s, ok := v.(string); ok {
+		return s == ""
+	}
+	return false
+}
+
+// truthy 字符串 "false"、"0" 视为假，兼容以字符串传递的布尔参数
+func truthy(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != "" && v != "false" && v != "0"
+	}
+	if f, ok := toFloat(v); ok {
+		return f != 0
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len() > 0
+	}
+	return true
+}
+
+// compare 两侧均为数字时按数值比较，否则按字符串比较
+func compare(op string, x, y any) bool {
+	var c int
+	fx, okx := toFloat(x)
+	fy, oky := toFloat(y)
+	if okx && oky {
+		switch {
+		case fx < fy:
+			c = -1
+		case fx > fy:
+			c = 1
+		}
+	} else {
+		c = strings.Compare(toString(x), toString(y))
+	}
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
@@ -0,0 +1,394 @@
+// Package templatex 消息模板引擎
+//
+// 语法均以 ${ 开头、} 结尾，兼容原有的 ${key} 变量替换：
+//
+//	${name}                          输出变量，变量缺失时渲染失败
+//	${user.name} ${items.0}          按路径读取嵌套对象与列表元素
+//	${name|default:"用户"}            变量缺失或为空时使用默认值
+//	${amount|number:2|default:"0"}   过滤器可串联，参数为字符串、数字或变量
+//	${if vip}...${elif level >= 3}...${else}...${end}
+//	${for item in items}${loop.index}. ${item.name}${else}空列表${end}
+//	$${                              输出字面量 ${
+//
+// 模板只能读取传入的变量并调用内置过滤器，无法访问方法、文件或执行代码；
+// 渲染限制循环次数与输出长度，避免恶意模板耗尽资源。
+package templatex
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	MaxDepth      = 32      // 条件与循环的最大嵌套层数
+	MaxIterations = 10000   // 单次渲染的最大循环次数
+	MaxOutput     = 1 << 20 // 单次渲染的最大输出字节数
+)
+
+// Error 模板解析或渲染错误，Line 为出错标签所在行
+type Error struct {
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+func errorf(line int, format string, args ...any) error {
+	return &Error{Line: line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Template 解析后的模板，可并发渲染
+type Template struct {
+	nodes []node
+}
+
+// Parse 解析模板，语法错误、未知过滤器或标签未闭合时返回错误
+func Parse(src string) (*Template, error) {
+	segs, err := scan(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{segs: segs}
+	nodes, end, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	if end != nil {
+		return nil, errorf(end.line, "unexpected ${%s}", end.text)
+	}
+	return &Template{nodes: nodes}, nil
+}
+
+// Render 解析并渲染模板
+func Render(src string, vars map[string]any) (string, error) {
+	t, err := Parse(src)
+	if err != nil {
+		return "", err
+	}
+	return t.Render(vars)
+}
+
+// RenderEscape 解析并渲染模板，输出标签的结果经 escape 转义后写入，模板文本原样保留
+func RenderEscape(src string, vars map[string]any, escape func(string) string) (string, error) {
+	t, err := Parse(src)
+	if err != nil {
+		return "", err
+	}
+	return t.RenderEscape(vars, escape)
+}
+
+// Render 使用变量渲染模板
+func (t *Template) Render(vars map[string]any) (string, error) {
+	return t.RenderEscape(vars, nil)
+}
+
+// RenderEscape 使用变量渲染模板，变量在过滤器与循环中保持原始值，仅在输出时按 escape 转义一次；
+// escape 为 nil 时不转义
+func (t *Template) RenderEscape(vars map[string]any, escape func(string) string) (string, error) {
+	r := &renderer{vars: vars, escape: escape}
+	if err := r.render(t.nodes); err != nil {
+		return "", err
+	}
+	return r.out.String(), nil
+}
+
+type node interface{}
+
+type textNode struct {
+	text string
+}
+
+// outputNode 输出标签，expr 为空时 raw 整体作为变量名（兼容含特殊字符的旧变量名）
+type outputNode struct {
+	raw  string
+	expr *expr
+	line int
+}
+
+type ifNode struct {
+	branches []*branch
+	elseBody []node
+}
+
+type branch struct {
+	cond *cond
+	body []node
+	line int
+}
+
+type forNode struct {
+	name     string
+	list     *expr
+	body     []node
+	elseBody []node
+	line     int
+}
+
+// expr 变量或字面量，后接过滤器
+type expr struct {
+	key     string   // 原始变量名，优先按完整名称查找
+	path    []string // 按 . 拆分的路径，为空时使用 literal
+	literal any
+	filters []*filterCall
+}
+
+type filterCall struct {
+	name string
+	def  filterDef
+	args []*expr
+}
+
+// cond 条件表达式：op 为 and/or/not 时使用 left/right，比较运算使用 x/y，为空时取 x 的真值
+type cond struct {
+	op          string
+	left, right *cond
+	x, y        *expr
+}
+
+// segment 扫描结果，tag 为 true 时 text 为 ${ } 内去除首尾空白的内容
+type segment struct {
+	text string
+	tag  bool
+	line int
+}
+
+// scan 将模板拆分为文本与标签
+func scan(src string) ([]segment, error) {
+	var segs []segment
+	line := 1
+	for len(src) > 0 {
+		i := strings.Index(src, "${")
+		if i < 0 {
+			segs = append(segs, segment{text: src, line: line})
+			break
+		}
+		if i > 0 && src[i-1] == '$' {
+			segs = append(segs, segment{text: src[:i-1] + "${", line: line})
+			line += strings.Count(src[:i], "\n")
+			src = src[i+2:]
+			continue
+		}
+		if i > 0 {
+			segs = append(segs, segment{text: src[:i], line: line})
+			line += strings.Count(src[:i], "\n")
+		}
+		j := tagEnd(src[i+2:])
+		if j < 0 {
+			return nil, errorf(line, "unclosed ${")
+		}
+		content := src[i+2 : i+2+j]
+		segs = append(segs, segment{text: strings.TrimSpace(content), tag: true, line: line})
+		line += strings.Count(content, "\n")
+		src = src[i+2+j+1:]
+	}
+	return segs, nil
+}
+
+// tagEnd 查找标签结束的 }，跳过引号内的内容
+func tagEnd(s string) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '}':
+			return i
+		}
+	}
+	return -1
+}
+
+// keyword 拆分标签关键字，非关键字标签返回空
+func keyword(text string) (string, string) {
+	switch text {
+	case "else", "end":
+		return text, ""
+	}
+	for _, kw := range []string{"if", "elif", "for"} {
+		if rest, ok := strings.CutPrefix(text, kw); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\n') {
+			return kw, strings.TrimSpace(rest)
+		}
+	}
+	return "", text
+}
+
+type parser struct {
+	segs  []segment
+	pos   int
+	depth int
+}
+
+// parseBody 解析到 elif/else/end 或模板结尾，返回终止的标签
+func (p *parser) parseBody() ([]node, *segment, error) {
+	var nodes []node
+	for p.pos < len(p.segs) {
+		seg := p.segs[p.pos]
+		p.pos++
+		if !seg.tag {
+			nodes = append(nodes, &textNode{text: seg.text})
+			continue
+		}
+		kw, rest := keyword(seg.text)
+		switch kw {
+		case "elif", "else", "end":
+			return nodes, &seg, nil
+		case "if", "for":
+			if p.depth >= MaxDepth {
+				return nil, nil, errorf(seg.line, "blocks nested deeper than %d", MaxDepth)
+			}
+			p.depth++
+			var (
+				n   node
+				err error
+			)
+			if kw == "if" {
+				n, err = p.parseIf(seg, rest)
+			} else {
+				n, err = p.parseFor(seg, rest)
+			}
+			p.depth--
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, n)
+		default:
+			n, err := parseOutput(seg)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes, nil, nil
+}
+
+func (p *parser) parseIf(seg segment, rest string) (node, error) {
+	n := &ifNode{}
+	c, err := parseCond(rest, seg.line)
+	if err != nil {
+		return nil, err
+	}
+	line := seg.line
+	for {
+		body, end, err := p.parseBody()
+		if err != nil {
+			return nil, err
+		}
+		if end == nil {
+			return nil, errorf(seg.line, "${if} is not closed by ${end}")
+		}
+		n.branches = append(n.branches, &branch{cond: c, body: body, line: line})
+		kw, rest := keyword(end.text)
+		switch kw {
+		case "end":
+			return n, nil
+		case "else":
+			if n.elseBody, err = p.parseTail(seg, "if"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		}
+		if c, err = parseCond(rest, end.line); err != nil {
+			return nil, err
+		}
+		line = end.line
+	}
+}
+
+func (p *parser) parseFor(seg segment, rest string) (node, error) {
+	name, list, ok := strings.Cut(rest, " in ")
+	name = strings.TrimSpace(name)
+	if !ok || !isIdent(name) || strings.Contains(name, ".") {
+		return nil, errorf(seg.line, "invalid ${for %s}, expected ${for item in list}", rest)
+	}
+	e, err := parseExpr(list, seg.line)
+	if err != nil {
+		return nil, err
+	}
+	n := &forNode{name: name, list: e, line: seg.line}
+	body, end, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	if end == nil {
+		return nil, errorf(seg.line, "${for} is not closed by ${end}")
+	}
+	n.body = body
+	switch end.text {
+	case "end":
+		return n, nil
+	case "else":
+		if n.elseBody, err = p.parseTail(seg, "for"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+	return nil, errorf(end.line, "unexpected ${%s} in ${for}", end.text)
+}
+
+// parseTail 解析 ${else} 到 ${end} 之间的内容
+func (p *parser) parseTail(seg segment, block string) ([]node, error) {
+	body, end, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	if end == nil {
+		return nil, errorf(seg.line, "${%s} is not closed by ${end}", block)
+	}
+	if end.text != "end" {
+		return nil, errorf(end.line, "unexpected ${%s} after ${else}", end.text)
+	}
+	return body, nil
+}
+
+// parseOutput 解析输出标签，无过滤器且无法按表达式解析时整体作为变量名
+func parseOutput(seg segment) (node, error) {
+	e, err := parseExpr(seg.text, seg.line)
+	if err != nil {
+		if seg.text == "" || strings.ContainsAny(seg.text, "|\"'") {
+			return nil, err
+		}
+		return &outputNode{raw: seg.text, line: seg.line}, nil
+	}
+	return &outputNode{raw: seg.text, expr: e, line: seg.line}, nil
+}
+
+func parseExpr(s string, line int) (*expr, error) {
+	tokens, err := lex(s, line)
+	if err != nil {
+		return nil, err
+	}
+	ts := &tokenStream{tokens: tokens, line: line}
+	e, err := ts.expr()
+	if err != nil {
+		return nil, err
+	}
+	if !ts.done() {
+		return nil, errorf(line, "unexpected %q in ${%s}", ts.peek().val, s)
+	}
+	return e, nil
+}
+
+func parseCond(s string, line int) (*cond, error) {
+	tokens, err := lex(s, line)
+	if err != nil {
+		return nil, err
+	}
+	ts := &tokenStream{tokens: tokens, line: line}
+	c, err := ts.or()
+	if err != nil {
+		return nil, err
+	}
+	if !ts.done() {
+		return nil, errorf(line, "unexpected %q in condition %q", ts.peek().val, s)
+	}
+	return c, nil
+}
@@ -0,0 +1,214 @@
+package templatex
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	vars := map[string]any{
+		"name":   "Alice",
+		"empty":  "",
+		"amount": 1234567.891,
+		"vip":    true,
+		"level":  "3",
+		"user":   map[string]any{"name": "Bob", "tags": []any{"a", "b"}},
+		"items":  []any{map[string]any{"name": "x"}, map[string]any{"name": "y"}},
+		"json":   `["p","q"]`,
+		"a.b":    "dotted",
+		"1":      "one",
+		"ts":     1700000000,
+	}
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"plain text", "hello", "hello"},
+		{"variable", "hi ${name}", "hi Alice"},
+		{"spaces inside tag", "hi ${ name }", "hi Alice"},
+		{"nested path", "${user.name} ${user.tags.1} ${items.0.name}", "Bob b x"},
+		{"dotted legacy name", "${a.b}", "dotted"},
+		{"numeric legacy name", "${1}", "one"},
+		{"literal dollar brace", "$${name} ${name}", "${name} Alice"},
+		{"default on missing", `${missing|default:"guest"}`, "guest"},
+		{"default on empty", `${empty|default:"guest"}`, "guest"},
+		{"default keeps value", `${name|default:"guest"}`, "Alice"},
+		{"default from variable", `${missing|default:name}`, "Alice"},
+		{"chained filters", `${name|upper|truncate:3}`, "ALI..."},
+		{"number", `${amount|number:2}`, "1,234,567.89"},
+		{"number separator", `${amount|number:0," "}`, "1 234 568"},
+		{"join", `${user.tags|join:"/"}`, "a/b"},
+		{"join json string", `${json|join}`, "p,q"},
+		{"length", `${items|length} ${name|length}`, "2 5"},
+		{"replace", `${name|replace:"A","a"}`, "alice"},
+		{"escape filter", `${tag|default:"<b>"|escape}`, "&lt;b&gt;"},
+		{"quoted brace in argument", `${missing|default:"}"}`, "}"},
+		{"if true", "${if vip}VIP${end}", "VIP"},
+		{"if false else", "${if missing}x${else}y${end}", "y"},
+		{"elif", "${if level > 5}high${elif level >= 3}mid${else}low${end}", "mid"},
+		{"numeric compare", "${if level < 10}lt${end}", "lt"},
+		{"string compare", `${if name == "Alice"}eq${end}`, "eq"},
+		{"and or not", "${if vip and not missing or empty}ok${end}", "ok"},
+		{"for", "${for item in items}${loop.index}.${item.name}${if !loop.last},${end}${end}", "1.x,2.y"},
+		{"for else", "${for item in missing}x${else}none${end}", "none"},
+		{"for json string", "${for v in json}[${v}]${end}", "[p][q]"},
+		{"nested for", "${for i in user.tags}${for j in user.tags}${i}${j} ${end}${end}", "aa ab ba bb "},
+		{"multiline", "a\n${name}\nb", "a\nAlice\nb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.src, vars)
+			if err != nil {
+				t.Fatalf("Render(%q) error: %v", tt.src, err)
+			}
+			if got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	deep := strings.Repeat("${if a}", MaxDepth+1) + strings.Repeat("${end}", MaxDepth+1)
+	tests := []struct {
+		name     string
+		src      string
+		wantLine int
+		wantMsg  string
+	}{
+		{"unclosed tag", "hi ${name", 1, "unclosed ${"},
+		{"unclosed tag on later line", "a\nb\n${name", 3, "unclosed ${"},
+		{"unknown filter", "${name|shout}", 1, `unknown filter "shout"`},
+		{"missing filter name", "${name|}", 1, "expected filter name"},
+		{"too many filter arguments", `${name|upper:"x"}`, 1, `filter "upper" takes 0 to 0 arguments`},
+		{"too few filter arguments", `${name|replace:"x"}`, 1, `filter "replace" takes 2 to 2 arguments`},
+		{"unclosed string", `${name|default:"x}`, 1, "unclosed ${"},
+		{"single equals", "${if a = b}x${end}", 1, `use "=="`},
+		{"unclosed if", "${if a}x", 1, "${if} is not closed by ${end}"},
+		{"unclosed for", "${for x in xs}x", 1, "${for} is not closed by ${end}"},
+		{"stray end", "x\n${end}", 2, "unexpected ${end}"},
+		{"stray else", "${else}", 1, "unexpected ${else}"},
+		{"elif after else", "${if a}x${else}y${elif b}z${end}", 1, "unexpected ${elif b} after ${else}"},
+		{"elif in for", "${for x in xs}${elif a}${end}", 1, "unexpected ${elif a} in ${for}"},
+		{"invalid for", "${for x of xs}${end}", 1, "expected ${for item in list}"},
+		{"for with path variable", "${for x.y in xs}${end}", 1, "expected ${for item in list}"},
+		{"empty tag", "${}", 1, "unexpected end of expression"},
+		{"keyword operand", "${if and}x${end}", 1, `unexpected "and"`},
+		{"trailing token", "${if a b}x${end}", 1, `unexpected "b"`},
+		{"too deep", deep, 1, "nested deeper than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("Parse(%q) error = %v, want *Error", tt.src, err)
+			}
+			if e.Line != tt.wantLine || !strings.Contains(e.Msg, tt.wantMsg) {
+				t.Errorf("Parse(%q) error = %v, want line %d containing %q", tt.src, err, tt.wantLine, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	many := make([]any, MaxIterations/2+1)
+	tests := []struct {
+		name    string
+		src     string
+		vars    map[string]any
+		wantMsg string
+	}{
+		{"missing variable", "hi\n${name}", nil, `line 2: variable "name" is missing`},
+		{"missing path", "${user.age}", map[string]any{"user": map[string]any{}}, `variable "user.age" is missing`},
+		{"missing filter argument", "${name|default:other}", nil, `filter "default" argument "other" is missing`},
+		{"filter error", `${amount|number}`, map[string]any{"amount": "abc"}, `filter "number": invalid number "abc"`},
+		{"non-integer argument", `${name|truncate:1.5}`, map[string]any{"name": "abc"}, "argument 1 must be an integer"},
+		{"not a list", "${for x in name}${x}${end}", map[string]any{"name": "abc"}, `variable "name": is not a list`},
+		{"loop limit", "${for x in xs}${for y in ys}${end}${end}", map[string]any{"xs": many, "ys": []any{1, 2}}, "loop iterations exceed"},
+		{"output limit", "${for x in xs}${big}${end}", map[string]any{"xs": many, "big": strings.Repeat("x", MaxOutput/len(many)+1)}, "output exceeds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Render(tt.src, tt.vars)
+			if err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("Render(%q) error = %v, want containing %q", tt.src, err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+// TestRenderEscape 仅转义输出标签的结果，模板文本与过滤器看到的均为原始值
+func TestRenderEscape(t *testing.T) {
+	vars := map[string]any{"name": "<Tom & Jerry>", "tags": []any{"<a>", "b"}}
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"template text kept", "<p>${name}</p>", "<p>&lt;Tom &amp; Jerry&gt;</p>"},
+		{"filters see raw value", `${name|replace:"&","and"}`, "&lt;Tom and Jerry&gt;"},
+		{"escaped once", "${name|escape}", "&amp;lt;Tom &amp;amp; Jerry&amp;gt;"},
+		{"default literal escaped", `${missing|default:"<none>"}`, "&lt;none&gt;"},
+		{"loop items escaped", "${for tag in tags}${tag};${end}", "&lt;a&gt;;b;"},
+		{"raw lists reach join", `${tags|join:"|"}`, "&lt;a&gt;|b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderEscape(tt.src, vars, html.EscapeString)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderEscape(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+	got, err := RenderEscape("${name}", vars, nil)
+	if err != nil || got != "<Tom & Jerry>" {
+		t.Errorf("RenderEscape with nil escape = %q, %v", got, err)
+	}
+}
+
+// TestSandbox 模板只能读取 map 与列表，不能访问结构体字段或方法
+func TestSandbox(t *testing.T) {
+	type secret struct{ Password string }
+	vars := map[string]any{"s": secret{Password: "p"}, "m": map[int]string{1: "x"}}
+	for _, src := range []string{"${s.Password}", "${m.1}", "${s.String}"} {
+		if got, err := Render(src, vars); err == nil {
+			t.Errorf("Render(%q) = %q, want missing variable error", src, got)
+		}
+	}
+}
+
+func TestVariables(t *testing.T) {
+	src := `${name} ${user.email|default:""} ${if vip}${coupon}${end}` +
+		`${for item in items}${item.name}${loop.index}${suffix}${end}${count|number:digits}${1}`
+	tmpl, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Variable{
+		{Name: "name", Type: VariableTypeString, Required: true},
+		{Name: "user", Type: VariableTypeObject, Required: false},
+		{Name: "vip", Type: VariableTypeString, Required: false},
+		{Name: "coupon", Type: VariableTypeString, Required: false},
+		{Name: "items", Type: VariableTypeList, Required: false},
+		{Name: "suffix", Type: VariableTypeString, Required: true},
+		{Name: "digits", Type: VariableTypeString, Required: true},
+		{Name: "count", Type: VariableTypeString, Required: true},
+		{Name: "1", Type: VariableTypeString, Required: true},
+	}
+	got := tmpl.Variables()
+	if len(got) != len(want) {
+		t.Fatalf("Variables() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("variable %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
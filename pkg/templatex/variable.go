@@ -0,0 +1,102 @@
+package templatex
+
+// 变量类型，由模板中的用法推断
+const (
+	VariableTypeString = "string"
+	VariableTypeList   = "list"   // 出现在 ${for item in list} 中
+	VariableTypeObject = "object" // 以 ${user.name} 形式读取字段
+)
+
+// Variable 模板引用的顶层变量
+type Variable struct {
+	Name string
+	Type string
+	// Required 存在未设置 default、且不在条件分支内的引用时为 true
+	Required bool
+}
+
+// Variables 按首次出现的顺序返回模板引用的变量，不包括循环变量与 loop
+func (t *Template) Variables() []Variable {
+	c := &collector{index: map[string]int{}}
+	c.nodes(t.nodes, nil, true)
+	return c.vars
+}
+
+type collector struct {
+	vars  []Variable
+	index map[string]int
+}
+
+func (c *collector) add(name, typ string, required bool) {
+	if i, ok := c.index[name]; ok {
+		v := &c.vars[i]
+		v.Required = v.Required || required
+		if v.Type == VariableTypeString {
+			v.Type = typ
+		}
+		return
+	}
+	c.index[name] = len(c.vars)
+	c.vars = append(c.vars, Variable{Name: name, Type: typ, Required: required})
+}
+
+func (c *collector) nodes(nodes []node, scope []string, required bool) {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *outputNode:
+			// ${1} 等按字面量解析的旧变量名仍按变量处理
+			if n.expr == nil || (n.expr.path == nil && len(n.expr.filters) == 0 && n.raw[0] != '"' && n.raw[0] != '\'') {
+				c.add(n.raw, VariableTypeString, required)
+				continue
+			}
+			c.expr(n.expr, scope, required, VariableTypeString)
+		case *ifNode:
+			for _, b := range n.branches {
+				c.cond(b.cond, scope)
+				c.nodes(b.body, scope, false)
+			}
+			c.nodes(n.elseBody, scope, false)
+		case *forNode:
+			// 列表变量缺失时按空列表渲染，不视为必填
+			c.expr(n.list, scope, false, VariableTypeList)
+			c.nodes(n.body, append(scope[:len(scope):len(scope)], n.name, "loop"), required)
+			c.nodes(n.elseBody, scope, false)
+		}
+	}
+}
+
+func (c *collector) cond(cd *cond, scope []string) {
+	if cd == nil {
+		return
+	}
+	c.cond(cd.left, scope)
+	c.cond(cd.right, scope)
+	for _, e := range []*expr{cd.x, cd.y} {
+		if e != nil {
+			c.expr(e, scope, false, VariableTypeString)
+		}
+	}
+}
+
+func (c *collector) expr(e *expr, scope []string, required bool, typ string) {
+	for _, f := range e.filters {
+		if f.name == "default" {
+			required = false
+		}
+		for _, arg := range f.args {
+			c.expr(arg, scope, required, VariableTypeString)
+		}
+	}
+	if e.path == nil {
+		return
+	}
+	for _, name := range scope {
+		if e.path[0] == name {
+			return
+		}
+	}
+	if len(e.path) > 1 && typ == VariableTypeString {
+		typ = VariableTypeObject
+	}
+	c.add(e.path[0], typ, required)
+}
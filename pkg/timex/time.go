@@ -8,6 +8,15 @@ const DateTimeLayout = "2006-01-02 15:04:05"
 // DateLayout 标准日期格式：年-月-日（例如：2023-10-01）
 const DateLayout = "2006-01-02"
 
+// ParseDateTime 解析日期时间字符串
+// 支持 DateTimeLayout（按本地时区解析）与 RFC3339 两种格式
+func ParseDateTime(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation(DateTimeLayout, s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
 // NowDateTime 获取当前时间的标准日期时间字符串
 // 返回格式遵循 DateTimeLayout（2006-01-02 15:04:05）
 func NowDateTime() string {
@@ -0,0 +1,32 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package batch
+
+import (
+	"chihqiang/msgbox-go/services/agent/api/internal/logic/batch"
+	"chihqiang/msgbox-go/services/agent/api/internal/svc"
+	"chihqiang/msgbox-go/services/agent/api/internal/types"
+	"net/http"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+	xhttp "github.com/zeromicro/x/http"
+)
+
+func BatchRescheduleHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.BatchRescheduleReq
+		if err := httpx.Parse(r, &req); err != nil {
+			xhttp.JsonBaseResponseCtx(r.Context(), w, err)
+			return
+		}
+
+		l := batch.NewBatchRescheduleLogic(r.Context(), svcCtx)
+		err := l.BatchReschedule(&req)
+		if err != nil {
+			xhttp.JsonBaseResponseCtx(r.Context(), w, err)
+		} else {
+			xhttp.JsonBaseResponseCtx(r.Context(), w, nil)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	agetent "chihqiang/msgbox-go/services/agent/api/internal/handler/agetent"
 	auth "chihqiang/msgbox-go/services/agent/api/internal/handler/auth"
+	batch "chihqiang/msgbox-go/services/agent/api/internal/handler/batch"
 	channel "chihqiang/msgbox-go/services/agent/api/internal/handler/channel"
 	nologin "chihqiang/msgbox-go/services/agent/api/internal/handler/nologin"
 	record "chihqiang/msgbox-go/services/agent/api/internal/handler/record"
@@ -51,6 +52,33 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 		rest.WithPrefix("/api/v1/agent"),
 	)
 
+	server.AddRoutes(
+		[]rest.Route{
+			{
+				Method:  http.MethodGet,
+				Path:    "/batch",
+				Handler: batch.BatchQueryHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/batch/cancel",
+				Handler: batch.BatchCancelHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/batch/reschedule",
+				Handler: batch.BatchRescheduleHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/batch/scheduled",
+				Handler: batch.BatchScheduledHandler(serverCtx),
+			},
+		},
+		rest.WithJwt(serverCtx.Config.Auth.AccessSecret),
+		rest.WithPrefix("/api/v1/agent"),
+	)
+
 	server.AddRoutes(
 		[]rest.Route{
 			{
@@ -101,6 +129,11 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/record",
 				Handler: record.RecordQueryHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/record/attempts",
+				Handler: record.RecordAttemptsHandler(serverCtx),
+			},
 		},
 		rest.WithJwt(serverCtx.Config.Auth.AccessSecret),
 		rest.WithPrefix("/api/v1/agent"),
@@ -123,16 +156,41 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/template/delete",
 				Handler: template.TemplateDeleteHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/template/preview",
+				Handler: template.TemplatePreviewHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/template/rollback",
+				Handler: template.TemplateRollbackHandler(serverCtx),
+			},
 			{
 				Method:  http.MethodPost,
 				Path:    "/template/status",
 				Handler: template.TemplateStatusHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/template/test",
+				Handler: template.TemplateTestHandler(serverCtx),
+			},
 			{
 				Method:  http.MethodPost,
 				Path:    "/template/update",
 				Handler: template.TemplateUpdateHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/template/version/diff",
+				Handler: template.TemplateVersionDiffHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/template/versions",
+				Handler: template.TemplateVersionQueryHandler(serverCtx),
+			},
 		},
 		rest.WithJwt(serverCtx.Config.Auth.AccessSecret),
 		rest.WithPrefix("/api/v1/agent"),
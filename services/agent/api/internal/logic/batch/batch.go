@@ -0,0 +1,112 @@
+package batch
+
+import (
+	"chihqiang/msgbox-go/pkg/timex"
+	"chihqiang/msgbox-go/services/agent/api/internal/types"
+	"chihqiang/msgbox-go/services/common/errs"
+	"chihqiang/msgbox-go/services/common/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// findEditableBatch 查询当前代理商下可改期/取消的异步批次
+func findEditableBatch(db *gorm.DB, agentID, id int64) (*models.SendBatch, error) {
+	var batch models.SendBatch
+	if err := db.Where(&models.SendBatch{ID: id, AgentID: agentID, Async: true}).First(&batch).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ErrBatchMissing
+		}
+		return nil, errs.ErrDB
+	}
+	if !batch.Editable() {
+		return nil, errs.ErrBatchStarted
+	}
+	return &batch, nil
+}
+
+// lockPendingRecords 锁定批次内的待发送记录并返回其ID，worker 领取时跳过被锁定的记录；
+// 记录已被 worker 领取且领取锁未过期时即将发送，批次视为已开始，返回 ErrBatchStarted
+func lockPendingRecords(tx *gorm.DB, batchID int64) ([]int64, error) {
+	var records []models.SendRecord
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "locked_until").
+		Where(&models.SendRecord{BatchID: batchID, Status: models.SendRecordStatusPending}).
+		Find(&records).Error; err != nil {
+		return nil, errs.ErrDB
+	}
+	now := time.Now()
+	ids := make([]int64, 0, len(records))
+	for _, record := range records {
+		if record.LockedUntil != nil && record.LockedUntil.After(now) {
+			return nil, errs.ErrBatchStarted
+		}
+		ids = append(ids, record.ID)
+	}
+	return ids, nil
+}
+
+// convertBatches 转换批次列表，并附带按通道统计的发送结果
+func convertBatches(db *gorm.DB, batches []models.SendBatch) ([]types.BatchItemResp, error) {
+	ids := make([]int64, 0, len(batches))
+	for _, item := range batches {
+		ids = append(ids, item.ID)
+	}
+	stats, err := models.QuerySendBatchChannelStats(db, ids)
+	if err != nil {
+		return nil, err
+	}
+	channelIDs := make([]int64, 0, len(stats))
+	for _, stat := range stats {
+		channelIDs = append(channelIDs, stat.ChannelID)
+	}
+	names := make(map[int64]string)
+	if len(channelIDs) > 0 {
+		var channels []models.Channel
+		if err := db.Unscoped().Where("id IN ?", channelIDs).Find(&channels).Error; err != nil {
+			return nil, err
+		}
+		for _, channel := range channels {
+			names[channel.ID] = channel.Name
+		}
+	}
+	channelStats := make(map[int64][]types.BatchChannelItemResp)
+	for _, stat := range stats {
+		channelStats[stat.BatchID] = append(channelStats[stat.BatchID], types.BatchChannelItemResp{
+			ChannelID:    stat.ChannelID,
+			ChannelName:  names[stat.ChannelID],
+			VendorName:   stat.VendorName,
+			TotalCount:   stat.TotalCount,
+			SuccessCount: stat.SuccessCount,
+			FailCount:    stat.FailCount,
+		})
+	}
+	items := make([]types.BatchItemResp, 0, len(batches))
+	for _, item := range batches {
+		resp := types.BatchItemResp{
+			ID:            item.ID,
+			BatchNo:       item.BatchNo,
+			TraceID:       item.TraceID,
+			ChannelID:     item.ChannelID,
+			TemplateID:    item.TemplateID,
+			TotalCount:    item.TotalCount,
+			SuccessCount:  item.SuccessCount,
+			FailCount:     item.FailCount,
+			ScheduledTime: timex.FormatDate(item.ScheduledTime),
+			SendStartTime: timex.FormatDate(item.SendStartTime),
+			SendEndTime:   timex.FormatDate(item.SendEndTime),
+			CancelTime:    timex.FormatDate(item.CancelTime),
+			Channels:      channelStats[item.ID],
+			CreatedAt:     timex.FormatDate(item.CreatedAt),
+		}
+		if item.Channel != nil {
+			resp.ChannelName = item.Channel.Name
+		}
+		if item.Template != nil {
+			resp.TemplateName = item.Template.Name
+		}
+		items = append(items, resp)
+	}
+	return items, nil
+}
@@ -0,0 +1,103 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"chihqiang/msgbox-go/pkg/timex"
+	"chihqiang/msgbox-go/services/agent/api/internal/svc"
+	"chihqiang/msgbox-go/services/agent/api/internal/types"
+	"chihqiang/msgbox-go/services/common/errs"
+	"chihqiang/msgbox-go/services/common/models"
+	"chihqiang/msgbox-go/services/common/models/modeltest"
+
+	"gorm.io/datatypes"
+)
+
+// newScheduledBatch 创建一个计划发送的异步批次，lockedUntil 为各条待发送记录的领取锁过期时间
+func newScheduledBatch(t *testing.T, lockedUntil ...*time.Time) (*svc.ServiceContext, context.Context, *models.SendBatch) {
+	t.Helper()
+	db := modeltest.NewDB(t)
+	agent := &models.Agent{Email: "tester@example.com", Password: "x", Status: true}
+	if err := db.Create(agent).Error; err != nil {
+		t.Fatal(err)
+	}
+	scheduled := time.Now().Add(time.Hour)
+	batch := &models.SendBatch{AgentID: agent.ID, ChannelID: 1, BatchNo: "B1", TraceID: "T1", Async: true, ScheduledTime: &scheduled}
+	if err := db.Create(batch).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i, locked := range lockedUntil {
+		record := &models.SendRecord{
+			BatchID: batch.ID, AgentID: agent.ID, ChannelID: 1, TraceID: "T1", Receiver: strconv.Itoa(i),
+			VendorName: "webhook", ChannelConfig: datatypes.JSON(`{}`), Content: "hi",
+			Status: models.SendRecordStatusPending, QueueTime: &scheduled, LockedUntil: locked,
+		}
+		if err := db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.WithValue(context.Background(), types.JWTAgentID, json.Number(strconv.FormatInt(agent.ID, 10)))
+	return &svc.ServiceContext{DB: db}, ctx, batch
+}
+
+func TestBatchClaimedRecords(t *testing.T) {
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Minute)
+	tests := []struct {
+		name    string
+		locked  []*time.Time
+		wantErr error
+	}{
+		{"not claimed", []*time.Time{nil, nil}, nil},
+		{"claim expired", []*time.Time{nil, &past}, nil},
+		{"claimed by worker", []*time.Time{nil, &future}, errs.ErrBatchStarted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+"/cancel", func(t *testing.T) {
+			svcCtx, ctx, batch := newScheduledBatch(t, tt.locked...)
+			err := NewBatchCancelLogic(ctx, svcCtx).BatchCancel(&types.IDReq{ID: batch.ID})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			var updated models.SendBatch
+			svcCtx.DB.First(&updated, batch.ID)
+			var cancelled int64
+			svcCtx.DB.Model(&models.SendRecord{}).Where("status = ?", models.SendRecordStatusCancel).Count(&cancelled)
+			if tt.wantErr != nil {
+				// 失败时整体回滚，批次与记录均保持原状
+				if updated.CancelTime != nil || cancelled != 0 {
+					t.Errorf("cancel not rolled back: cancel_time=%v cancelled=%d", updated.CancelTime, cancelled)
+				}
+				return
+			}
+			if updated.CancelTime == nil || cancelled != int64(len(tt.locked)) {
+				t.Errorf("cancel_time=%v cancelled=%d", updated.CancelTime, cancelled)
+			}
+		})
+		t.Run(tt.name+"/reschedule", func(t *testing.T) {
+			svcCtx, ctx, batch := newScheduledBatch(t, tt.locked...)
+			sendAt := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+			err := NewBatchRescheduleLogic(ctx, svcCtx).BatchReschedule(&types.BatchRescheduleReq{ID: batch.ID, SendAt: sendAt.Format(timex.DateTimeLayout)})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			var updated models.SendBatch
+			svcCtx.DB.First(&updated, batch.ID)
+			var moved int64
+			svcCtx.DB.Model(&models.SendRecord{}).Where("queue_time = ?", sendAt).Count(&moved)
+			if tt.wantErr != nil {
+				if !updated.ScheduledTime.Equal(*batch.ScheduledTime) || moved != 0 {
+					t.Errorf("reschedule not rolled back: scheduled=%v moved=%d", updated.ScheduledTime, moved)
+				}
+				return
+			}
+			if !updated.ScheduledTime.Equal(sendAt) || moved != int64(len(tt.locked)) {
+				t.Errorf("scheduled=%v moved=%d", updated.ScheduledTime, moved)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package batch
+
+import (
+	"chihqiang/msgbox-go/services/agent/api/internal/svc"
+	"chihqiang/msgbox-go/services/agent/api/internal/types"
+	"chihqiang/msgbox-go/services/common/errs"
+	"chihqiang/msgbox-go/services/common/models"
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+	"time"
+)
+
+type BatchCancelLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewBatchCancelLogic(ctx context.Context, svcCtx *svc.ServiceContext) *BatchCancelLogic {
+	return &BatchCancelLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// BatchCancel 取消尚未开始发送的批次，待发送记录标记为已取消；已有记录被 worker 领取时不可取消
+func (l *BatchCancelLogic) BatchCancel(req *types.IDReq) error {
+	agentID, err := types.GetAgentID(l.ctx)
+	if err != nil {
+		return err
+	}
+	batch, err := findEditableBatch(l.svcCtx.DB, agentID, req.ID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	return l.svcCtx.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.SendBatch{}).
+			Where("id = ? AND send_start_time IS NULL AND cancel_time IS NULL", batch.ID).
+			Update("cancel_time", now)
+		if result.Error != nil {
+			l.Logger.Errorf("update send batch cancel time failed, err: %v", result.Error)
+			return errs.ErrDB
+		}
+		if result.RowsAffected == 0 {
+			return errs.ErrBatchStarted
+		}
+		ids, err := lockPendingRecords(tx, batch.ID)
+		if err != nil {
+			return err
+		}
+		if _, err := models.TransitionSendRecords(tx, ids, models.SendRecordStatusCancel, nil); err != nil {
+			l.Logger.Errorf("update send record status failed, err: %v", err)
+			return errs.ErrDB
+		}
+		return nil
+	})
+}
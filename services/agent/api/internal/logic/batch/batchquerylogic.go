@@ -0,0 +1,53 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package batch
+
+import (
+	"chihqiang/msgbox-go/services/agent/api/internal/svc"
+	"chihqiang/msgbox-go/services/agent/api/internal/types"
+	"chihqiang/msgbox-go/services/common/errs"
+	"chihqiang/msgbox-go/services/common/models"
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type BatchQueryLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewBatchQueryLogic(ctx context.Context, svcCtx *svc.ServiceContext) *BatchQueryLogic {
+	return &BatchQueryLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// BatchQuery 查询全部发送批次，按创建时间倒序，不含管理后台的测试发送
+func (l *BatchQueryLogic) BatchQuery(req *types.BatchQueryReq) (resp *types.BatchQueryResp, err error) {
+	agentID, err := types.GetAgentID(l.ctx)
+	if err != nil {
+		return nil, err
+	}
+	db := l.svcCtx.DB.Model(&models.SendBatch{}).Preload("Channel").Preload("Template").Where("agent_id = ? AND test = ?", agentID, false)
+	if req.Keywords != "" {
+		keyword := "%" + req.Keywords + "%"
+		db = db.Where("batch_no LIKE ? OR trace_id LIKE ?", keyword, keyword)
+	}
+	total, batches, err := models.Page[models.SendBatch](db.Order("id DESC"), req.Page, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	items, err := convertBatches(l.svcCtx.DB, batches)
+	if err != nil {
+		l.Logger.Errorf("convert send batches failed, err: %v", err)
+		return nil, errs.ErrDB
+	}
+	return &types.BatchQueryResp{
+		Total: total,
+		Data:  items,
+	}, nil
+}
@@ -0,0 +1,72 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package batch
+
+import (
+	"chihqiang/msgbox-go/pkg/timex"
+	"chihqiang/msgbox-go/services/agent/api/internal/svc"
+	"chihqiang/msgbox-go/services/agent/api/internal/types"
+	"chihqiang/msgbox-go/services/common/errs"
+	"chihqiang/msgbox-go/services/common/models"
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+	"time"
+)
+
+type BatchRescheduleLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewBatchRescheduleLogic(ctx context.Context, svcCtx *svc.ServiceContext) *BatchRescheduleLogic {
+	return &BatchRescheduleLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// BatchReschedule 修改计划发送时间，批次开始发送、已有记录被 worker 领取或取消后不可修改
+func (l *BatchRescheduleLogic) BatchReschedule(req *types.BatchRescheduleReq) error {
+	agentID, err := types.GetAgentID(l.ctx)
+	if err != nil {
+		return err
+	}
+	sendAt, err := timex.ParseDateTime(req.SendAt)
+	if err != nil || sendAt.Before(time.Now()) {
+		return errs.ErrSendAtInvalid
+	}
+	batch, err := findEditableBatch(l.svcCtx.DB, agentID, req.ID)
+	if err != nil {
+		return err
+	}
+	return l.svcCtx.DB.Transaction(func(tx *gorm.DB) error {
+		// 条件更新，避免与 worker 开始发送产生竞争
+		result := tx.Model(&models.SendBatch{}).
+			Where("id = ? AND send_start_time IS NULL AND cancel_time IS NULL", batch.ID).
+			Update("scheduled_time", sendAt)
+		if result.Error != nil {
+			l.Logger.Errorf("update send batch scheduled time failed, err: %v", result.Error)
+			return errs.ErrDB
+		}
+		if result.RowsAffected == 0 {
+			return errs.ErrBatchStarted
+		}
+		ids, err := lockPendingRecords(tx, batch.ID)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Model(&models.SendRecord{}).Where("id IN ?", ids).
+			Update("queue_time", sendAt).Error; err != nil {
+			l.Logger.Errorf("update send record queue time failed, err: %v", err)
+			return errs.ErrDB
+		}
+		return nil
+	})
+}
@@ -0,0 +1,55 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package batch
+
+import (
+	"chihqiang/msgbox-go/services/agent/api/internal/svc"
+	"chihqiang/msgbox-go/services/agent/api/internal/types"
+	"chihqiang/msgbox-go/services/common/errs"
+	"chihqiang/msgbox-go/services/common/models"
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type BatchScheduledLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewBatchScheduledLogic(ctx context.Context, svcCtx *svc.ServiceContext) *BatchScheduledLogic {
+	return &BatchScheduledLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// BatchScheduled 查询尚未开始发送且未取消的异步/定时批次，按计划发送时间排序
+func (l *BatchScheduledLogic) BatchScheduled(req *types.BatchScheduledReq) (resp *types.BatchScheduledResp, err error) {
+	agentID, err := types.GetAgentID(l.ctx)
+	if err != nil {
+		return nil, err
+	}
+	db := l.svcCtx.DB.Model(&models.SendBatch{}).Preload("Channel").Preload("Template").
+		Where("agent_id = ? AND async = ? AND test = ?", agentID, true, false).
+		Where("send_start_time IS NULL AND cancel_time IS NULL")
+	if req.Keywords != "" {
+		keyword := "%" + req.Keywords + "%"
+		db = db.Where("batch_no LIKE ?", keyword)
+	}
+	total, batches, err := models.Page[models.SendBatch](db.Order("scheduled_time"), req.Page, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	items, err := convertBatches(l.svcCtx.DB, batches)
+	if err != nil {
+		l.Logger.Errorf("convert send batches failed, err: %v", err)
+		return nil, errs.ErrDB
+	}
+	return &types.BatchScheduledResp{
+		Total: total,
+		Data:  items,
+	}, nil
+}
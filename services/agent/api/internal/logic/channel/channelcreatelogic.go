@@ -42,12 +42,18 @@ func (l *ChannelCreateLogic) ChannelCreate(req *types.ChannelCreateReq) error {
 	}
 	if err = l.svcCtx.DB.Model(&models.Channel{}).Create(
 		&models.Channel{
-			AgentID:    agentID,
-			Code:       req.Code,
-			Name:       req.Name,
-			VendorName: req.VendorName,
-			Config:     models.MapToDataTypesJSON(req.Config),
-			Status:     req.Status,
+			AgentID:          agentID,
+			Code:             req.Code,
+			Name:             req.Name,
+			VendorName:       req.VendorName,
+			Config:           models.MapToDataTypesJSON(req.Config),
+			Timeout:          req.Timeout,
+			MergeMention:     req.MergeMention,
+			RetryMaxAttempts: req.RetryMaxAttempts,
+			RetryBackoff:     req.RetryBackoff,
+			RetryInterval:    req.RetryInterval,
+			RetryCodes:       req.RetryCodes,
+			Status:           req.Status,
 		},
 	).Error; err != nil {
 		return err
@@ -53,16 +53,23 @@ func (l ChannelQueryLogic) convert(channels []models.Channel) []types.ChannelIte
 	for _, item := range channels {
 		vendor, _ := senders.Get(item.VendorName)
 		items = append(items, types.ChannelItemResp{
-			ID:              item.ID,
-			AgentID:         item.AgentID,
-			Code:            item.Code,
-			Name:            item.Name,
-			VendorName:      item.VendorName,
-			VendorNameLabel: vendor.Label,
-			Config:          models.DataTypesToMap(item.Config),
-			Status:          item.Status,
-			CreatedAt:       timex.FormatDate(item.CreatedAt),
-			UpdatedAt:       timex.FormatDate(item.UpdatedAt),
+			ID:               item.ID,
+			AgentID:          item.AgentID,
+			Code:             item.Code,
+			Name:             item.Name,
+			VendorName:       item.VendorName,
+			VendorNameLabel:  vendor.Label,
+			Config:           models.DataTypesToMap(item.Config),
+			Timeout:          item.Timeout,
+			MergeMention:     item.MergeMention,
+			RetryMaxAttempts: item.RetryMaxAttempts,
+			RetryBackoff:     item.RetryBackoff,
+			RetryInterval:    item.RetryInterval,
+			RetryCodes:       item.RetryCodes,
+			ReceiptToken:     item.ReceiptToken,
+			Status:           item.Status,
+			CreatedAt:        timex.FormatDate(item.CreatedAt),
+			UpdatedAt:        timex.FormatDate(item.UpdatedAt),
 		})
 	}
 	return items
@@ -51,5 +51,35 @@ func (l *ChannelUpdateLogic) ChannelUpdate(req *types.ChannelUpdateReq) error {
 	if err := l.svcCtx.DB.Model(&models.Channel{}).Where(models.Channel{ID: req.ID, AgentID: agentID}).Updates(updateData).Error; err != nil {
 		return err
 	}
+	// 超时时间允许改回 0（使用默认值），struct 更新会忽略零值，单独更新
+	if req.Timeout != nil {
+		if err := l.svcCtx.DB.Model(&models.Channel{}).Where(models.Channel{ID: req.ID, AgentID: agentID}).Update("timeout", *req.Timeout).Error; err != nil {
+			return err
+		}
+	}
+	// 重试策略允许清空限流错误码，使用 map 更新以保留零值
+	retry := make(map[string]interface{})
+	if req.RetryMaxAttempts != nil {
+		retry["retry_max_attempts"] = max(*req.RetryMaxAttempts, 1)
+	}
+	if req.RetryBackoff != nil {
+		retry["retry_backoff"] = *req.RetryBackoff
+	}
+	if req.RetryInterval != nil {
+		retry["retry_interval"] = *req.RetryInterval
+	}
+	if req.RetryCodes != nil {
+		retry["retry_codes"] = *req.RetryCodes
+	}
+	if len(retry) > 0 {
+		if err := l.svcCtx.DB.Model(&models.Channel{}).Where(models.Channel{ID: req.ID, AgentID: agentID}).Updates(retry).Error; err != nil {
+			return err
+		}
+	}
+	if req.MergeMention != nil {
+		if err := l.svcCtx.DB.Model(&models.Channel{}).Where(models.Channel{ID: req.ID, AgentID: agentID}).Update("merge_mention", *req.MergeMention).Error; err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,59 @@
+package record
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"chihqiang/msgbox-go/services/agent/api/internal/svc"
+	"chihqiang/msgbox-go/services/agent/api/internal/types"
+	"chihqiang/msgbox-go/services/common/models"
+	"chihqiang/msgbox-go/services/common/models/modeltest"
+
+	"gorm.io/datatypes"
+)
+
+// TestRecordQueryExcludesTestSends 管理后台的测试发送不出现在发送记录列表与批次统计中
+func TestRecordQueryExcludesTestSends(t *testing.T) {
+	db := modeltest.NewDB(t)
+	agent := &models.Agent{Email: "tester@example.com", Password: "x", Status: true}
+	if err := db.Create(agent).Error; err != nil {
+		t.Fatal(err)
+	}
+	channel := &models.Channel{AgentID: agent.ID, Code: "hook", Name: "hook", VendorName: "webhook", Config: datatypes.JSON(`{}`), Status: true}
+	if err := db.Create(channel).Error; err != nil {
+		t.Fatal(err)
+	}
+	batchIDs := make([]int64, 0, 2)
+	for _, test := range []bool{false, true} {
+		batchNo := "B" + strconv.FormatBool(test)
+		batch := &models.SendBatch{AgentID: agent.ID, ChannelID: channel.ID, BatchNo: batchNo, TraceID: batchNo, TotalCount: 1, Test: test}
+		if err := db.Create(batch).Error; err != nil {
+			t.Fatal(err)
+		}
+		record := &models.SendRecord{
+			BatchID: batch.ID, AgentID: agent.ID, ChannelID: channel.ID, TraceID: batchNo, Receiver: batchNo,
+			VendorName: "webhook", ChannelConfig: datatypes.JSON(`{}`), Content: "hi", Status: models.SendRecordStatusAccepted,
+		}
+		if err := db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+		batchIDs = append(batchIDs, batch.ID)
+	}
+	ctx := context.WithValue(context.Background(), types.JWTAgentID, json.Number(strconv.FormatInt(agent.ID, 10)))
+	resp, err := NewRecordQueryLogic(ctx, &svc.ServiceContext{DB: db}).RecordQuery(&types.RecordQueryReq{PaginationReq: types.PaginationReq{Page: 1, Size: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Total != 1 || len(resp.Data) != 1 || resp.Data[0].Receiver != "Bfalse" {
+		t.Errorf("records = %+v, total = %d, want only the non-test record", resp.Data, resp.Total)
+	}
+	stats, err := models.QuerySendBatchChannelStats(db, batchIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].BatchID != batchIDs[0] {
+		t.Errorf("stats = %+v, want only the non-test batch", stats)
+	}
+}
@@ -0,0 +1,58 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package record
+
+import (
+	"chihqiang/msgbox-go/pkg/timex"
+	"chihqiang/msgbox-go/services/agent/api/internal/svc"
+	"chihqiang/msgbox-go/services/agent/api/internal/types"
+	"chihqiang/msgbox-go/services/common/models"
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type RecordAttemptsLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewRecordAttemptsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *RecordAttemptsLogic {
+	return &RecordAttemptsLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// RecordAttempts 按发送顺序返回记录的每次发送尝试及失败原因
+func (l *RecordAttemptsLogic) RecordAttempts(req *types.RecordAttemptsReq) (resp *types.RecordAttemptsResp, err error) {
+	agentID, err := types.GetAgentID(l.ctx)
+	if err != nil {
+		return nil, err
+	}
+	var attempts []models.SendAttempt
+	if err := l.svcCtx.DB.Where(&models.SendAttempt{RecordID: req.ID, AgentID: agentID}).
+		Order("attempt, id").Find(&attempts).Error; err != nil {
+		return nil, err
+	}
+	items := make([]types.RecordAttemptItemResp, 0, len(attempts))
+	for _, item := range attempts {
+		items = append(items, types.RecordAttemptItemResp{
+			ID:              item.ID,
+			Attempt:         item.Attempt,
+			ChannelID:       item.ChannelID,
+			VendorName:      item.VendorName,
+			Status:          item.Status,
+			StatusMsg:       item.StatusMsg(),
+			Error:           item.Error,
+			FailReason:      item.FailReason,
+			Response:        models.DataTypesToMap(item.Response),
+			StartTime:       timex.FormatDate(item.StartTime),
+			EndTime:         timex.FormatDate(item.EndTime),
+			NextAttemptTime: timex.FormatDate(item.NextAttemptTime),
+		})
+	}
+	return &types.RecordAttemptsResp{Data: items}, nil
+}
@@ -31,7 +31,8 @@ func (l *RecordQueryLogic) RecordQuery(req *types.RecordQueryReq) (resp *types.R
 	if err != nil {
 		return nil, err
 	}
-	db := l.svcCtx.DB.Model(&models.SendRecord{}).Preload("Channel").Where("agent_id = ?", agentID)
+	db := l.svcCtx.DB.Model(&models.SendRecord{}).Preload("Channel").Preload("TemplateVersion").
+		Where("agent_id = ?", agentID).Scopes(models.WithoutTestSends)
 	if req.Keywords != "" {
 		keyword := "%" + req.Keywords + "%"
 		db = db.Where("receiver LIKE ?", keyword)
@@ -49,28 +50,39 @@ func (l *RecordQueryLogic) RecordQuery(req *types.RecordQueryReq) (resp *types.R
 func (l RecordQueryLogic) convert(channels []models.SendRecord) []types.RecordItemResp {
 	items := make([]types.RecordItemResp, 0, len(channels))
 	for _, item := range channels {
+		var templateVersion int
+		if item.TemplateVersion != nil {
+			templateVersion = item.TemplateVersion.Version
+		}
 		items = append(items, types.RecordItemResp{
-			ID:            item.ID,
-			Receiver:      item.Receiver,
-			TraceID:       item.TraceID,
-			ChannelName:   item.Channel.Name,
-			ChannelConfig: models.DataTypesToMap(item.ChannelConfig),
-			VendorName:    item.VendorName,
-			VendorCode:    item.VendorCode,
-			Signature:     item.Signature,
-			Title:         item.Title,
-			Content:       item.Content,
-			Variables:     models.DataTypesToMap(item.Variables),
-			Extra:         models.DataTypesToMap(item.Extra),
-			Status:        item.Status,
-			StatusMsg:     item.StatusMsg(),
-			SendTime:      timex.FormatDate(item.SendTime),
-			Error:         item.Error,
-			Response:      models.DataTypesToMap(item.Response),
-			DeliveryTime:  timex.FormatDate(item.DeliveryTime),
-			DeliveryRaw:   models.DataTypesToMap(item.DeliveryRaw),
-			CreatedAt:     timex.FormatDate(item.CreatedAt),
-			UpdatedAt:     timex.FormatDate(item.UpdatedAt),
+			ID:              item.ID,
+			Receiver:        item.Receiver,
+			TraceID:         item.TraceID,
+			TemplateID:      item.TemplateID,
+			TemplateVersion: templateVersion,
+			ChannelName:     item.Channel.Name,
+			ChannelConfig:   models.DataTypesToMap(item.ChannelConfig),
+			VendorName:      item.VendorName,
+			Locale:          item.Locale,
+			VendorCode:      item.VendorCode,
+			Signature:       item.Signature,
+			Title:           item.Title,
+			Content:         item.Content,
+			Variables:       models.DataTypesToMap(item.Variables),
+			Extra:           models.DataTypesToMap(item.Extra),
+			Status:          item.Status,
+			StatusMsg:       item.StatusMsg(),
+			SendTime:        timex.FormatDate(item.SendTime),
+			Attempts:        item.Attempts,
+			NextAttemptTime: timex.FormatDate(item.NextAttemptTime),
+			Error:           item.Error,
+			FailReason:      item.FailReason,
+			VendorMsgID:     item.VendorMsgID,
+			Response:        models.DataTypesToMap(item.Response),
+			DeliveryTime:    timex.FormatDate(item.DeliveryTime),
+			DeliveryRaw:     models.DataTypesToMap(item.DeliveryRaw),
+			CreatedAt:       timex.FormatDate(item.CreatedAt),
+			UpdatedAt:       timex.FormatDate(item.UpdatedAt),
 		})
 	}
 	return items
@@ -0,0 +1,343 @@
+package template
+
+import (
+	"chihqiang/msgbox-go/pkg/templatex"
+	"chihqiang/msgbox-go/pkg/timex"
+	"chihqiang/msgbox-go/services/agent/api/internal/types"
+	"chihqiang/msgbox-go/services/common/channels/senders"
+	"chihqiang/msgbox-go/services/common/models"
+	"errors"
+	"fmt"
+
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+// checkSyntax 校验标题与内容的模版语法，保存时提前发现错误，避免发送时才失败
+func checkSyntax(title, content string) error {
+	if _, err := templatex.Parse(title); err != nil {
+		return fmt.Errorf("模版标题语法错误: %v", err)
+	}
+	if _, err := templatex.Parse(content); err != nil {
+		return fmt.Errorf("模版内容语法错误: %v", err)
+	}
+	return nil
+}
+
+// extractVariables 从模版及扇出通道、语言变体的标题与内容，以及主通道、备用通道与扇出通道的配置中提取变量声明，
+// items 中同名变量的类型与校验规则保持不变，手动添加的变量即使未被引用也保留
+func extractVariables(db *gorm.DB, agentID int64, template *models.Template, fanouts []types.TemplateFanoutItem, locales []types.TemplateLocaleItem, items []types.TemplateVariableItem) ([]models.TemplateVariable, error) {
+	declared := make([]models.TemplateVariable, 0, len(items))
+	for _, item := range items {
+		declared = append(declared, models.TemplateVariable{
+			Name:        item.Name,
+			Type:        item.Type,
+			Required:    item.Required,
+			Pattern:     item.Pattern,
+			MinLength:   item.MinLength,
+			MaxLength:   item.MaxLength,
+			Description: item.Description,
+			Manual:      item.Manual,
+		})
+	}
+	sources := []string{template.Title, template.Content}
+	channelIDs := template.ChannelIDs()
+	for _, fanout := range fanouts {
+		sources = append(sources, fanout.Title, fanout.Content)
+		channelIDs = append(channelIDs, fanout.ChannelID)
+	}
+	for _, locale := range locales {
+		sources = append(sources, locale.Title, locale.Content)
+	}
+	refs, err := channelVariables(db, agentID, channelIDs)
+	if err != nil {
+		return nil, err
+	}
+	return models.ExtractVariables(declared, refs, sources...)
+}
+
+// channelVariables 通道配置中引用的模版变量（如 webhook 请求体模板），按 ids 的顺序合并
+func channelVariables(db *gorm.DB, agentID int64, ids []int64) ([]templatex.Variable, error) {
+	var channels []*models.Channel
+	if err := db.Where("id IN ? AND agent_id = ?", lo.Uniq(ids), agentID).Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	byID := lo.KeyBy(channels, func(channel *models.Channel) int64 { return channel.ID })
+	refs := make([]templatex.Variable, 0)
+	for _, id := range lo.Uniq(ids) {
+		channel, ok := byID[id]
+		if !ok {
+			continue
+		}
+		form, ok := senders.Get(channel.VendorName)
+		if !ok {
+			continue
+		}
+		sender, err := form.Sender(models.DataTypesToMap(channel.Config))
+		if err != nil {
+			return nil, fmt.Errorf("通道 %s 配置无效: %v", channel.Name, err)
+		}
+		if vs, ok := sender.(senders.IVariableSender); ok {
+			vars, err := vs.Variables()
+			if err != nil {
+				return nil, fmt.Errorf("通道 %s 配置的模版语法错误: %v", channel.Name, err)
+			}
+			refs = append(refs, vars...)
+		}
+	}
+	return refs, nil
+}
+
+// variableItems 变量声明转换为接口结构
+func variableItems(vars []models.TemplateVariable) []types.TemplateVariableItem {
+	items := make([]types.TemplateVariableItem, 0, len(vars))
+	for _, v := range vars {
+		items = append(items, types.TemplateVariableItem{
+			Name:        v.Name,
+			Type:        v.Type,
+			Required:    v.Required,
+			Pattern:     v.Pattern,
+			MinLength:   v.MinLength,
+			MaxLength:   v.MaxLength,
+			Description: v.Description,
+			Manual:      v.Manual,
+		})
+	}
+	return items
+}
+
+// findTemplate 查询当前代理商的模版及其主通道、扇出通道与语言变体
+func findTemplate(db *gorm.DB, agentID, id int64) (*models.Template, error) {
+	var template models.Template
+	err := db.Where("id = ? AND agent_id = ?", id, agentID).
+		Preload("Channel", "agent_id = ?", agentID).
+		Preload("Fanouts", func(db *gorm.DB) *gorm.DB { return db.Order("sort, id") }).
+		Preload("Fanouts.Channel", "agent_id = ?", agentID).
+		Preload("Locales", func(db *gorm.DB) *gorm.DB { return db.Order("channel_id, locale") }).
+		First(&template).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("模版不存在")
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// render 按变量声明校验示例变量后渲染模版，与网关发送时的校验和渲染一致
+func render(template *models.Template, variables map[string]interface{}) (title, content string, err error) {
+	if err := models.CheckVariables(template.Variables, variables); err != nil {
+		return "", "", fmt.Errorf("模版变量校验失败: %v", err)
+	}
+	if title, content, err = template.Render(variables); err != nil {
+		return "", "", fmt.Errorf("模版渲染失败: %v", err)
+	}
+	return title, content, nil
+}
+
+// checkFallbackChannels 校验备用通道均属于当前代理商，去重并排除主通道，保持传入顺序
+func checkFallbackChannels(db *gorm.DB, agentID, channelID int64, ids []int64) ([]int64, error) {
+	fallbackChannelIDs := (&models.Template{ChannelID: channelID, FallbackChannelIDs: ids}).ChannelIDs()[1:]
+	if len(fallbackChannelIDs) == 0 {
+		return fallbackChannelIDs, nil
+	}
+	var count int64
+	if err := db.Model(&models.Channel{}).Where("id IN ? AND agent_id = ?", fallbackChannelIDs, agentID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if int(count) != len(fallbackChannelIDs) {
+		return nil, errors.New("指定的备用通道不存在")
+	}
+	return fallbackChannelIDs, nil
+}
+
+// checkFanouts 校验扇出通道并转换为模型，扇出通道需属于当前代理商且不能与主通道重复
+func checkFanouts(db *gorm.DB, agentID, channelID int64, items []types.TemplateFanoutItem) ([]*models.TemplateFanout, error) {
+	fanouts := make([]*models.TemplateFanout, 0, len(items))
+	seen := map[int64]bool{channelID: true}
+	for i, item := range items {
+		if seen[item.ChannelID] {
+			return nil, errors.New("扇出通道不能重复且不能与主通道相同")
+		}
+		seen[item.ChannelID] = true
+		if err := checkSyntax(item.Title, item.Content); err != nil {
+			return nil, err
+		}
+		var count int64
+		if err := db.Model(&models.Channel{}).Where(&models.Channel{ID: item.ChannelID, AgentID: agentID}).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, errors.New("指定的扇出通道不存在")
+		}
+		fanouts = append(fanouts, &models.TemplateFanout{
+			AgentID:    agentID,
+			ChannelID:  item.ChannelID,
+			VendorCode: item.VendorCode,
+			Signature:  item.Signature,
+			MsgType:    item.MsgType,
+			Title:      item.Title,
+			Content:    item.Content,
+			Sort:       i,
+		})
+	}
+	return fanouts, nil
+}
+
+// replaceFanouts 以校验后的列表整体替换模版的扇出通道
+func replaceFanouts(tx *gorm.DB, templateID int64, fanouts []*models.TemplateFanout) error {
+	if err := tx.Where(&models.TemplateFanout{TemplateID: templateID}).Delete(&models.TemplateFanout{}).Error; err != nil {
+		return err
+	}
+	if len(fanouts) == 0 {
+		return nil
+	}
+	for _, fanout := range fanouts {
+		fanout.TemplateID = templateID
+	}
+	return tx.Create(&fanouts).Error
+}
+
+// checkLocales 校验语言变体并转换为模型，语言统一写法后在同一通道内不能重复，
+// 指定通道的语言变体需对应模版的扇出通道
+func checkLocales(agentID int64, fanouts []types.TemplateFanoutItem, items []types.TemplateLocaleItem) ([]*models.TemplateLocale, error) {
+	channels := map[int64]bool{0: true}
+	for _, fanout := range fanouts {
+		channels[fanout.ChannelID] = true
+	}
+	locales := make([]*models.TemplateLocale, 0, len(items))
+	seen := map[string]bool{}
+	for _, item := range items {
+		locale, ok := models.NormalizeLocale(item.Locale)
+		if !ok {
+			return nil, fmt.Errorf("语言 %q 无效，请使用 en、zh-TW 等格式", item.Locale)
+		}
+		if !channels[item.ChannelID] {
+			return nil, fmt.Errorf("语言 %s 指定的通道 %d 不是模版的扇出通道", locale, item.ChannelID)
+		}
+		key := fmt.Sprintf("%d:%s", item.ChannelID, locale)
+		if seen[key] {
+			return nil, fmt.Errorf("语言 %s 重复", locale)
+		}
+		seen[key] = true
+		if err := checkSyntax(item.Title, item.Content); err != nil {
+			return nil, fmt.Errorf("语言 %s: %v", locale, err)
+		}
+		locales = append(locales, &models.TemplateLocale{
+			AgentID:    agentID,
+			ChannelID:  item.ChannelID,
+			Locale:     locale,
+			VendorCode: item.VendorCode,
+			Signature:  item.Signature,
+			Title:      item.Title,
+			Content:    item.Content,
+		})
+	}
+	return locales, nil
+}
+
+// replaceLocales 以校验后的列表整体替换模版的语言变体
+func replaceLocales(tx *gorm.DB, templateID int64, locales []*models.TemplateLocale) error {
+	if err := tx.Where(&models.TemplateLocale{TemplateID: templateID}).Delete(&models.TemplateLocale{}).Error; err != nil {
+		return err
+	}
+	if len(locales) == 0 {
+		return nil
+	}
+	for _, locale := range locales {
+		locale.TemplateID = templateID
+	}
+	return tx.Create(&locales).Error
+}
+
+// localeItems 语言变体转换为接口结构
+func localeItems(locales []*models.TemplateLocale) []types.TemplateLocaleItem {
+	items := make([]types.TemplateLocaleItem, 0, len(locales))
+	for _, locale := range locales {
+		items = append(items, types.TemplateLocaleItem{
+			ChannelID:  locale.ChannelID,
+			Locale:     locale.Locale,
+			VendorCode: locale.VendorCode,
+			Signature:  locale.Signature,
+			Title:      locale.Title,
+			Content:    locale.Content,
+		})
+	}
+	return items
+}
+
+// saveVersion 以模版当前的发送内容生成新版本，修改人为当前代理商；内容未变化时不生成版本
+func saveVersion(db *gorm.DB, agentID, id int64, remark string) error {
+	template, err := findTemplate(db, agentID, id)
+	if err != nil {
+		return err
+	}
+	var agent models.Agent
+	if err := db.Select("id", "name", "email").Where(&models.Agent{ID: agentID}).First(&agent).Error; err != nil {
+		return err
+	}
+	author := agent.Name
+	if author == "" {
+		author = agent.Email
+	}
+	_, err = models.SaveTemplateVersion(db, template, agentID, author, remark)
+	return err
+}
+
+// findVersion 查询模版的指定版本号
+func findVersion(db *gorm.DB, templateID int64, version int) (*models.TemplateVersion, error) {
+	var v models.TemplateVersion
+	if err := db.Where(&models.TemplateVersion{TemplateID: templateID, Version: version}).First(&v).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("模版版本 %d 不存在", version)
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// versionItem 版本快照转换为接口结构，currentID 为模版当前使用的版本ID
+func versionItem(v *models.TemplateVersion, currentID int64) types.TemplateVersionItem {
+	fanouts := make([]types.TemplateFanoutItem, 0, len(v.Fanouts))
+	for _, fanout := range v.Fanouts {
+		fanouts = append(fanouts, types.TemplateFanoutItem{
+			ChannelID:  fanout.ChannelID,
+			VendorCode: fanout.VendorCode,
+			Signature:  fanout.Signature,
+			MsgType:    fanout.MsgType,
+			Title:      fanout.Title,
+			Content:    fanout.Content,
+		})
+	}
+	locales := make([]types.TemplateLocaleItem, 0, len(v.Locales))
+	for _, locale := range v.Locales {
+		locales = append(locales, types.TemplateLocaleItem{
+			ChannelID:  locale.ChannelID,
+			Locale:     locale.Locale,
+			VendorCode: locale.VendorCode,
+			Signature:  locale.Signature,
+			Title:      locale.Title,
+			Content:    locale.Content,
+		})
+	}
+	return types.TemplateVersionItem{
+		ID:                 v.ID,
+		TemplateID:         v.TemplateID,
+		Version:            v.Version,
+		ChannelID:          v.ChannelID,
+		FallbackChannelIDs: v.FallbackChannelIDs,
+		Fanouts:            fanouts,
+		Locales:            locales,
+		VendorCode:         v.VendorCode,
+		Signature:          v.Signature,
+		MsgType:            v.MsgType,
+		Title:              v.Title,
+		Content:            v.Content,
+		Variables:          variableItems(v.Variables),
+		AuthorID:           v.AuthorID,
+		Author:             v.Author,
+		Remark:             v.Remark,
+		Current:            v.ID == currentID,
+		CreatedAt:          timex.FormatDate(v.CreatedAt),
+	}
+}
@@ -0,0 +1,391 @@
+package template
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"chihqiang/msgbox-go/services/agent/api/internal/svc"
+	"chihqiang/msgbox-go/services/agent/api/internal/types"
+	"chihqiang/msgbox-go/services/common/models"
+	"chihqiang/msgbox-go/services/common/models/modeltest"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// fixture 测试用的代理商、通道与服务上下文
+type fixture struct {
+	svcCtx  *svc.ServiceContext
+	ctx     context.Context
+	agent   *models.Agent
+	channel *models.Channel
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	db := modeltest.NewDB(t)
+	agent := &models.Agent{AgentNo: "A1", Name: "tester", Email: "tester@example.com", Password: "x", Status: true}
+	if err := db.Create(agent).Error; err != nil {
+		t.Fatal(err)
+	}
+	channel := &models.Channel{AgentID: agent.ID, Code: "hook", Name: "hook", VendorName: "webhook", Config: datatypes.JSON(`{}`), Status: true}
+	if err := db.Create(channel).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &fixture{
+		svcCtx:  &svc.ServiceContext{DB: db},
+		ctx:     context.WithValue(context.Background(), types.JWTAgentID, json.Number(strconv.FormatInt(agent.ID, 10))),
+		agent:   agent,
+		channel: channel,
+	}
+}
+
+// createTemplate 通过创建接口创建模版并返回保存后的记录
+func (f *fixture) createTemplate(t *testing.T, req types.TemplateCreateReq) *models.Template {
+	t.Helper()
+	if req.ChannelID == 0 {
+		req.ChannelID = f.channel.ID
+	}
+	if err := NewTemplateCreateLogic(f.ctx, f.svcCtx).TemplateCreate(&req); err != nil {
+		t.Fatalf("create template failed: %v", err)
+	}
+	return f.template(t, req.Code)
+}
+
+// template 按编码查询模版，预加载扇出通道与语言变体
+func (f *fixture) template(t *testing.T, code string) *models.Template {
+	t.Helper()
+	var template models.Template
+	err := f.svcCtx.DB.Preload("Fanouts").Preload("Locales").
+		Where(&models.Template{AgentID: f.agent.ID, Code: code}).First(&template).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &template
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestTemplateUpdateClearFields(t *testing.T) {
+	f := newFixture(t)
+	created := f.createTemplate(t, types.TemplateCreateReq{
+		Name: "notice", Code: "notice", Title: "Hi ${name}", MsgType: "markdown",
+		VendorCode: "SMS_1", Signature: "sign", Content: "hello ${name}", Status: true,
+	})
+	err := NewTemplateUpdateLogic(f.ctx, f.svcCtx).TemplateUpdate(&types.TemplateUpdateReq{
+		ID: created.ID, Title: ptr(""), MsgType: ptr(""), VendorCode: ptr(""), Signature: ptr(""), Status: ptr(false),
+	})
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	updated := f.template(t, "notice")
+	if updated.Title != "" || updated.MsgType != "" || updated.VendorCode != "" || updated.Signature != "" {
+		t.Errorf("fields not cleared: %+v", updated)
+	}
+	if updated.Status {
+		t.Errorf("status not disabled")
+	}
+	// 未传的字段保持不变
+	if updated.Content != "hello ${name}" || updated.Name != "notice" || updated.ChannelID != f.channel.ID {
+		t.Errorf("untouched fields changed: %+v", updated)
+	}
+}
+
+// addChannel 为当前代理商再创建一个通道，用作扇出通道
+func (f *fixture) addChannel(t *testing.T, code, vendor string) *models.Channel {
+	t.Helper()
+	channel := &models.Channel{AgentID: f.agent.ID, Code: code, Name: code, VendorName: vendor, Config: datatypes.JSON(`{}`), Status: true}
+	if err := f.svcCtx.DB.Create(channel).Error; err != nil {
+		t.Fatal(err)
+	}
+	return channel
+}
+
+func (f *fixture) count(t *testing.T, model any) int64 {
+	t.Helper()
+	var count int64
+	if err := f.svcCtx.DB.Model(model).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestTemplateFanoutLocales(t *testing.T) {
+	f := newFixture(t)
+	email := f.addChannel(t, "mail", "email")
+	// 语言变体指定的通道不是扇出通道时整体不创建
+	err := NewTemplateCreateLogic(f.ctx, f.svcCtx).TemplateCreate(&types.TemplateCreateReq{
+		Name: "bad", Code: "bad", ChannelID: f.channel.ID, Content: "hi",
+		Locales: []types.TemplateLocaleItem{{ChannelID: email.ID, Locale: "zh", Content: "你好"}},
+	})
+	if err == nil {
+		t.Fatal("create with locale of unknown fanout succeeded")
+	}
+	if n := f.count(t, &models.Template{}); n != 0 {
+		t.Fatalf("template created despite invalid locale, count = %d", n)
+	}
+
+	created := f.createTemplate(t, types.TemplateCreateReq{
+		Name: "notice", Code: "notice", Content: "hi ${name}", Status: true,
+		Fanouts: []types.TemplateFanoutItem{{ChannelID: email.ID, Content: "mail ${name}"}},
+		Locales: []types.TemplateLocaleItem{
+			{Locale: "zh", Content: "你好 ${name}"},
+			{ChannelID: email.ID, Locale: "zh", Content: "邮件 ${name}"},
+		},
+	})
+	if len(created.Locales) != 2 {
+		t.Fatalf("locales = %+v", created.Locales)
+	}
+	created.Fanouts[0].Channel = email
+	variants := created.Variants("zh-CN")
+	if variants[1].Content != "邮件 ${name}" || variants[1].Locale != "zh" {
+		t.Errorf("fanout variant = %q/%q, want the fanout's zh content", variants[1].Content, variants[1].Locale)
+	}
+
+	// 移除扇出通道时一并去掉该通道的语言变体
+	err = NewTemplateUpdateLogic(f.ctx, f.svcCtx).TemplateUpdate(&types.TemplateUpdateReq{ID: created.ID, Fanouts: []types.TemplateFanoutItem{}})
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	updated := f.template(t, "notice")
+	if len(updated.Fanouts) != 0 || len(updated.Locales) != 1 || updated.Locales[0].ChannelID != 0 {
+		t.Errorf("after removing fanout: fanouts=%d locales=%+v", len(updated.Fanouts), updated.Locales)
+	}
+}
+
+func TestTemplateUpdateAtomic(t *testing.T) {
+	f := newFixture(t)
+	created := f.createTemplate(t, types.TemplateCreateReq{Name: "notice", Code: "notice", Content: "hello", Status: true})
+	versions := f.count(t, &models.TemplateVersion{})
+	// 内容修改合法但语言变体不合法，整个修改都不生效
+	err := NewTemplateUpdateLogic(f.ctx, f.svcCtx).TemplateUpdate(&types.TemplateUpdateReq{
+		ID: created.ID, Content: ptr("changed"),
+		Locales: []types.TemplateLocaleItem{{ChannelID: 999, Locale: "en", Content: "changed"}},
+	})
+	if err == nil {
+		t.Fatal("update with invalid locale succeeded")
+	}
+	updated := f.template(t, "notice")
+	if updated.Content != "hello" || len(updated.Locales) != 0 {
+		t.Errorf("template changed by failed update: content=%q locales=%d", updated.Content, len(updated.Locales))
+	}
+	if n := f.count(t, &models.TemplateVersion{}); n != versions {
+		t.Errorf("versions = %d, want %d", n, versions)
+	}
+}
+
+// TestTemplateChannelVariables 变量声明合并通道请求体模板引用的变量，手动添加的变量在内容未引用时保留
+func TestTemplateChannelVariables(t *testing.T) {
+	f := newFixture(t)
+	body := `{"to":"${receiver}","text":"${content}","order":"${order_id}"}`
+	f.svcCtx.DB.Model(f.channel).Update("config", datatypes.JSON(`{"url":"http://127.0.0.1","body":`+strconv.Quote(body)+`}`))
+	created := f.createTemplate(t, types.TemplateCreateReq{
+		Name: "notice", Code: "notice", Content: "hi ${name}", Status: true,
+		Variables: []types.TemplateVariableItem{
+			{Name: "code", Type: models.VariableTypeNumber, Required: true, Manual: true},
+			{Name: "stale", Type: models.VariableTypeString},
+		},
+	})
+	names := func(vars []models.TemplateVariable) []string {
+		list := make([]string, 0, len(vars))
+		for _, v := range vars {
+			list = append(list, v.Name)
+		}
+		return list
+	}
+	if got, want := names(created.Variables), []string{"name", "order_id", "code"}; !slices.Equal(got, want) {
+		t.Fatalf("variables = %v, want %v", got, want)
+	}
+
+	// 通道与手动添加的变量均已声明，预览时不会被当作未声明变量拒绝
+	preview := NewTemplatePreviewLogic(f.ctx, f.svcCtx)
+	if _, err := preview.TemplatePreview(&types.TemplatePreviewReq{
+		ID: created.ID, Variables: map[string]interface{}{"name": "Tom", "order_id": "O1", "code": 1234},
+	}); err != nil {
+		t.Fatalf("preview with declared variables: %v", err)
+	}
+	if _, err := preview.TemplatePreview(&types.TemplatePreviewReq{
+		ID: created.ID, Variables: map[string]interface{}{"name": "Tom", "order_id": "O1", "code": 1234, "other": "x"},
+	}); err == nil || !strings.Contains(err.Error(), "variable other is not declared") {
+		t.Errorf("preview with undeclared variable: err = %v", err)
+	}
+
+	// 主通道换成不引用变量的通道后，通道变量随之移除，手动添加的变量仍保留
+	email := f.addChannel(t, "mail", "email")
+	err := NewTemplateUpdateLogic(f.ctx, f.svcCtx).TemplateUpdate(&types.TemplateUpdateReq{ID: created.ID, ChannelID: ptr(email.ID)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(f.template(t, "notice").Variables), []string{"name", "code"}; !slices.Equal(got, want) {
+		t.Errorf("variables after channel change = %v, want %v", got, want)
+	}
+}
+
+// version 查询模版当前使用的版本号
+func (f *fixture) version(t *testing.T, template *models.Template) int {
+	t.Helper()
+	var version models.TemplateVersion
+	if err := f.svcCtx.DB.First(&version, template.VersionID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return version.Version
+}
+
+func TestTemplateVersionDiff(t *testing.T) {
+	f := newFixture(t)
+	created := f.createTemplate(t, types.TemplateCreateReq{
+		Name: "notice", Code: "notice", Signature: "A", Title: "Hi", Content: "line1\nline2\nline3", Status: true,
+	})
+	err := NewTemplateUpdateLogic(f.ctx, f.svcCtx).TemplateUpdate(&types.TemplateUpdateReq{
+		ID: created.ID, Signature: ptr("B"), Content: ptr("line1\nchanged\nline3\nline4"), Name: ptr("renamed"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := NewTemplateVersionDiffLogic(f.ctx, f.svcCtx).TemplateVersionDiff(&types.TemplateVersionDiffReq{TemplateID: created.ID, From: 1, To: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.From.Version != 1 || resp.To.Version != 2 || resp.From.Current || !resp.To.Current {
+		t.Errorf("from=%+v to=%+v", resp.From, resp.To)
+	}
+	// 名称不属于发送内容，不出现在差异中
+	wantFields := []types.TemplateDiffField{{Field: "signature", From: "A", To: "B"}}
+	if !slices.Equal(resp.Fields, wantFields) {
+		t.Errorf("fields = %+v, want %+v", resp.Fields, wantFields)
+	}
+	wantTitle := []types.TemplateDiffLine{{Op: "=", Text: "Hi"}}
+	if !slices.Equal(resp.Title, wantTitle) {
+		t.Errorf("title = %+v, want %+v", resp.Title, wantTitle)
+	}
+	wantContent := []types.TemplateDiffLine{
+		{Op: "=", Text: "line1"}, {Op: "-", Text: "line2"}, {Op: "+", Text: "changed"}, {Op: "=", Text: "line3"}, {Op: "+", Text: "line4"},
+	}
+	if !slices.Equal(resp.Content, wantContent) {
+		t.Errorf("content = %+v, want %+v", resp.Content, wantContent)
+	}
+	if _, err := NewTemplateVersionDiffLogic(f.ctx, f.svcCtx).TemplateVersionDiff(&types.TemplateVersionDiffReq{TemplateID: created.ID, From: 1, To: 3}); err == nil {
+		t.Error("diff with missing version: want error")
+	}
+}
+
+func TestTemplateRollback(t *testing.T) {
+	f := newFixture(t)
+	email := f.addChannel(t, "mail", "email")
+	created := f.createTemplate(t, types.TemplateCreateReq{
+		Name: "notice", Code: "notice", Content: "v1 ${name}", Status: true,
+		Fanouts: []types.TemplateFanoutItem{{ChannelID: email.ID, Content: "mail v1"}},
+		Locales: []types.TemplateLocaleItem{{ChannelID: email.ID, Locale: "en", Content: "mail en"}},
+	})
+	err := NewTemplateUpdateLogic(f.ctx, f.svcCtx).TemplateUpdate(&types.TemplateUpdateReq{
+		ID: created.ID, Content: ptr("v2"), Fanouts: []types.TemplateFanoutItem{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rollback := NewTemplateRollbackLogic(f.ctx, f.svcCtx)
+	if err := rollback.TemplateRollback(&types.TemplateRollbackReq{TemplateID: created.ID, Version: 2}); err == nil {
+		t.Error("rollback to the current version: want error")
+	}
+	if err := rollback.TemplateRollback(&types.TemplateRollbackReq{TemplateID: created.ID, Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	restored := f.template(t, "notice")
+	if restored.Content != "v1 ${name}" || len(restored.Fanouts) != 1 || restored.Fanouts[0].Content != "mail v1" ||
+		len(restored.Locales) != 1 || restored.Locales[0].Content != "mail en" {
+		t.Errorf("restored = %q fanouts=%+v locales=%+v", restored.Content, restored.Fanouts, restored.Locales)
+	}
+	if len(restored.Variables) != 1 || restored.Variables[0].Name != "name" {
+		t.Errorf("restored variables = %+v", restored.Variables)
+	}
+	// 回滚生成新版本，历史版本保持不变
+	var latest models.TemplateVersion
+	f.svcCtx.DB.First(&latest, restored.VersionID)
+	if latest.Version != 3 || latest.Remark != "回滚至版本 1" {
+		t.Errorf("current version = %d (%q), want 3", latest.Version, latest.Remark)
+	}
+	if n := f.count(t, &models.TemplateVersion{}); n != 3 {
+		t.Errorf("versions = %d, want 3", n)
+	}
+}
+
+// TestTemplateVersionAtomic 修改与回滚失败时模版内容、子表与当前版本均保持不变
+func TestTemplateVersionAtomic(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, f *fixture, template *models.Template) error
+	}{
+		{
+			// 版本中的扇出通道已被删除，校验失败
+			name: "rollback to version with deleted fanout channel",
+			change: func(t *testing.T, f *fixture, template *models.Template) error {
+				f.svcCtx.DB.Delete(&models.Channel{}, template.Fanouts[0].ChannelID)
+				return NewTemplateRollbackLogic(f.ctx, f.svcCtx).TemplateRollback(&types.TemplateRollbackReq{TemplateID: template.ID, Version: 1})
+			},
+		},
+		{
+			// 模版与子表已写入后生成版本失败
+			name: "rollback with version insert failure",
+			change: func(t *testing.T, f *fixture, template *models.Template) error {
+				f.failVersionInsert(t)
+				return NewTemplateRollbackLogic(f.ctx, f.svcCtx).TemplateRollback(&types.TemplateRollbackReq{TemplateID: template.ID, Version: 1})
+			},
+		},
+		{
+			name: "update with version insert failure",
+			change: func(t *testing.T, f *fixture, template *models.Template) error {
+				f.failVersionInsert(t)
+				return NewTemplateUpdateLogic(f.ctx, f.svcCtx).TemplateUpdate(&types.TemplateUpdateReq{
+					ID: template.ID, Content: ptr("v3"), Fanouts: []types.TemplateFanoutItem{{ChannelID: template.Fanouts[0].ChannelID}},
+				})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			email := f.addChannel(t, "mail", "email")
+			created := f.createTemplate(t, types.TemplateCreateReq{
+				Name: "notice", Code: "notice", Content: "v1", Status: true,
+				Fanouts: []types.TemplateFanoutItem{{ChannelID: email.ID, Content: "mail v1"}},
+			})
+			err := NewTemplateUpdateLogic(f.ctx, f.svcCtx).TemplateUpdate(&types.TemplateUpdateReq{ID: created.ID, Content: ptr("v2"), Fanouts: []types.TemplateFanoutItem{}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.change(t, f, created); err == nil {
+				t.Fatal("change succeeded, want error")
+			}
+			current := f.template(t, "notice")
+			if current.Content != "v2" || len(current.Fanouts) != 0 {
+				t.Errorf("template changed: content=%q fanouts=%d", current.Content, len(current.Fanouts))
+			}
+			if v := f.version(t, current); v != 2 {
+				t.Errorf("current version = %d, want 2", v)
+			}
+			if n := f.count(t, &models.TemplateVersion{}); n != 2 {
+				t.Errorf("versions = %d, want 2", n)
+			}
+		})
+	}
+}
+
+// failVersionInsert 之后写入模版版本时返回错误
+func (f *fixture) failVersionInsert(t *testing.T) {
+	t.Helper()
+	err := f.svcCtx.DB.Callback().Create().Before("gorm:create").Register("test:fail_version_insert", func(tx *gorm.DB) {
+		if tx.Statement.Table == (models.TemplateVersion{}).TableName() {
+			_ = tx.AddError(errors.New("version insert failed"))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -34,6 +34,9 @@ func (l *TemplateCreateLogic) TemplateCreate(req *types.TemplateCreateReq) error
 	if err != nil {
 		return err
 	}
+	if err := checkSyntax(req.Title, req.Content); err != nil {
+		return err
+	}
 	var channel models.Channel
 	if err := l.svcCtx.DB.Model(&channel).Where(&models.Channel{ID: req.ChannelID, AgentID: agentID}).First(&channel).Error; err != nil {
 		if gorm.ErrRecordNotFound == err {
@@ -41,6 +44,10 @@ func (l *TemplateCreateLogic) TemplateCreate(req *types.TemplateCreateReq) error
 		}
 		return err
 	}
+	fallbackChannelIDs, err := checkFallbackChannels(l.svcCtx.DB, agentID, channel.ID, req.FallbackChannelIDs)
+	if err != nil {
+		return err
+	}
 	var count int64
 	_ = l.svcCtx.DB.Model(&models.Template{}).Where(&models.Template{
 		AgentID: agentID,
@@ -50,17 +57,46 @@ func (l *TemplateCreateLogic) TemplateCreate(req *types.TemplateCreateReq) error
 		return fmt.Errorf("%s模版已存在", req.Code)
 	}
 	template := &models.Template{
-		AgentID:    agentID,
-		ChannelID:  channel.ID,
-		Name:       req.Name,
-		Code:       req.Code,
-		VendorCode: req.VendorCode,
-		Signature:  req.Signature,
-		Content:    req.Content,
-		Status:     req.Status,
+		AgentID:            agentID,
+		ChannelID:          channel.ID,
+		FallbackChannelIDs: fallbackChannelIDs,
+		Name:               req.Name,
+		Code:               req.Code,
+		VendorCode:         req.VendorCode,
+		Signature:          req.Signature,
+		Title:              req.Title,
+		MsgType:            req.MsgType,
+		Content:            req.Content,
+		Status:             req.Status,
+	}
+	variables, err := extractVariables(l.svcCtx.DB, agentID, template, req.Fanouts, req.Locales, req.Variables)
+	if err != nil {
+		return err
 	}
-	if err := l.svcCtx.DB.Create(template).Error; err != nil {
+	template.Variables = variables
+	fanouts, err := checkFanouts(l.svcCtx.DB, agentID, channel.ID, req.Fanouts)
+	if err != nil {
+		return err
+	}
+	locales, err := checkLocales(agentID, req.Fanouts, req.Locales)
+	if err != nil {
 		return err
 	}
-	return nil
+	remark := req.Remark
+	if remark == "" {
+		remark = "创建模版"
+	}
+	// 模版、扇出通道、语言变体与初始版本一起写入，任一步失败时不会留下不完整的模版
+	return l.svcCtx.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(template).Error; err != nil {
+			return err
+		}
+		if err := replaceFanouts(tx, template.ID, fanouts); err != nil {
+			return err
+		}
+		if err := replaceLocales(tx, template.ID, locales); err != nil {
+			return err
+		}
+		return saveVersion(tx, agentID, template.ID, remark)
+	})
 }
@@ -9,6 +9,7 @@ import (
 	"chihqiang/msgbox-go/services/common/models"
 	"context"
 	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
 )
 
 type TemplateDeleteLogic struct {
@@ -37,8 +38,13 @@ func (l *TemplateDeleteLogic) TemplateDelete(req *types.IDReq) error {
 	}).First(&template).Error; err != nil {
 		return err
 	}
-	if err := l.svcCtx.DB.Delete(&template).Error; err != nil {
-		return err
-	}
-	return nil
+	return l.svcCtx.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(&models.TemplateFanout{TemplateID: template.ID}).Delete(&models.TemplateFanout{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where(&models.TemplateLocale{TemplateID: template.ID}).Delete(&models.TemplateLocale{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&template).Error
+	})
 }
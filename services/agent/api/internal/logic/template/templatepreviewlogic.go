@@ -0,0 +1,72 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package template
+
+import (
+	"chihqiang/msgbox-go/pkg/stringx"
+	"chihqiang/msgbox-go/services/agent/api/internal/svc"
+	"chihqiang/msgbox-go/services/agent/api/internal/types"
+	"chihqiang/msgbox-go/services/common/models"
+	"context"
+	"errors"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type TemplatePreviewLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewTemplatePreviewLogic(ctx context.Context, svcCtx *svc.ServiceContext) *TemplatePreviewLogic {
+	return &TemplatePreviewLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// TemplatePreview 使用示例变量渲染模版，未指定通道时预览主通道的内容，指定语言时按网关相同的回退顺序选择语言变体
+func (l *TemplatePreviewLogic) TemplatePreview(req *types.TemplatePreviewReq) (resp *types.TemplatePreviewResp, err error) {
+	agentID, err := types.GetAgentID(l.ctx)
+	if err != nil {
+		return nil, err
+	}
+	template, err := findTemplate(l.svcCtx.DB, agentID, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	variant, err := l.variant(template, req.ChannelID, req.Locale)
+	if err != nil {
+		return nil, err
+	}
+	title, content, err := render(variant.Template, req.Variables)
+	if err != nil {
+		return nil, err
+	}
+	length, segments := stringx.SmsSegments(content)
+	return &types.TemplatePreviewResp{
+		Locale:    variant.Locale,
+		Title:     title,
+		Signature: variant.Signature,
+		Content:   content,
+		Length:    length,
+		Segments:  segments,
+	}, nil
+}
+
+// variant 按语言查找主通道或扇出通道对应的内容变体
+func (l *TemplatePreviewLogic) variant(template *models.Template, channelID int64, locale string) (*models.TemplateVariant, error) {
+	variants := template.Variants(locale)
+	if channelID == 0 {
+		return variants[0], nil
+	}
+	for _, variant := range variants {
+		if variant.ChannelID == channelID {
+			return variant, nil
+		}
+	}
+	return nil, errors.New("指定的通道不是模版的主通道或扇出通道")
+}
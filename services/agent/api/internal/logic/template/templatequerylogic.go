@@ -10,6 +10,7 @@ import (
 	"chihqiang/msgbox-go/services/common/models"
 	"context"
 	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
 )
 
 type TemplateQueryLogic struct {
@@ -31,7 +32,10 @@ func (l *TemplateQueryLogic) TemplateQuery(req *types.TemplateQueryReq) (resp *t
 	if err != nil {
 		return nil, err
 	}
-	db := l.svcCtx.DB.Model(&models.Template{}).Where("agent_id = ?", agentID)
+	db := l.svcCtx.DB.Model(&models.Template{}).Where("agent_id = ?", agentID).
+		Preload("Fanouts", func(db *gorm.DB) *gorm.DB { return db.Order("sort, id") }).
+		Preload("Locales", func(db *gorm.DB) *gorm.DB { return db.Order("channel_id, locale") }).
+		Preload("CurrentVersion")
 	if req.Keywords != "" {
 		keyword := "%" + req.Keywords + "%"
 		db = db.Where("code LIKE ?", keyword).Or("vendor_code LIKE ?", keyword).Or("content LIKE ?", keyword)
@@ -49,19 +53,46 @@ func (l *TemplateQueryLogic) TemplateQuery(req *types.TemplateQueryReq) (resp *t
 func (l TemplateQueryLogic) convert(templates []models.Template) []types.TemplateItemResp {
 	items := make([]types.TemplateItemResp, 0, len(templates))
 	for _, item := range templates {
+		var version int
+		if item.CurrentVersion != nil {
+			version = item.CurrentVersion.Version
+		}
 		items = append(items, types.TemplateItemResp{
-			ID:         item.ID,
-			AgentID:    item.AgentID,
-			Name:       item.Name,
-			Code:       item.Code,
-			VendorCode: item.VendorCode,
-			Signature:  item.Signature,
-			Content:    item.Content,
-			Status:     item.Status,
-			UsedCount:  item.UsedCount,
-			CreatedAt:  timex.FormatDate(item.CreatedAt),
-			UpdatedAt:  timex.FormatDate(item.UpdatedAt),
-			ChannelID:  item.ChannelID,
+			ID:                 item.ID,
+			AgentID:            item.AgentID,
+			Name:               item.Name,
+			Code:               item.Code,
+			VendorCode:         item.VendorCode,
+			Signature:          item.Signature,
+			Title:              item.Title,
+			MsgType:            item.MsgType,
+			Content:            item.Content,
+			Variables:          variableItems(item.Variables),
+			VersionID:          item.VersionID,
+			Version:            version,
+			Status:             item.Status,
+			UsedCount:          item.UsedCount,
+			CreatedAt:          timex.FormatDate(item.CreatedAt),
+			UpdatedAt:          timex.FormatDate(item.UpdatedAt),
+			ChannelID:          item.ChannelID,
+			FallbackChannelIDs: item.FallbackChannelIDs,
+			Fanouts:            l.fanouts(item.Fanouts),
+			Locales:            localeItems(item.Locales),
+		})
+	}
+	return items
+}
+
+func (l TemplateQueryLogic) fanouts(fanouts []*models.TemplateFanout) []types.TemplateFanoutItem {
+	items := make([]types.TemplateFanoutItem, 0, len(fanouts))
+	for _, fanout := range fanouts {
+		items = append(items, types.TemplateFanoutItem{
+			ChannelID:  fanout.ChannelID,
+			VendorCode: fanout.VendorCode,
+			Signature:  fanout.Signature,
+			MsgType:    fanout.MsgType,
+			Title:      fanout.Title,
+			Content:    fanout.Content,
 		})
 	}
 	return items
@@ -0,0 +1,123 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package template
+
+import (
+	"chihqiang/msgbox-go/services/agent/api/internal/svc"
+	"chihqiang/msgbox-go/services/agent/api/internal/types"
+	"chihqiang/msgbox-go/services/common/models"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type TemplateRollbackLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewTemplateRollbackLogic(ctx context.Context, svcCtx *svc.ServiceContext) *TemplateRollbackLogic {
+	return &TemplateRollbackLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// TemplateRollback 将模版的发送内容恢复为指定版本，历史版本保持不变，恢复后的内容作为新版本保存
+func (l *TemplateRollbackLogic) TemplateRollback(req *types.TemplateRollbackReq) error {
+	agentID, err := types.GetAgentID(l.ctx)
+	if err != nil {
+		return err
+	}
+	template, err := findTemplate(l.svcCtx.DB, agentID, req.TemplateID)
+	if err != nil {
+		return err
+	}
+	version, err := findVersion(l.svcCtx.DB, template.ID, req.Version)
+	if err != nil {
+		return err
+	}
+	if version.ID == template.VersionID {
+		return errors.New("模版当前已是该版本")
+	}
+	// 版本中的通道可能已被删除，写入前重新校验主通道、备用通道、扇出通道与语言变体
+	var count int64
+	if err := l.svcCtx.DB.Model(&models.Channel{}).Where(&models.Channel{ID: version.ChannelID, AgentID: agentID}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("版本使用的通道不存在")
+	}
+	fallbackChannelIDs, err := checkFallbackChannels(l.svcCtx.DB, agentID, version.ChannelID, version.FallbackChannelIDs)
+	if err != nil {
+		return err
+	}
+	fanouts := make([]types.TemplateFanoutItem, 0, len(version.Fanouts))
+	for _, fanout := range version.Fanouts {
+		fanouts = append(fanouts, types.TemplateFanoutItem{
+			ChannelID:  fanout.ChannelID,
+			VendorCode: fanout.VendorCode,
+			Signature:  fanout.Signature,
+			MsgType:    fanout.MsgType,
+			Title:      fanout.Title,
+			Content:    fanout.Content,
+		})
+	}
+	locales := make([]types.TemplateLocaleItem, 0, len(version.Locales))
+	for _, locale := range version.Locales {
+		locales = append(locales, types.TemplateLocaleItem{
+			ChannelID:  locale.ChannelID,
+			Locale:     locale.Locale,
+			VendorCode: locale.VendorCode,
+			Signature:  locale.Signature,
+			Title:      locale.Title,
+			Content:    locale.Content,
+		})
+	}
+	fanoutModels, err := checkFanouts(l.svcCtx.DB, agentID, version.ChannelID, fanouts)
+	if err != nil {
+		return err
+	}
+	localeModels, err := checkLocales(agentID, fanouts, locales)
+	if err != nil {
+		return err
+	}
+	remark := req.Remark
+	if remark == "" {
+		remark = fmt.Sprintf("回滚至版本 %d", version.Version)
+	}
+
+	// 模版、扇出通道、语言变体与新版本在同一事务中写入，任一步失败时模版内容与当前版本保持不变
+	return l.svcCtx.DB.Transaction(func(tx *gorm.DB) error {
+		// 版本功能上线前创建的模版先以回滚前的内容生成初始版本，保证修改历史完整
+		if _, err := models.EnsureTemplateVersion(tx, template); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Template{ID: template.ID}).Updates(map[string]interface{}{
+			"channel_id":           version.ChannelID,
+			"fallback_channel_ids": datatypes.JSONSlice[int64](fallbackChannelIDs),
+			"vendor_code":          version.VendorCode,
+			"signature":            version.Signature,
+			"msg_type":             version.MsgType,
+			"title":                version.Title,
+			"content":              version.Content,
+			"variables":            version.Variables,
+		}).Error; err != nil {
+			return err
+		}
+		if err := replaceFanouts(tx, template.ID, fanoutModels); err != nil {
+			return err
+		}
+		if err := replaceLocales(tx, template.ID, localeModels); err != nil {
+			return err
+		}
+		return saveVersion(tx, agentID, template.ID, remark)
+	})
+}
@@ -0,0 +1,143 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package template
+
+import (
+	"chihqiang/msgbox-go/pkg/stringx"
+	"chihqiang/msgbox-go/services/agent/api/internal/svc"
+	"chihqiang/msgbox-go/services/agent/api/internal/types"
+	"chihqiang/msgbox-go/services/common/models"
+	"chihqiang/msgbox-go/services/common/pipeline/tasks"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TemplateTestExtraKey 测试发送写入 SendRecord.Extra 的标记
+const TemplateTestExtraKey = "test"
+
+type TemplateTestLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewTemplateTestLogic(ctx context.Context, svcCtx *svc.ServiceContext) *TemplateTestLogic {
+	return &TemplateTestLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// TemplateTest 通过模版主通道同步发送一条测试消息，不做故障转移与重试；
+// 批次标记为测试不计入批次统计，记录的 extra.test 为 true
+func (l *TemplateTestLogic) TemplateTest(req *types.TemplateTestReq) (resp *types.TemplateTestResp, err error) {
+	agentID, err := types.GetAgentID(l.ctx)
+	if err != nil {
+		return nil, err
+	}
+	receiver := strings.TrimSpace(req.Receiver)
+	if receiver == "" {
+		return nil, errors.New("接收者不能为空")
+	}
+	template, err := findTemplate(l.svcCtx.DB, agentID, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if template.Channel == nil {
+		return nil, errors.New("模版没有配置通道")
+	}
+	if !template.Channel.Status {
+		return nil, errors.New("模版通道已被禁用")
+	}
+	localized, locale := template.Localize(req.Locale)
+	title, content, err := render(localized, req.Variables)
+	if err != nil {
+		return nil, err
+	}
+	record, err := l.create(localized, locale, receiver, title, content, req.Variables)
+	if err != nil {
+		return nil, err
+	}
+	// 只使用主通道发送一次：不加载备用通道，失败不重新入队
+	channel := *template.Channel
+	channel.RetryMaxAttempts = 1
+	record.Channel = &channel
+	record.Template = &models.Template{ID: template.ID, ChannelID: template.ChannelID}
+	if _, err := tasks.NewSendTask(l.Logger, l.svcCtx.DB, record).Task().OnAction(l.ctx); err != nil {
+		l.Logger.Errorf("test send record %d failed, err: %v", record.ID, err)
+	}
+	now := time.Now()
+	if err := l.svcCtx.DB.Model(&models.SendBatch{}).Where(&models.SendBatch{ID: record.BatchID}).
+		Updates(&models.SendBatch{SendEndTime: &now}).Error; err != nil {
+		l.Logger.Errorf("update test send batch end time failed, err: %v", err)
+	}
+	var sent models.SendRecord
+	if err := l.svcCtx.DB.Preload("Batch").First(&sent, record.ID).Error; err != nil {
+		return nil, err
+	}
+	return &types.TemplateTestResp{
+		RecordID:    sent.ID,
+		BatchNo:     sent.Batch.BatchNo,
+		Status:      sent.Status,
+		StatusMsg:   sent.StatusMsg(),
+		VendorName:  sent.VendorName,
+		VendorMsgID: sent.VendorMsgID,
+		Content:     sent.Content,
+		Error:       sent.Error,
+	}, nil
+}
+
+// create 创建测试批次与发送记录，template 为选中语言后的内容变体
+func (l *TemplateTestLogic) create(template *models.Template, locale, receiver, title, content string, variables map[string]interface{}) (*models.SendRecord, error) {
+	now := time.Now()
+	traceID := stringx.UUID()
+	batch := &models.SendBatch{
+		AgentID:       template.AgentID,
+		ChannelID:     template.ChannelID,
+		TemplateID:    template.ID,
+		BatchNo:       stringx.UUID(),
+		TraceID:       traceID,
+		TotalCount:    1,
+		Test:          true,
+		ScheduledTime: &now,
+		SendStartTime: &now,
+	}
+	record := &models.SendRecord{
+		AgentID:           template.AgentID,
+		ChannelID:         template.ChannelID,
+		TemplateID:        template.ID,
+		TemplateVersionID: template.VersionID,
+		TraceID:           traceID,
+		Receiver:          receiver,
+		VendorName:        template.Channel.VendorName,
+		ChannelConfig:     template.Channel.Config,
+		Locale:            locale,
+		VendorCode:        template.VendorCode,
+		Signature:         template.Signature,
+		MsgType:           template.MsgType,
+		Title:             title,
+		Content:           content,
+		Variables:         models.MapToDataTypesJSON(variables),
+		Extra:             models.MapToDataTypesJSON(map[string]interface{}{TemplateTestExtraKey: true}),
+		Status:            models.SendRecordStatusPending,
+	}
+	err := l.svcCtx.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(batch).Error; err != nil {
+			return err
+		}
+		record.BatchID = batch.ID
+		return tx.Omit(clause.Associations).Create(record).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
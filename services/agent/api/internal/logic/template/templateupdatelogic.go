@@ -11,9 +11,16 @@ import (
 	"errors"
 	"gorm.io/gorm"
 
+	"github.com/samber/lo"
 	"github.com/zeromicro/go-zero/core/logx"
 )
 
+// templateUpdateColumns 修改模版时写入的字段，未传的字段已在写入前沿用原值
+var templateUpdateColumns = []string{
+	"channel_id", "fallback_channel_ids", "name", "vendor_code", "signature",
+	"msg_type", "title", "content", "variables", "status",
+}
+
 type TemplateUpdateLogic struct {
 	logx.Logger
 	ctx    context.Context
@@ -37,13 +44,18 @@ func (l *TemplateUpdateLogic) TemplateUpdate(req *types.TemplateUpdateReq) error
 	if err := l.svcCtx.DB.Where("id = ? AND agent_id = ?", req.ID, agentID).First(&template).Error; err != nil {
 		return err
 	}
-
 	if req.VendorCode != nil {
 		template.VendorCode = *req.VendorCode
 	}
 	if req.Signature != nil {
 		template.Signature = *req.Signature
 	}
+	if req.Title != nil {
+		template.Title = *req.Title
+	}
+	if req.MsgType != nil {
+		template.MsgType = *req.MsgType
+	}
 	if req.Content != nil {
 		template.Content = *req.Content
 	}
@@ -53,10 +65,13 @@ func (l *TemplateUpdateLogic) TemplateUpdate(req *types.TemplateUpdateReq) error
 	if req.Name != nil {
 		template.Name = *req.Name
 	}
+	if err := checkSyntax(template.Title, template.Content); err != nil {
+		return err
+	}
 	// 更新 Channels 关联
 	if req.ChannelID != nil {
 		var channel models.Channel
-		if err := l.svcCtx.DB.Where("id = ?", *req.ChannelID).First(&channel).Error; err != nil {
+		if err := l.svcCtx.DB.Where(&models.Channel{ID: *req.ChannelID, AgentID: agentID}).First(&channel).Error; err != nil {
 			if gorm.ErrRecordNotFound == err {
 				return errors.New("指定的通道不存在")
 			}
@@ -64,9 +79,105 @@ func (l *TemplateUpdateLogic) TemplateUpdate(req *types.TemplateUpdateReq) error
 		}
 		template.ChannelID = *req.ChannelID
 	}
-
-	if err := l.svcCtx.DB.Model(&template).Where(models.Template{ID: req.ID, AgentID: agentID}).Updates(template).Error; err != nil {
+	// 备用通道未传时保持不变，传空数组表示清空
+	if req.FallbackChannelIDs != nil {
+		fallbackChannelIDs, err := checkFallbackChannels(l.svcCtx.DB, agentID, template.ChannelID, req.FallbackChannelIDs)
+		if err != nil {
+			return err
+		}
+		template.FallbackChannelIDs = fallbackChannelIDs
+	}
+	// 内容修改后重新提取变量声明，变量声明未传时沿用已有的类型与校验规则
+	declared := req.Variables
+	if declared == nil {
+		declared = variableItems(template.Variables)
+	}
+	fanouts := req.Fanouts
+	if fanouts == nil {
+		if fanouts, err = l.fanouts(template.ID); err != nil {
+			return err
+		}
+	}
+	locales := req.Locales
+	if locales == nil {
+		if locales, err = l.locales(template.ID, fanouts); err != nil {
+			return err
+		}
+	}
+	variables, err := extractVariables(l.svcCtx.DB, agentID, &template, fanouts, locales, declared)
+	if err != nil {
 		return err
 	}
-	return nil
+	template.Variables = variables
+	var fanoutModels []*models.TemplateFanout
+	if req.Fanouts != nil {
+		if fanoutModels, err = checkFanouts(l.svcCtx.DB, agentID, template.ChannelID, req.Fanouts); err != nil {
+			return err
+		}
+	}
+	localeModels, err := checkLocales(agentID, fanouts, locales)
+	if err != nil {
+		return err
+	}
+
+	// 校验全部通过后在同一事务中写入模版、扇出通道、语言变体与新版本，任一步失败时整体回滚
+	return l.svcCtx.DB.Transaction(func(tx *gorm.DB) error {
+		// 版本功能上线前创建的模版先以修改前的内容生成初始版本，保证修改历史完整
+		if template.VersionID == 0 {
+			current, err := findTemplate(tx, agentID, template.ID)
+			if err != nil {
+				return err
+			}
+			if _, err := models.EnsureTemplateVersion(tx, current); err != nil {
+				return err
+			}
+		}
+		// 结构体更新会跳过零值，显式指定字段才能清空标题、消息类型等字段或禁用模版
+		if err := tx.Model(&template).Where(models.Template{ID: req.ID, AgentID: agentID}).
+			Select(templateUpdateColumns).Updates(&template).Error; err != nil {
+			return err
+		}
+		// 扇出通道未传时保持不变，传空数组表示清空
+		if req.Fanouts != nil {
+			if err := replaceFanouts(tx, template.ID, fanoutModels); err != nil {
+				return err
+			}
+		}
+		// 语言变体未传时保持不变，传空数组表示清空；扇出通道变化时同时去掉已移除通道的语言变体
+		if req.Locales != nil || req.Fanouts != nil {
+			if err := replaceLocales(tx, template.ID, localeModels); err != nil {
+				return err
+			}
+		}
+		// 只修改名称或状态时发送内容不变，不生成新版本
+		return saveVersion(tx, agentID, template.ID, req.Remark)
+	})
+}
+
+// fanouts 查询模版已有的扇出通道，用于提取变量声明
+func (l *TemplateUpdateLogic) fanouts(templateID int64) ([]types.TemplateFanoutItem, error) {
+	var fanouts []*models.TemplateFanout
+	if err := l.svcCtx.DB.Where(&models.TemplateFanout{TemplateID: templateID}).Order("sort, id").Find(&fanouts).Error; err != nil {
+		return nil, err
+	}
+	items := make([]types.TemplateFanoutItem, 0, len(fanouts))
+	for _, fanout := range fanouts {
+		items = append(items, types.TemplateFanoutItem{ChannelID: fanout.ChannelID, Title: fanout.Title, Content: fanout.Content})
+	}
+	return items, nil
+}
+
+// locales 查询模版已有的语言变体，用于提取变量声明；只保留主内容与 fanouts 中扇出通道的语言变体
+func (l *TemplateUpdateLogic) locales(templateID int64, fanouts []types.TemplateFanoutItem) ([]types.TemplateLocaleItem, error) {
+	var locales []*models.TemplateLocale
+	if err := l.svcCtx.DB.Where(&models.TemplateLocale{TemplateID: templateID}).Order("channel_id, locale").Find(&locales).Error; err != nil {
+		return nil, err
+	}
+	channels := map[int64]bool{0: true}
+	for _, fanout := range fanouts {
+		channels[fanout.ChannelID] = true
+	}
+	return lo.Filter(localeItems(locales), func(item types.TemplateLocaleItem, _ int) bool {
+		return channels[item.ChannelID]
+	}), nil
 }
@@ -0,0 +1,102 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package template
+
+import (
+	"chihqiang/msgbox-go/pkg/stringx"
+	"chihqiang/msgbox-go/services/agent/api/internal/svc"
+	"chihqiang/msgbox-go/services/agent/api/internal/types"
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type TemplateVersionDiffLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewTemplateVersionDiffLogic(ctx context.Context, svcCtx *svc.ServiceContext) *TemplateVersionDiffLogic {
+	return &TemplateVersionDiffLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// TemplateVersionDiff 比较两个版本，标题与内容按行给出差异，其余字段只列出发生变化的部分
+func (l *TemplateVersionDiffLogic) TemplateVersionDiff(req *types.TemplateVersionDiffReq) (resp *types.TemplateVersionDiffResp, err error) {
+	agentID, err := types.GetAgentID(l.ctx)
+	if err != nil {
+		return nil, err
+	}
+	template, err := findTemplate(l.svcCtx.DB, agentID, req.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	from, err := findVersion(l.svcCtx.DB, template.ID, req.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := findVersion(l.svcCtx.DB, template.ID, req.To)
+	if err != nil {
+		return nil, err
+	}
+	fromItem, toItem := versionItem(from, template.VersionID), versionItem(to, template.VersionID)
+	return &types.TemplateVersionDiffResp{
+		From:    fromItem,
+		To:      toItem,
+		Fields:  l.fields(fromItem, toItem),
+		Title:   l.lines(stringx.DiffLines(from.Title, to.Title)),
+		Content: l.lines(stringx.DiffLines(from.Content, to.Content)),
+	}, nil
+}
+
+// fields 标题与内容以外发生变化的字段
+func (l *TemplateVersionDiffLogic) fields(from, to types.TemplateVersionItem) []types.TemplateDiffField {
+	pairs := []struct {
+		field    string
+		from, to any
+	}{
+		{"channel_id", from.ChannelID, to.ChannelID},
+		{"fallback_channel_ids", from.FallbackChannelIDs, to.FallbackChannelIDs},
+		{"vendor_code", from.VendorCode, to.VendorCode},
+		{"signature", from.Signature, to.Signature},
+		{"msg_type", from.MsgType, to.MsgType},
+		{"variables", from.Variables, to.Variables},
+		{"fanouts", from.Fanouts, to.Fanouts},
+		{"locales", from.Locales, to.Locales},
+	}
+	fields := make([]types.TemplateDiffField, 0)
+	for _, pair := range pairs {
+		a, b := diffValue(pair.from), diffValue(pair.to)
+		if a != b {
+			fields = append(fields, types.TemplateDiffField{Field: pair.field, From: a, To: b})
+		}
+	}
+	return fields
+}
+
+func (l *TemplateVersionDiffLogic) lines(lines []stringx.DiffLine) []types.TemplateDiffLine {
+	items := make([]types.TemplateDiffLine, 0, len(lines))
+	for _, line := range lines {
+		items = append(items, types.TemplateDiffLine{Op: line.Op, Text: line.Text})
+	}
+	return items
+}
+
+// diffValue 字段值转换为字符串，列表与对象使用 JSON
+func diffValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
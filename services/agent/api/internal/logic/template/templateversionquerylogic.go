@@ -0,0 +1,52 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package template
+
+import (
+	"chihqiang/msgbox-go/services/agent/api/internal/svc"
+	"chihqiang/msgbox-go/services/agent/api/internal/types"
+	"chihqiang/msgbox-go/services/common/models"
+	"context"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type TemplateVersionQueryLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewTemplateVersionQueryLogic(ctx context.Context, svcCtx *svc.ServiceContext) *TemplateVersionQueryLogic {
+	return &TemplateVersionQueryLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *TemplateVersionQueryLogic) TemplateVersionQuery(req *types.TemplateVersionQueryReq) (resp *types.TemplateVersionQueryResp, err error) {
+	agentID, err := types.GetAgentID(l.ctx)
+	if err != nil {
+		return nil, err
+	}
+	template, err := findTemplate(l.svcCtx.DB, agentID, req.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	db := l.svcCtx.DB.Model(&models.TemplateVersion{}).
+		Where(&models.TemplateVersion{TemplateID: template.ID}).Order("version DESC")
+	total, versions, err := models.Page[models.TemplateVersion](db, req.Page, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]types.TemplateVersionItem, 0, len(versions))
+	for i := range versions {
+		items = append(items, versionItem(&versions[i], template.VersionID))
+	}
+	return &types.TemplateVersionQueryResp{
+		Total: total,
+		Data:  items,
+	}, nil
+}
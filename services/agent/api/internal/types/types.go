@@ -3,25 +3,91 @@
 
 package types
 
+type BatchChannelItemResp struct {
+	ChannelID    int64  `json:"channel_id"`
+	ChannelName  string `json:"channel_name"`
+	VendorName   string `json:"vendor_name"`
+	TotalCount   int    `json:"total_count"`
+	SuccessCount int    `json:"success_count"`
+	FailCount    int    `json:"fail_count"`
+}
+
+type BatchItemResp struct {
+	ID            int64                  `json:"id"`
+	BatchNo       string                 `json:"batch_no"`
+	TraceID       string                 `json:"trace_id"`
+	ChannelID     int64                  `json:"channel_id"`
+	ChannelName   string                 `json:"channel_name"`
+	TemplateID    int64                  `json:"template_id"`
+	TemplateName  string                 `json:"template_name"`
+	TotalCount    int                    `json:"total_count"`
+	SuccessCount  int                    `json:"success_count"`
+	FailCount     int                    `json:"fail_count"`
+	ScheduledTime string                 `json:"scheduled_time"`
+	SendStartTime string                 `json:"send_start_time"`
+	SendEndTime   string                 `json:"send_end_time"`
+	CancelTime    string                 `json:"cancel_time"`
+	Channels      []BatchChannelItemResp `json:"channels"`
+	CreatedAt     string                 `json:"created_at"`
+}
+
+type BatchQueryReq struct {
+	PaginationReq
+	Keywords string `json:"keywords,optional" form:"keywords,optional"`
+}
+
+type BatchQueryResp struct {
+	Total int64           `json:"total"`
+	Data  []BatchItemResp `json:"data"`
+}
+
+type BatchRescheduleReq struct {
+	ID     int64  `json:"id" validate:"required"`
+	SendAt string `json:"send_at" validate:"required"`
+}
+
+type BatchScheduledReq struct {
+	PaginationReq
+	Keywords string `json:"keywords,optional" form:"keywords,optional"`
+}
+
+type BatchScheduledResp struct {
+	Total int64           `json:"total"`
+	Data  []BatchItemResp `json:"data"`
+}
+
 type ChannelCreateReq struct {
-	Code       string                 `json:"code,optional"`
-	Name       string                 `json:"name,optional"`
-	VendorName string                 `json:"vendor_name"`
-	Config     map[string]interface{} `json:"config,omitempty"`
-	Status     bool                   `json:"status,omitempty"`
+	Code             string                 `json:"code,optional"`
+	Name             string                 `json:"name,optional"`
+	VendorName       string                 `json:"vendor_name"`
+	Config           map[string]interface{} `json:"config,omitempty"`
+	Timeout          int                    `json:"timeout,optional"`
+	MergeMention     bool                   `json:"merge_mention,optional"`
+	RetryMaxAttempts int                    `json:"retry_max_attempts,optional"`
+	RetryBackoff     string                 `json:"retry_backoff,optional"`
+	RetryInterval    int                    `json:"retry_interval,optional"`
+	RetryCodes       string                 `json:"retry_codes,optional"`
+	Status           bool                   `json:"status,omitempty"`
 }
 
 type ChannelItemResp struct {
-	ID              int64                  `json:"id"`
-	AgentID         int64                  `json:"agent_id"`
-	Code            string                 `json:"code"`
-	Name            string                 `json:"name"`
-	VendorName      string                 `json:"vendor_name"`
-	VendorNameLabel string                 `json:"vendor_name_label"`
-	Config          map[string]interface{} `json:"config"`
-	Status          bool                   `json:"status"`
-	CreatedAt       string                 `json:"created_at"`
-	UpdatedAt       string                 `json:"updated_at"`
+	ID               int64                  `json:"id"`
+	AgentID          int64                  `json:"agent_id"`
+	Code             string                 `json:"code"`
+	Name             string                 `json:"name"`
+	VendorName       string                 `json:"vendor_name"`
+	VendorNameLabel  string                 `json:"vendor_name_label"`
+	Config           map[string]interface{} `json:"config"`
+	Timeout          int                    `json:"timeout"`
+	MergeMention     bool                   `json:"merge_mention"`
+	RetryMaxAttempts int                    `json:"retry_max_attempts"`
+	RetryBackoff     string                 `json:"retry_backoff"`
+	RetryInterval    int                    `json:"retry_interval"`
+	RetryCodes       string                 `json:"retry_codes"`
+	ReceiptToken     string                 `json:"receipt_token"`
+	Status           bool                   `json:"status"`
+	CreatedAt        string                 `json:"created_at"`
+	UpdatedAt        string                 `json:"updated_at"`
 }
 
 type ChannelQueryReq struct {
@@ -36,11 +102,17 @@ type ChannelQueryResp struct {
 }
 
 type ChannelUpdateReq struct {
-	ID         int64                  `json:"id"`
-	Name       *string                `json:"name,optional,omitempty"`
-	VendorName *string                `json:"vendor_name,optional,omitempty"`
-	Config     map[string]interface{} `json:"config,optional,omitempty"`
-	Status     *bool                  `json:"status,optional,omitempty"`
+	ID               int64                  `json:"id"`
+	Name             *string                `json:"name,optional,omitempty"`
+	VendorName       *string                `json:"vendor_name,optional,omitempty"`
+	Config           map[string]interface{} `json:"config,optional,omitempty"`
+	Timeout          *int                   `json:"timeout,optional,omitempty"`
+	MergeMention     *bool                  `json:"merge_mention,optional,omitempty"`
+	RetryMaxAttempts *int                   `json:"retry_max_attempts,optional,omitempty"`
+	RetryBackoff     *string                `json:"retry_backoff,optional,omitempty"`
+	RetryInterval    *int                   `json:"retry_interval,optional,omitempty"`
+	RetryCodes       *string                `json:"retry_codes,optional,omitempty"`
+	Status           *bool                  `json:"status,optional,omitempty"`
 }
 
 type FormField struct {
@@ -96,28 +168,58 @@ type PaginationReq struct {
 	Size int `json:"size,default=10" json:"page,default=1"`
 }
 
+type RecordAttemptItemResp struct {
+	ID              int64                  `json:"id"`
+	Attempt         int                    `json:"attempt"`
+	ChannelID       int64                  `json:"channel_id"`
+	VendorName      string                 `json:"vendor_name"`
+	Status          int                    `json:"status"`
+	StatusMsg       string                 `json:"status_msg"`
+	Error           string                 `json:"error"`
+	FailReason      string                 `json:"fail_reason"`
+	Response        map[string]interface{} `json:"response"`
+	StartTime       string                 `json:"start_time"`
+	EndTime         string                 `json:"end_time"`
+	NextAttemptTime string                 `json:"next_attempt_time"`
+}
+
+type RecordAttemptsReq struct {
+	ID int64 `json:"id" form:"id"`
+}
+
+type RecordAttemptsResp struct {
+	Data []RecordAttemptItemResp `json:"data"`
+}
+
 type RecordItemResp struct {
-	ID            int64                  `json:"id"`
-	Receiver      string                 `json:"receiver"`
-	TraceID       string                 `json:"trace_id"`
-	ChannelName   string                 `json:"channel_name"`
-	ChannelConfig map[string]interface{} `json:"channel_config"`
-	VendorName    string                 `json:"vendor_name"`
-	VendorCode    string                 `json:"vendor_code"`
-	Signature     string                 `json:"signature"`
-	Title         string                 `json:"title"`
-	Content       string                 `json:"content"`
-	Variables     map[string]interface{} `json:"variables"`
-	Extra         map[string]interface{} `json:"extra"`
-	Status        int                    `json:"status"`
-	StatusMsg     string                 `json:"status_msg"`
-	SendTime      string                 `json:"send_time"`
-	Error         string                 `json:"error"`
-	Response      map[string]interface{} `json:"response"`
-	DeliveryTime  string                 `json:"delivery_time"`
-	DeliveryRaw   map[string]interface{} `json:"delivery_raw"`
-	CreatedAt     string                 `json:"created_at"`
-	UpdatedAt     string                 `json:"updated_at"`
+	ID              int64                  `json:"id"`
+	Receiver        string                 `json:"receiver"`
+	TraceID         string                 `json:"trace_id"`
+	TemplateID      int64                  `json:"template_id"`
+	TemplateVersion int                    `json:"template_version"` // 发送时使用的模版版本号，0 表示版本功能上线前的记录
+	ChannelName     string                 `json:"channel_name"`
+	ChannelConfig   map[string]interface{} `json:"channel_config"`
+	VendorName      string                 `json:"vendor_name"`
+	Locale          string                 `json:"locale"` // 选中的模版语言，空表示默认内容
+	VendorCode      string                 `json:"vendor_code"`
+	Signature       string                 `json:"signature"`
+	Title           string                 `json:"title"`
+	Content         string                 `json:"content"`
+	Variables       map[string]interface{} `json:"variables"`
+	Extra           map[string]interface{} `json:"extra"`
+	Status          int                    `json:"status"`
+	StatusMsg       string                 `json:"status_msg"`
+	SendTime        string                 `json:"send_time"`
+	Attempts        int                    `json:"attempts"`
+	NextAttemptTime string                 `json:"next_attempt_time"`
+	Error           string                 `json:"error"`
+	FailReason      string                 `json:"fail_reason"`
+	VendorMsgID     string                 `json:"vendor_msg_id"`
+	Response        map[string]interface{} `json:"response"`
+	DeliveryTime    string                 `json:"delivery_time"`
+	DeliveryRaw     map[string]interface{} `json:"delivery_raw"`
+	CreatedAt       string                 `json:"created_at"`
+	UpdatedAt       string                 `json:"updated_at"`
 }
 
 type RecordQueryReq struct {
@@ -143,28 +245,88 @@ type ResetSecretResp struct {
 }
 
 type TemplateCreateReq struct {
+	ChannelID          int64                  `json:"channel_id"`
+	FallbackChannelIDs []int64                `json:"fallback_channel_ids,optional,omitempty"`
+	Fanouts            []TemplateFanoutItem   `json:"fanouts,optional,omitempty"`
+	Locales            []TemplateLocaleItem   `json:"locales,optional,omitempty"`
+	Name               string                 `json:"name"`
+	Code               string                 `json:"code"`
+	VendorCode         string                 `json:"vendor_code,optional,omitempty"`
+	Signature          string                 `json:"signature,optional,omitempty"`
+	Title              string                 `json:"title,optional,omitempty"`
+	MsgType            string                 `json:"msg_type,optional,omitempty"`
+	Content            string                 `json:"content"`
+	Variables          []TemplateVariableItem `json:"variables,optional,omitempty"`
+	Status             bool                   `json:"status"`
+	Remark             string                 `json:"remark,optional,omitempty"` // 版本说明
+}
+
+type TemplateDiffField struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type TemplateDiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type TemplateFanoutItem struct {
 	ChannelID  int64  `json:"channel_id"`
-	Name       string `json:"name"`
-	Code       string `json:"code"`
-	VendorCode string `json:"vendor_code,optional,omitempty"`
-	Signature  string `json:"signature,optional,omitempty"`
-	Content    string `json:"content"`
-	Status     bool   `json:"status"`
+	VendorCode string `json:"vendor_code,optional"`
+	Signature  string `json:"signature,optional"`
+	MsgType    string `json:"msg_type,optional"`
+	Title      string `json:"title,optional"`
+	Content    string `json:"content,optional"`
 }
 
 type TemplateItemResp struct {
-	ID         int64  `json:"id"`
-	AgentID    int64  `json:"agent_id"`
-	ChannelID  int64  `json:"channel_id"`
-	Name       string `json:"name"`
-	Code       string `json:"code"`
-	VendorCode string `json:"vendor_code"`
-	Signature  string `json:"signature"`
-	Content    string `json:"content"`
-	Status     bool   `json:"status"`
-	UsedCount  int64  `json:"used_count"`
-	CreatedAt  string `json:"created_at"`
-	UpdatedAt  string `json:"updated_at"`
+	ID                 int64                  `json:"id"`
+	AgentID            int64                  `json:"agent_id"`
+	ChannelID          int64                  `json:"channel_id"`
+	FallbackChannelIDs []int64                `json:"fallback_channel_ids"`
+	Fanouts            []TemplateFanoutItem   `json:"fanouts"`
+	Locales            []TemplateLocaleItem   `json:"locales"`
+	Name               string                 `json:"name"`
+	Code               string                 `json:"code"`
+	VendorCode         string                 `json:"vendor_code"`
+	Signature          string                 `json:"signature"`
+	Title              string                 `json:"title"`
+	MsgType            string                 `json:"msg_type"`
+	Content            string                 `json:"content"`
+	Variables          []TemplateVariableItem `json:"variables"`
+	VersionID          int64                  `json:"version_id"`
+	Version            int                    `json:"version"`
+	Status             bool                   `json:"status"`
+	UsedCount          int64                  `json:"used_count"`
+	CreatedAt          string                 `json:"created_at"`
+	UpdatedAt          string                 `json:"updated_at"`
+}
+
+type TemplateLocaleItem struct {
+	ChannelID  int64  `json:"channel_id,optional"`
+	Locale     string `json:"locale"`
+	VendorCode string `json:"vendor_code,optional"`
+	Signature  string `json:"signature,optional"`
+	Title      string `json:"title,optional"`
+	Content    string `json:"content,optional"`
+}
+
+type TemplatePreviewReq struct {
+	ID        int64                  `json:"id"`
+	ChannelID int64                  `json:"channel_id,optional"`
+	Locale    string                 `json:"locale,optional"`
+	Variables map[string]interface{} `json:"variables,optional"`
+}
+
+type TemplatePreviewResp struct {
+	Locale    string `json:"locale"` // 选中的语言，空表示默认内容
+	Title     string `json:"title"`
+	Signature string `json:"signature"`
+	Content   string `json:"content"`  // 含签名的最终内容
+	Length    int    `json:"length"`   // 字数（含签名）
+	Segments  int    `json:"segments"` // 短信计费条数
 }
 
 type TemplateQueryReq struct {
@@ -178,12 +340,99 @@ type TemplateQueryResp struct {
 	Data  []TemplateItemResp `json:"data"`
 }
 
+type TemplateRollbackReq struct {
+	TemplateID int64  `json:"template_id"`
+	Version    int    `json:"version"`
+	Remark     string `json:"remark,optional"`
+}
+
+type TemplateTestReq struct {
+	ID        int64                  `json:"id"`
+	Receiver  string                 `json:"receiver"`
+	Locale    string                 `json:"locale,optional"`
+	Variables map[string]interface{} `json:"variables,optional"`
+}
+
+type TemplateTestResp struct {
+	RecordID    int64  `json:"record_id"`
+	BatchNo     string `json:"batch_no"`
+	Status      int    `json:"status"`
+	StatusMsg   string `json:"status_msg"`
+	VendorName  string `json:"vendor_name"`
+	VendorMsgID string `json:"vendor_msg_id"`
+	Content     string `json:"content"`
+	Error       string `json:"error"`
+}
+
 type TemplateUpdateReq struct {
-	ID         int64   `json:"id"`
-	Name       *string `json:"name"`
-	ChannelID  *int64  `json:"channel_id"`
-	VendorCode *string `json:"vendor_code,optional,omitempty"`
-	Signature  *string `json:"signature,optional,omitempty"`
-	Content    *string `json:"content,optional,omitempty"`
-	Status     *bool   `json:"status,optional,omitempty"`
+	ID                 int64                  `json:"id"`
+	Name               *string                `json:"name"`
+	ChannelID          *int64                 `json:"channel_id"`
+	FallbackChannelIDs []int64                `json:"fallback_channel_ids,optional,omitempty"`
+	Fanouts            []TemplateFanoutItem   `json:"fanouts,optional,omitempty"`
+	Locales            []TemplateLocaleItem   `json:"locales,optional,omitempty"`
+	VendorCode         *string                `json:"vendor_code,optional,omitempty"`
+	Signature          *string                `json:"signature,optional,omitempty"`
+	Title              *string                `json:"title,optional,omitempty"`
+	MsgType            *string                `json:"msg_type,optional,omitempty"`
+	Content            *string                `json:"content,optional,omitempty"`
+	Variables          []TemplateVariableItem `json:"variables,optional,omitempty"`
+	Status             *bool                  `json:"status,optional,omitempty"`
+	Remark             string                 `json:"remark,optional,omitempty"` // 版本说明
+}
+
+type TemplateVariableItem struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required,optional"`
+	Pattern     string `json:"pattern,optional"`
+	MinLength   int    `json:"min_length,optional"`
+	MaxLength   int    `json:"max_length,optional"`
+	Description string `json:"description,optional"`
+	Manual      bool   `json:"manual,optional"`
+}
+
+type TemplateVersionDiffReq struct {
+	TemplateID int64 `json:"template_id" form:"template_id"`
+	From       int   `json:"from" form:"from"`
+	To         int   `json:"to" form:"to"`
+}
+
+type TemplateVersionDiffResp struct {
+	From    TemplateVersionItem `json:"from"`
+	To      TemplateVersionItem `json:"to"`
+	Fields  []TemplateDiffField `json:"fields"`
+	Title   []TemplateDiffLine  `json:"title"`
+	Content []TemplateDiffLine  `json:"content"`
+}
+
+type TemplateVersionItem struct {
+	ID                 int64                  `json:"id"`
+	TemplateID         int64                  `json:"template_id"`
+	Version            int                    `json:"version"`
+	ChannelID          int64                  `json:"channel_id"`
+	FallbackChannelIDs []int64                `json:"fallback_channel_ids"`
+	Fanouts            []TemplateFanoutItem   `json:"fanouts"`
+	Locales            []TemplateLocaleItem   `json:"locales"`
+	VendorCode         string                 `json:"vendor_code"`
+	Signature          string                 `json:"signature"`
+	MsgType            string                 `json:"msg_type"`
+	Title              string                 `json:"title"`
+	Content            string                 `json:"content"`
+	Variables          []TemplateVariableItem `json:"variables"`
+	AuthorID           int64                  `json:"author_id"`
+	Author             string                 `json:"author"`
+	Remark             string                 `json:"remark"`
+	Current            bool                   `json:"current"` // 是否为模版当前使用的版本
+	CreatedAt          string                 `json:"created_at"`
+}
+
+type TemplateVersionQueryReq struct {
+	PaginationReq
+	TemplateID int64 `json:"template_id" form:"template_id"`
+}
+
+type TemplateVersionQueryResp struct {
+	Total int64                 `json:"total"`
+	Data  []TemplateVersionItem `json:"data"`
 }
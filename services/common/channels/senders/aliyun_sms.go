@@ -0,0 +1,182 @@
+package senders
+
+import (
+	"chihqiang/msgbox-go/pkg/clientx"
+	"chihqiang/msgbox-go/pkg/htmlx"
+	"chihqiang/msgbox-go/pkg/stringx"
+	"chihqiang/msgbox-go/pkg/timex"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+type AliyunSmsSender struct {
+	AccessKeyID     string `json:"access_key_id" ui:"label=AccessKeyId;type=text;required;placeholder=请输入 AccessKeyId"`
+	AccessKeySecret string `json:"access_key_secret" ui:"label=AccessKeySecret;type=text;required;placeholder=请输入 AccessKeySecret"`
+	Endpoint        string `json:"endpoint" ui:"label=接口地址;type=text;placeholder=请输入 Dysms 接口地址;default=https://dysmsapi.aliyuncs.com"`
+	RegionID        string `json:"region_id" ui:"label=RegionId;type=text;placeholder=请输入地域;default=cn-hangzhou"`
+}
+
+func (a *AliyunSmsSender) SetConfig(config map[string]any) error {
+	return htmlx.MapSet(a, config)
+}
+
+// percentEncode 阿里云 RPC 签名要求的 URL 编码（RFC3986）
+func (a *AliyunSmsSender) percentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	s = strings.ReplaceAll(s, "%7E", "~")
+	return s
+}
+
+// canonicalize 按参数名排序后拼接规范化请求字符串
+func (a *AliyunSmsSender) canonicalize(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, a.percentEncode(key)+"="+a.percentEncode(params[key]))
+	}
+	return strings.Join(pairs, "&")
+}
+
+// sign 计算 RPC 签名：HMAC-SHA1(AccessKeySecret + "&", Method&%2F&percentEncode(query))
+func (a *AliyunSmsSender) sign(method string, params map[string]string) string {
+	stringToSign := strings.Join([]string{method, a.percentEncode("/"), a.percentEncode(a.canonicalize(params))}, "&")
+	mac := hmac.New(sha1.New, []byte(a.AccessKeySecret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (a *AliyunSmsSender) params(message IMessage) (map[string]string, error) {
+	params := map[string]string{
+		"AccessKeyId":      a.AccessKeyID,
+		"Action":           "SendSms",
+		"Format":           "JSON",
+		"RegionId":         a.RegionID,
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureNonce":   stringx.UUID(),
+		"SignatureVersion": "1.0",
+		"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		"Version":          "2017-05-25",
+		"PhoneNumbers":     message.GetReceiver(),
+		"SignName":         message.GetSignature(),
+		"TemplateCode":     message.GetVendorCode(),
+	}
+	if variables := message.GetVariables(); len(variables) > 0 {
+		templateParam := make(map[string]string, len(variables))
+		for key, value := range variables {
+			templateParam[key] = paramString(value)
+		}
+		b, err := json.Marshal(templateParam)
+		if err != nil {
+			return nil, err
+		}
+		params["TemplateParam"] = string(b)
+	}
+	return params, nil
+}
+
+func (a *AliyunSmsSender) Send(ctx context.Context, message IMessage) (resp map[string]any, err error) {
+	if message.GetVendorCode() == "" {
+		return resp, errors.New("aliyun sms template code is empty")
+	}
+	params, err := a.params(message)
+	if err != nil {
+		return resp, err
+	}
+	form := url.Values{}
+	for key, value := range params {
+		form.Set(key, value)
+	}
+	form.Set("Signature", a.sign(http.MethodPost, params))
+	response, err := clientx.PostForm(ctx, strings.TrimRight(strings.TrimSpace(a.Endpoint), "/")+"/", form)
+	if err != nil {
+		return resp, err
+	}
+	defer response.Body.Close()
+	if err = json.NewDecoder(response.Body).Decode(&resp); err != nil {
+		return resp, err
+	}
+	if code, _ := resp["Code"].(string); code != "OK" {
+		msg, _ := resp["Message"].(string)
+		return resp, fmt.Errorf("%s: %s", code, msg)
+	}
+	resp["biz_id"] = resp["BizId"]
+	return resp, nil
+}
+
+// aliyunSmsReport 短信发送状态报告（HTTP 批量推送）
+type aliyunSmsReport struct {
+	PhoneNumber string `json:"phone_number"`
+	SendTime    string `json:"send_time"`
+	ReportTime  string `json:"report_time"`
+	Success     bool   `json:"success"`
+	ErrCode     string `json:"err_code"`
+	ErrMsg      string `json:"err_msg"`
+	SmsSize     string `json:"sms_size"`
+	BizID       string `json:"biz_id"`
+	OutID       string `json:"out_id"`
+}
+
+// MessageID 回执通过 BizId 匹配发送记录
+func (a *AliyunSmsSender) MessageID(response map[string]any) string {
+	bizID, _ := response["biz_id"].(string)
+	return bizID
+}
+
+// ParseReceipts 解析状态报告，请求体为 JSON 数组
+func (a *AliyunSmsSender) ParseReceipts(r *http.Request) ([]Receipt, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	var reports []aliyunSmsReport
+	if err := json.Unmarshal(body, &reports); err != nil {
+		return nil, fmt.Errorf("invalid aliyun sms report: %w", err)
+	}
+	var raws []map[string]any
+	_ = json.Unmarshal(body, &raws)
+	receipts := make([]Receipt, 0, len(reports))
+	for i, report := range reports {
+		receipt := Receipt{
+			MessageID: report.BizID,
+			Receiver:  report.PhoneNumber,
+			Delivered: report.Success,
+			Time:      time.Now(),
+		}
+		if !report.Success {
+			receipt.Error = fmt.Sprintf("%s: %s", report.ErrCode, report.ErrMsg)
+		}
+		if t, err := timex.ParseDateTime(report.ReportTime); err == nil {
+			receipt.Time = t
+		}
+		if i < len(raws) {
+			receipt.Raw = raws[i]
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, nil
+}
+
+// ReceiptAck 应答 code=0 表示接收成功，否则服务商会重推
+func (a *AliyunSmsSender) ReceiptAck(err error) any {
+	if err != nil {
+		return map[string]any{"code": 1, "msg": err.Error()}
+	}
+	return map[string]any{"code": 0, "msg": "成功"}
+}
@@ -0,0 +1,163 @@
+package senders
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestAliyunSmsSenderSign(t *testing.T) {
+	a := &AliyunSmsSender{AccessKeySecret: "testsecret"}
+	for in, want := range map[string]string{"a b": "a%20b", "a*b": "a%2Ab", "a~b": "a~b", "短信": "%E7%9F%AD%E4%BF%A1", "/": "%2F"} {
+		if got := a.percentEncode(in); got != want {
+			t.Errorf("percentEncode(%q) = %s, want %s", in, got, want)
+		}
+	}
+	params := map[string]string{"Version": "2017-05-25", "Action": "SendSms", "SignName": "测试 签名"}
+	if got, want := a.canonicalize(params), "Action=SendSms&SignName=%E6%B5%8B%E8%AF%95%20%E7%AD%BE%E5%90%8D&Version=2017-05-25"; got != want {
+		t.Errorf("canonicalize = %s, want %s", got, want)
+	}
+	mac := hmac.New(sha1.New, []byte("testsecret&"))
+	mac.Write([]byte("POST&%2F&" + a.percentEncode(a.canonicalize(params))))
+	if got, want := a.sign(http.MethodPost, params), base64.StdEncoding.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("sign = %s, want %s", got, want)
+	}
+}
+
+// verifyAliyunSignature 假服务端按 RPC 签名规则独立校验请求签名
+func verifyAliyunSignature(form url.Values, secret string) bool {
+	encode := func(s string) string {
+		return strings.NewReplacer("+", "%20", "*", "%2A", "%7E", "~").Replace(url.QueryEscape(s))
+	}
+	keys := make([]string, 0, len(form))
+	for key := range form {
+		if key != "Signature" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, encode(key)+"="+encode(form.Get(key)))
+	}
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte("POST&%2F&" + encode(strings.Join(pairs, "&"))))
+	return form.Get("Signature") == base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestAliyunSmsSenderSend(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   map[string]any
+		wantErr string
+	}{
+		{"ok", map[string]any{"Code": "OK", "Message": "OK", "BizId": "900619746936498440^0", "RequestId": "r1"}, ""},
+		{"business limit", map[string]any{"Code": "isv.BUSINESS_LIMIT_CONTROL", "Message": "触发分钟级流控", "RequestId": "r2"}, "isv.BUSINESS_LIMIT_CONTROL: 触发分钟级流控"},
+		{"invalid signature", map[string]any{"Code": "SignatureDoesNotMatch", "Message": "Specified signature is not matched", "RequestId": "r3"}, "SignatureDoesNotMatch: Specified signature is not matched"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t, func(w http.ResponseWriter, r capturedRequest) {
+				writeJSON(w, http.StatusOK, tt.reply)
+			})
+			sender := &AliyunSmsSender{}
+			err := sender.SetConfig(map[string]any{
+				"access_key_id":     "testid",
+				"access_key_secret": "testsecret",
+				"endpoint":          server.URL,
+				"region_id":         "cn-hangzhou",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := sender.Send(context.Background(), &testMessage{
+				Receiver:   "13800000000",
+				Signature:  "阿里云短信测试",
+				VendorCode: "SMS_154950909",
+				Variables:  map[string]any{"code": 1234},
+			})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("send failed: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("err = %v, want %s", err, tt.wantErr)
+			}
+			if tt.wantErr == "" && sender.MessageID(resp) != tt.reply["BizId"] {
+				t.Errorf("message id = %q", sender.MessageID(resp))
+			}
+			request := server.Requests()[0]
+			form, err := url.ParseQuery(string(request.Body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if request.Method != http.MethodPost || request.Path != "/" {
+				t.Errorf("request = %s %s", request.Method, request.Path)
+			}
+			if !verifyAliyunSignature(form, "testsecret") {
+				t.Errorf("signature mismatch: %v", form)
+			}
+			for key, want := range map[string]string{"Action": "SendSms", "PhoneNumbers": "13800000000", "SignName": "阿里云短信测试", "TemplateCode": "SMS_154950909", "AccessKeyId": "testid"} {
+				if form.Get(key) != want {
+					t.Errorf("%s = %q, want %q", key, form.Get(key), want)
+				}
+			}
+			var templateParam map[string]string
+			if err := json.Unmarshal([]byte(form.Get("TemplateParam")), &templateParam); err != nil || templateParam["code"] != "1234" {
+				t.Errorf("TemplateParam = %s", form.Get("TemplateParam"))
+			}
+		})
+	}
+}
+
+// 请求变量经 JSON 解码后数字为 float64，7 位验证码不能格式化为科学计数法
+func TestAliyunSmsSenderNumberParam(t *testing.T) {
+	var variables map[string]any
+	if err := json.Unmarshal([]byte(`{"code":1234567,"amount":12.5}`), &variables); err != nil {
+		t.Fatal(err)
+	}
+	sender := &AliyunSmsSender{}
+	params, err := sender.params(&testMessage{Receiver: "13800000000", VendorCode: "SMS_154950909", Variables: variables})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var templateParam map[string]string
+	if err := json.Unmarshal([]byte(params["TemplateParam"]), &templateParam); err != nil {
+		t.Fatal(err)
+	}
+	if templateParam["code"] != "1234567" || templateParam["amount"] != "12.5" {
+		t.Errorf("TemplateParam = %s", params["TemplateParam"])
+	}
+}
+
+func TestAliyunSmsSenderEmptyTemplate(t *testing.T) {
+	sender := &AliyunSmsSender{Endpoint: "http://127.0.0.1:0"}
+	if _, err := sender.Send(context.Background(), &testMessage{Receiver: "13800000000"}); err == nil {
+		t.Fatal("empty template code should be rejected")
+	}
+}
+
+func TestAliyunSmsSenderParseReceipts(t *testing.T) {
+	body := `[{"phone_number":"13800000000","report_time":"2024-01-02 03:04:05","success":false,"err_code":"IS_CLOSE","err_msg":"停机","biz_id":"b1"},` +
+		`{"phone_number":"13800000001","success":true,"biz_id":"b2"}]`
+	request, _ := http.NewRequest(http.MethodPost, "/receipt/aliyun_sms", strings.NewReader(body))
+	receipts, err := (&AliyunSmsSender{}).ParseReceipts(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receipts) != 2 {
+		t.Fatalf("receipts = %d", len(receipts))
+	}
+	if receipts[0].MessageID != "b1" || receipts[0].Delivered || receipts[0].Error != "IS_CLOSE: 停机" || receipts[0].Time.Year() != 2024 {
+		t.Errorf("receipt 0 = %+v", receipts[0])
+	}
+	if receipts[1].MessageID != "b2" || !receipts[1].Delivered || receipts[1].Raw["biz_id"] != "b2" {
+		t.Errorf("receipt 1 = %+v", receipts[1])
+	}
+}
@@ -1,9 +1,36 @@
 package senders
 
+import (
+	"chihqiang/msgbox-go/pkg/templatex"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 消息类型，由模板 msg_type 配置或请求 extra.msg_type 指定，发送器按各自支持的类型构建请求
+const (
+	MsgTypeText         = "text"          // 纯文本（默认）
+	MsgTypeMarkdown     = "markdown"      // 钉钉、企业微信
+	MsgTypeLink         = "link"          // 钉钉
+	MsgTypeActionCard   = "actionCard"    // 钉钉
+	MsgTypeFeedCard     = "feedCard"      // 钉钉
+	MsgTypeNews         = "news"          // 企业微信
+	MsgTypeTemplateCard = "template_card" // 企业微信
+	MsgTypePost         = "post"          // 飞书富文本
+	MsgTypeInteractive  = "interactive"   // 飞书消息卡片
+)
+
+// ReceiverAll 群机器人接收者为 all 时 @所有人
+const ReceiverAll = "all"
+
 type IMessage interface {
 	GetReceiver() string
 	GetSignature() string
 	GetVendorCode() string
+	GetMsgType() string
 	GetTitle() string
 	GetContent() string
 	GetVariables() map[string]any
@@ -12,5 +39,72 @@ type IMessage interface {
 
 type ISender interface {
 	SetConfig(config map[string]any) error
-	Send(message IMessage) (map[string]any, error)
+	// Send 发送消息，ctx 携带请求链路、截止时间与取消信号，发送器需透传给服务商调用
+	Send(ctx context.Context, message IMessage) (map[string]any, error)
+}
+
+// IMentionSender 群机器人类发送器可选实现：一条群消息同时 @多个接收者，
+// 通道开启合并提醒后，同一批次的全部接收者只发送一次
+type IMentionSender interface {
+	ISender
+	SendMention(ctx context.Context, message IMessage, receivers []string) (map[string]any, error)
+}
+
+// Receipt 服务商回执（状态报告）
+type Receipt struct {
+	MessageID string         // 服务商消息ID，与发送响应中提取的 MessageID 对应
+	Receiver  string         // 接收者，同一消息ID对应多个接收者时用于区分
+	Delivered bool           // 是否送达
+	Error     string         // 未送达时的错误描述
+	Time      time.Time      // 送达或失败时间，服务商未提供时为回调时间
+	Raw       map[string]any // 回执原始内容
+}
+
+// IReceiptSender 支持回执回调的发送器可选实现
+type IReceiptSender interface {
+	ISender
+	// MessageID 从发送响应中提取服务商消息ID，已受理的记录保存该ID用于匹配回执
+	MessageID(response map[string]any) string
+	// ParseReceipts 解析回执回调请求，回调不携带通道配置，实现不能依赖 SetConfig
+	ParseReceipts(r *http.Request) ([]Receipt, error)
+	// ReceiptAck 服务商要求的回调应答内容，err 为处理失败原因
+	ReceiptAck(err error) any
+}
+
+// IVariableSender 配置中引用模板变量的发送器可选实现（如 webhook 请求体模板），
+// 保存消息模板时这些变量与模板内容中的变量一起生成变量声明
+type IVariableSender interface {
+	ISender
+	// Variables 配置引用的模板变量，不包括发送器自行填充的内置变量
+	Variables() ([]templatex.Variable, error)
+}
+
+// extraString 读取 extra 中的字符串参数
+func extraString(message IMessage, key string) string {
+	value, _ := message.GetExtra()[key].(string)
+	return value
+}
+
+// paramString 模板参数转为字符串；JSON 解码的数字为 float64，按普通小数格式输出，避免验证码等长数字变成 1.234567e+06
+func paramString(value any) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// titleOf 消息标题，未配置标题时截取内容首行
+func titleOf(message IMessage) string {
+	if title := message.GetTitle(); title != "" {
+		return title
+	}
+	line := []rune(strings.SplitN(message.GetContent(), "\n", 2)[0])
+	if len(line) > 20 {
+		line = line[:20]
+	}
+	return string(line)
 }
@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -42,27 +43,128 @@ func (d *DingTalkSender) url() *url.URL {
 	return uri
 }
 
-func (d *DingTalkSender) Send(message IMessage) (resp map[string]any, err error) {
-	req := map[string]any{
-		"msgtype": "text",
-		"text": map[string]any{
-			"content": message.GetContent(),
-		},
+// at 构建 at 字段，receivers 包含 all 时 @所有人，其余按手机号处理
+func (d *DingTalkSender) at(receivers []string) map[string]any {
+	mobiles := make([]string, 0, len(receivers))
+	isAtAll := false
+	for _, receiver := range receivers {
+		switch receiver {
+		case "":
+		case ReceiverAll:
+			isAtAll = true
+		default:
+			mobiles = append(mobiles, receiver)
+		}
 	}
-	receiver := message.GetReceiver()
-	switch receiver {
-	case "all":
-		req["isAtAll"] = true
+	return map[string]any{
+		"atMobiles": mobiles,
+		"isAtAll":   isAtAll,
+	}
+}
+
+// mentionText markdown 消息需要在正文中包含 @手机号 才会高亮提醒
+func (d *DingTalkSender) mentionText(content string, receivers []string) string {
+	mentions := make([]string, 0, len(receivers))
+	for _, receiver := range receivers {
+		if receiver != "" && receiver != ReceiverAll {
+			mentions = append(mentions, "@"+receiver)
+		}
+	}
+	if len(mentions) == 0 {
+		return content
+	}
+	return content + "\n\n" + strings.Join(mentions, " ")
+}
+
+// build 按消息类型构建请求体，link/actionCard/feedCard 所需的链接、按钮等参数来自 extra
+func (d *DingTalkSender) build(message IMessage, receivers []string) (map[string]any, error) {
+	switch msgType := message.GetMsgType(); msgType {
+	case MsgTypeText, "":
+		return map[string]any{
+			"msgtype": MsgTypeText,
+			"text": map[string]any{
+				"content": message.GetContent(),
+			},
+		}, nil
+	case MsgTypeMarkdown:
+		return map[string]any{
+			"msgtype": MsgTypeMarkdown,
+			"markdown": map[string]any{
+				"title": titleOf(message),
+				"text":  d.mentionText(message.GetContent(), receivers),
+			},
+		}, nil
+	case MsgTypeLink:
+		messageURL := extraString(message, "message_url")
+		if messageURL == "" {
+			return nil, errors.New("dingtalk link message requires extra.message_url")
+		}
+		return map[string]any{
+			"msgtype": MsgTypeLink,
+			"link": map[string]any{
+				"title":      titleOf(message),
+				"text":       message.GetContent(),
+				"messageUrl": messageURL,
+				"picUrl":     extraString(message, "pic_url"),
+			},
+		}, nil
+	case MsgTypeActionCard:
+		card := map[string]any{
+			"title":          titleOf(message),
+			"text":           message.GetContent(),
+			"btnOrientation": extraString(message, "btn_orientation"),
+		}
+		if btns, ok := message.GetExtra()["btns"]; ok && btns != nil {
+			card["btns"] = btns
+		} else {
+			card["singleTitle"] = extraString(message, "single_title")
+			card["singleURL"] = extraString(message, "single_url")
+		}
+		return map[string]any{
+			"msgtype":    MsgTypeActionCard,
+			"actionCard": card,
+		}, nil
+	case MsgTypeFeedCard:
+		links, ok := message.GetExtra()["links"]
+		if !ok || links == nil {
+			return nil, errors.New("dingtalk feedCard message requires extra.links")
+		}
+		return map[string]any{
+			"msgtype": MsgTypeFeedCard,
+			"feedCard": map[string]any{
+				"links": links,
+			},
+		}, nil
 	default:
-		req["atMobiles"] = []string{receiver}
+		return nil, fmt.Errorf("dingtalk does not support msg type %s", msgType)
+	}
+}
+
+func (d *DingTalkSender) Send(ctx context.Context, message IMessage) (resp map[string]any, err error) {
+	return d.SendMention(ctx, message, []string{message.GetReceiver()})
+}
+
+// SendMention 发送一条群消息并 @全部接收者
+func (d *DingTalkSender) SendMention(ctx context.Context, message IMessage, receivers []string) (resp map[string]any, err error) {
+	req, err := d.build(message, receivers)
+	if err != nil {
+		return resp, err
 	}
-	response, err := clientx.PostJSON(context.Background(), d.url().String(), req)
+	req["at"] = d.at(receivers)
+	response, err := clientx.PostJSON(ctx, d.url().String(), req)
 	if err != nil {
 		return resp, err
 	}
-	err = json.NewDecoder(response.Body).Decode(&resp)
+	defer response.Body.Close()
+	if err = json.NewDecoder(response.Body).Decode(&resp); err != nil {
+		return resp, err
+	}
 	if errCode, ok := resp["errcode"].(float64); ok && errCode != 0 {
-		err = errors.New(resp["errmsg"].(string))
+		msg, _ := resp["errmsg"].(string)
+		if msg == "" {
+			msg = fmt.Sprintf("dingtalk errcode %v", errCode)
+		}
+		return resp, errors.New(msg)
 	}
-	return resp, err
+	return resp, nil
 }
@@ -0,0 +1,42 @@
+package senders
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestDingTalkSenderSendMention(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   map[string]any
+		wantErr string
+	}{
+		{"ok", map[string]any{"errcode": 0, "errmsg": "ok"}, ""},
+		{"errmsg", map[string]any{"errcode": 310000, "errmsg": "keywords not in content"}, "keywords not in content"},
+		{"no errmsg", map[string]any{"errcode": 130101}, "dingtalk errcode 130101"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t, func(w http.ResponseWriter, r capturedRequest) {
+				writeJSON(w, http.StatusOK, tt.reply)
+			})
+			sender := &DingTalkSender{}
+			if err := sender.SetConfig(map[string]any{"endpoint": server.URL + "/robot/send", "access_token": "token"}); err != nil {
+				t.Fatal(err)
+			}
+			_, err := sender.SendMention(context.Background(), &testMessage{Content: "hello"}, []string{"13800000000", "13900000000"})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("send failed: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("err = %v, want %s", err, tt.wantErr)
+			}
+			body := server.Requests()[0].JSON(t)
+			at, _ := body["at"].(map[string]any)
+			if mobiles, _ := at["atMobiles"].([]any); len(mobiles) != 2 {
+				t.Errorf("at = %v", body["at"])
+			}
+		})
+	}
+}
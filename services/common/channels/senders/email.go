@@ -0,0 +1,251 @@
+package senders
+
+import (
+	"bytes"
+	"chihqiang/msgbox-go/pkg/htmlx"
+	"chihqiang/msgbox-go/pkg/stringx"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTP 连接加密方式
+const (
+	EmailTLSModeNone     = "none"     // 明文连接
+	EmailTLSModeStartTLS = "starttls" // 明文连接后升级 STARTTLS
+	EmailTLSModeTLS      = "tls"      // 隐式 TLS（SMTPS）
+)
+
+var (
+	// emailRootCAs 校验 SMTP 服务器证书的根证书，nil 表示使用系统根证书
+	emailRootCAs *x509.CertPool
+
+	emailHTMLPattern     = regexp.MustCompile(`(?i)<\s*(html|body|div|p|br|table|span|a|b|i|strong|em|h[1-6]|ul|ol|li|img|font)(\s[^>]*)?/?\s*>`)
+	emailHTMLTagPattern  = regexp.MustCompile(`(?s)<[^>]*>`)
+	emailHTMLLinePattern = regexp.MustCompile(`(?i)<\s*(br\s*/?|/p|/div|/h[1-6]|/li|/tr)\s*>`)
+)
+
+type EmailSender struct {
+	Host     string `json:"host" ui:"label=SMTP服务器;type=text;required;placeholder=请输入 SMTP 服务器地址，如 smtp.qq.com"`
+	Port     int    `json:"port" ui:"label=端口;type=text;placeholder=请输入端口（默认 tls=465、starttls=587、none=25）"`
+	Username string `json:"username" ui:"label=用户名;type=text;placeholder=请输入 SMTP 登录用户名（可选）"`
+	Password string `json:"password" ui:"label=密码;type=text;placeholder=请输入 SMTP 登录密码或授权码（可选）"`
+	From     string `json:"from" ui:"label=发件人地址;type=text;required;placeholder=请输入发件人邮箱"`
+	FromName string `json:"from_name" ui:"label=发件人名称;type=text;placeholder=请输入发件人显示名称（可选）"`
+	TLSMode  string `json:"tls_mode" ui:"label=加密方式;type=text;placeholder=tls、starttls 或 none;default=tls"`
+}
+
+func (e *EmailSender) SetConfig(config map[string]any) error {
+	return htmlx.MapSet(e, config)
+}
+
+func (e *EmailSender) addr() string {
+	port := e.Port
+	if port <= 0 {
+		switch e.TLSMode {
+		case EmailTLSModeStartTLS:
+			port = 587
+		case EmailTLSModeNone:
+			port = 25
+		default:
+			port = 465
+		}
+	}
+	return net.JoinHostPort(strings.TrimSpace(e.Host), strconv.Itoa(port))
+}
+
+// receivers 拆分接收者，支持逗号、分号、空白分隔的多个邮箱
+func (e *EmailSender) receivers(receiver string) ([]string, error) {
+	fields := strings.FieldsFunc(receiver, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\n' || r == '\t'
+	})
+	list := make([]string, 0, len(fields))
+	for _, field := range fields {
+		address, err := mail.ParseAddress(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid email receiver %q: %w", field, err)
+		}
+		list = append(list, address.Address)
+	}
+	if len(list) == 0 {
+		return nil, errors.New("email receiver is empty")
+	}
+	return list, nil
+}
+
+// isHTML 判断模板内容是否为 HTML
+func (e *EmailSender) isHTML(content string) bool {
+	return emailHTMLPattern.MatchString(content)
+}
+
+// plainText 将 HTML 内容转换为纯文本，作为 multipart 中的 text/plain 部分
+func (e *EmailSender) plainText(content string) string {
+	text := emailHTMLLinePattern.ReplaceAllString(content, "\n")
+	text = emailHTMLTagPattern.ReplaceAllString(text, "")
+	return strings.TrimSpace(html.UnescapeString(text))
+}
+
+func (e *EmailSender) writePart(w *multipart.Writer, contentType, body string) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType + "; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// buildMessage 构建 MIME 邮件，HTML 内容使用 multipart/alternative 同时携带纯文本与 HTML
+func (e *EmailSender) buildMessage(message IMessage, from *mail.Address, receivers []string) (string, []byte, error) {
+	messageID := fmt.Sprintf("<%s@%s>", stringx.UUID(), strings.TrimSpace(e.Host))
+	var buf bytes.Buffer
+	headers := []string{
+		"From: " + from.String(),
+		"To: " + strings.Join(receivers, ", "),
+		"Subject: " + mime.BEncoding.Encode("UTF-8", message.GetTitle()),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Message-ID: " + messageID,
+		"MIME-Version: 1.0",
+	}
+	content := message.GetContent()
+	if !e.isHTML(content) {
+		headers = append(headers,
+			"Content-Type: text/plain; charset=UTF-8",
+			"Content-Transfer-Encoding: quoted-printable",
+		)
+		buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+		qp := quotedprintable.NewWriter(&buf)
+		if _, err := qp.Write([]byte(content)); err != nil {
+			return "", nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return "", nil, err
+		}
+		return messageID, buf.Bytes(), nil
+	}
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	headers = append(headers, fmt.Sprintf("Content-Type: multipart/alternative; boundary=%q", w.Boundary()))
+	if err := e.writePart(w, "text/plain", e.plainText(content)); err != nil {
+		return "", nil, err
+	}
+	if err := e.writePart(w, "text/html", content); err != nil {
+		return "", nil, err
+	}
+	if err := w.Close(); err != nil {
+		return "", nil, err
+	}
+	buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+	buf.Write(body.Bytes())
+	return messageID, buf.Bytes(), nil
+}
+
+func (e *EmailSender) dial(ctx context.Context) (*smtp.Client, error) {
+	host := strings.TrimSpace(e.Host)
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if e.TLSMode == EmailTLSModeNone || e.TLSMode == EmailTLSModeStartTLS {
+		conn, err = dialer.DialContext(ctx, "tcp", e.addr())
+	} else {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host, RootCAs: emailRootCAs}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", e.addr())
+	}
+	if err != nil {
+		return nil, err
+	}
+	// SMTP 会话整体截止时间取 30s 与 ctx 截止时间中较早者
+	deadline := time.Now().Add(30 * time.Second)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if e.TLSMode == EmailTLSModeStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			_ = client.Close()
+			return nil, errors.New("smtp server does not support STARTTLS")
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: host, RootCAs: emailRootCAs}); err != nil {
+			_ = client.Close()
+			return nil, err
+		}
+	}
+	// 配置了账号时服务器必须支持 AUTH，否则以未认证身份发送通常会被拒收或被当作垃圾邮件
+	if e.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			_ = client.Close()
+			return nil, errors.New("smtp server does not support AUTH")
+		}
+		if err := client.Auth(smtp.PlainAuth("", e.Username, e.Password, host)); err != nil {
+			_ = client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+func (e *EmailSender) Send(ctx context.Context, message IMessage) (resp map[string]any, err error) {
+	receivers, err := e.receivers(message.GetReceiver())
+	if err != nil {
+		return resp, err
+	}
+	from := &mail.Address{Name: e.FromName, Address: strings.TrimSpace(e.From)}
+	messageID, msg, err := e.buildMessage(message, from, receivers)
+	if err != nil {
+		return resp, err
+	}
+	client, err := e.dial(ctx)
+	if err != nil {
+		return resp, err
+	}
+	defer client.Close()
+	if err = client.Mail(from.Address); err != nil {
+		return resp, err
+	}
+	for _, receiver := range receivers {
+		if err = client.Rcpt(receiver); err != nil {
+			return resp, err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return resp, err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return resp, err
+	}
+	if err = w.Close(); err != nil {
+		return resp, err
+	}
+	_ = client.Quit()
+	return map[string]any{
+		"message_id": messageID,
+		"receivers":  receivers,
+	}, nil
+}
@@ -0,0 +1,324 @@
+package senders
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// smtpSession 假 SMTP 服务器记录的一次会话
+type smtpSession struct {
+	TLS  bool
+	Auth string // AUTH PLAIN 解码后的 identity\x00username\x00password
+	From string
+	Rcpt []string
+	Data string
+}
+
+// fakeSMTPServer 进程内的 SMTP 服务器，支持隐式 TLS、STARTTLS 与 AUTH PLAIN
+type fakeSMTPServer struct {
+	listener net.Listener
+	tls      *tls.Config
+	implicit bool // 隐式 TLS，否则在 EHLO 中提供 STARTTLS
+	noAuth   bool // EHLO 中不提供 AUTH
+	mu       sync.Mutex
+	sessions []*smtpSession
+}
+
+// testTLSConfig 使用 httptest 自带的 127.0.0.1 证书，返回服务端配置与信任该证书的根证书池
+func testTLSConfig(t *testing.T) (*tls.Config, *x509.CertPool) {
+	t.Helper()
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	return &tls.Config{Certificates: server.TLS.Certificates}, pool
+}
+
+func newFakeSMTPServer(t *testing.T, implicit bool) *fakeSMTPServer {
+	t.Helper()
+	config, pool := testTLSConfig(t)
+	previous := emailRootCAs
+	emailRootCAs = pool
+	t.Cleanup(func() { emailRootCAs = previous })
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if implicit {
+		listener = tls.NewListener(listener, config)
+	}
+	s := &fakeSMTPServer{listener: listener, tls: config, implicit: implicit}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// config 指向假服务器的发送器配置
+func (s *fakeSMTPServer) config(mode string) map[string]any {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return map[string]any{"host": "127.0.0.1", "port": addr.Port, "from": "noreply@example.com", "from_name": "MsgBox", "tls_mode": mode}
+}
+
+func (s *fakeSMTPServer) Sessions() []*smtpSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*smtpSession(nil), s.sessions...)
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	session := &smtpSession{TLS: s.implicit}
+	reader := bufio.NewReader(conn)
+	reply := func(lines ...string) {
+		_, _ = io.WriteString(conn, strings.Join(lines, "\r\n")+"\r\n")
+	}
+	reply("220 fake ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			lines := []string{"250-fake"}
+			if !session.TLS {
+				lines = append(lines, "250-STARTTLS")
+			}
+			if !s.noAuth {
+				lines = append(lines, "250-AUTH PLAIN")
+			}
+			reply(append(lines, "250 8BITMIME")...)
+		case "STARTTLS":
+			reply("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, reader, session.TLS = tlsConn, bufio.NewReader(tlsConn), true
+		case "AUTH":
+			mechanism, response, _ := strings.Cut(arg, " ")
+			decoded, err := base64.StdEncoding.DecodeString(response)
+			if !strings.EqualFold(mechanism, "PLAIN") || err != nil {
+				reply("504 unsupported")
+				continue
+			}
+			session.Auth = string(decoded)
+			reply("235 authenticated")
+		case "MAIL":
+			from, _, _ := strings.Cut(strings.TrimPrefix(arg, "FROM:"), " ")
+			session.From = strings.Trim(from, "<>")
+			reply("250 ok")
+		case "RCPT":
+			session.Rcpt = append(session.Rcpt, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			reply("250 ok")
+		case "DATA":
+			reply("354 end with .")
+			var data strings.Builder
+			for {
+				l, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			session.Data = data.String()
+			s.mu.Lock()
+			s.sessions = append(s.sessions, session)
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestEmailSenderAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		implicit bool
+		username string
+		wantTLS  bool
+		wantAuth string
+	}{
+		{"implicit tls with auth", EmailTLSModeTLS, true, "user", true, "\x00user\x00pass"},
+		{"starttls with auth", EmailTLSModeStartTLS, false, "user", true, "\x00user\x00pass"},
+		{"plain with auth", EmailTLSModeNone, false, "user", false, "\x00user\x00pass"},
+		{"plain without auth", EmailTLSModeNone, false, "", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeSMTPServer(t, tt.implicit)
+			config := server.config(tt.mode)
+			config["username"], config["password"] = tt.username, "pass"
+			sender := &EmailSender{}
+			if err := sender.SetConfig(config); err != nil {
+				t.Fatal(err)
+			}
+			resp, err := sender.Send(context.Background(), &testMessage{Receiver: "a@example.com", Title: "Hi", Content: "hello"})
+			if err != nil {
+				t.Fatalf("send failed: %v", err)
+			}
+			if resp["message_id"] == "" {
+				t.Errorf("message_id is empty")
+			}
+			sessions := server.Sessions()
+			if len(sessions) != 1 {
+				t.Fatalf("sessions = %d", len(sessions))
+			}
+			if sessions[0].TLS != tt.wantTLS {
+				t.Errorf("tls = %v, want %v", sessions[0].TLS, tt.wantTLS)
+			}
+			if sessions[0].Auth != tt.wantAuth {
+				t.Errorf("auth = %q, want %q", sessions[0].Auth, tt.wantAuth)
+			}
+			if sessions[0].From != "noreply@example.com" {
+				t.Errorf("from = %q", sessions[0].From)
+			}
+		})
+	}
+}
+
+// TestEmailSenderAuthUnsupported 配置了账号但服务器不支持 AUTH 时返回错误，不以未认证身份发送
+func TestEmailSenderAuthUnsupported(t *testing.T) {
+	server := newFakeSMTPServer(t, true)
+	server.noAuth = true
+	config := server.config(EmailTLSModeTLS)
+	config["username"], config["password"] = "user", "pass"
+	sender := &EmailSender{}
+	if err := sender.SetConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	_, err := sender.Send(context.Background(), &testMessage{Receiver: "a@example.com", Title: "hi", Content: "hello"})
+	if err == nil || err.Error() != "smtp server does not support AUTH" {
+		t.Fatalf("err = %v, want AUTH unsupported", err)
+	}
+	if n := len(server.Sessions()); n != 0 {
+		t.Errorf("sessions = %d, want no message sent", n)
+	}
+}
+
+func TestEmailSenderBody(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantType  string
+		wantParts map[string]string
+	}{
+		{"plain text", "您的验证码是 1234", "text/plain", map[string]string{"text/plain": "您的验证码是 1234"}},
+		{"html", "<p>您的验证码是 <b>1234</b></p>", "multipart/alternative", map[string]string{
+			"text/plain": "您的验证码是 1234",
+			"text/html":  "<p>您的验证码是 <b>1234</b></p>",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeSMTPServer(t, true)
+			sender := &EmailSender{}
+			if err := sender.SetConfig(server.config(EmailTLSModeTLS)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := sender.Send(context.Background(), &testMessage{Receiver: "a@example.com", Title: "验证码", Content: tt.content}); err != nil {
+				t.Fatalf("send failed: %v", err)
+			}
+			msg, err := mail.ReadMessage(strings.NewReader(server.Sessions()[0].Data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); subject != "验证码" {
+				t.Errorf("subject = %q", subject)
+			}
+			mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+			if err != nil || mediaType != tt.wantType {
+				t.Fatalf("content type = %q, err: %v", mediaType, err)
+			}
+			parts := map[string]string{}
+			if mediaType == "text/plain" {
+				body, _ := io.ReadAll(quotedprintable.NewReader(msg.Body))
+				parts[mediaType] = strings.TrimRight(string(body), "\r\n")
+			} else {
+				reader := multipart.NewReader(msg.Body, params["boundary"])
+				for {
+					part, err := reader.NextRawPart()
+					if err != nil {
+						break
+					}
+					partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+					body, _ := io.ReadAll(quotedprintable.NewReader(part))
+					parts[partType] = strings.TrimRight(string(body), "\r\n")
+				}
+			}
+			for partType, want := range tt.wantParts {
+				if parts[partType] != want {
+					t.Errorf("%s = %q, want %q", partType, parts[partType], want)
+				}
+			}
+		})
+	}
+}
+
+func TestEmailSenderMultipleReceivers(t *testing.T) {
+	server := newFakeSMTPServer(t, true)
+	sender := &EmailSender{}
+	if err := sender.SetConfig(server.config(EmailTLSModeTLS)); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := sender.Send(context.Background(), &testMessage{Receiver: "a@example.com, b@example.com;c@example.com", Content: "hi"})
+	if err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	want := []string{"a@example.com", "b@example.com", "c@example.com"}
+	session := server.Sessions()[0]
+	if strings.Join(session.Rcpt, ",") != strings.Join(want, ",") {
+		t.Errorf("rcpt = %v, want %v", session.Rcpt, want)
+	}
+	if got := resp["receivers"].([]string); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("receivers = %v", got)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(session.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if to := msg.Header.Get("To"); to != strings.Join(want, ", ") {
+		t.Errorf("to = %q", to)
+	}
+}
+
+func TestEmailSenderInvalidReceiver(t *testing.T) {
+	sender := &EmailSender{Host: "127.0.0.1", From: "noreply@example.com"}
+	for _, receiver := range []string{"", "not-an-email"} {
+		if _, err := sender.Send(context.Background(), &testMessage{Receiver: receiver, Content: "hi"}); err == nil {
+			t.Errorf("receiver %q should be rejected", receiver)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package senders
+
+import (
+	"chihqiang/msgbox-go/pkg/clientx"
+	"chihqiang/msgbox-go/pkg/htmlx"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type FeishuSender struct {
+	Webhook string `json:"webhook" ui:"label=Webhook地址;type=text;required;placeholder=请输入 Webhook，如 https://open.feishu.cn/open-apis/bot/v2/hook/xxx"`
+	Secret  string `json:"secret" ui:"label=签名校验Secret;type=text;placeholder=请输入签名校验 Secret（可选）"`
+}
+
+func (f *FeishuSender) SetConfig(config map[string]any) error {
+	return htmlx.MapSet(f, config)
+}
+
+// sign 计算签名：以 timestamp + "\n" + secret 作为 key 对空串做 HmacSHA256，再 Base64
+func (f *FeishuSender) sign(timestamp int64) string {
+	stringToSign := fmt.Sprintf("%d\n%s", timestamp, f.Secret)
+	mac := hmac.New(sha256.New, []byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// mention 构建 @ 标签，all 表示 @所有人，其余按 open_id/user_id 处理
+func (f *FeishuSender) mention(receiver string) string {
+	switch receiver {
+	case "":
+		return ""
+	case "all":
+		return `<at user_id="all">所有人</at>`
+	default:
+		return fmt.Sprintf(`<at user_id="%s"></at>`, receiver)
+	}
+}
+
+func (f *FeishuSender) buildText(message IMessage) map[string]any {
+	text := message.GetContent()
+	if at := f.mention(message.GetReceiver()); at != "" {
+		text = strings.Join([]string{text, at}, " ")
+	}
+	return map[string]any{
+		"msg_type": MsgTypeText,
+		"content": map[string]any{
+			"text": text,
+		},
+	}
+}
+
+// buildPost 富文本消息：每一行内容作为一个段落，最后追加 @ 段落
+func (f *FeishuSender) buildPost(message IMessage) map[string]any {
+	paragraphs := make([][]map[string]any, 0)
+	for _, line := range strings.Split(message.GetContent(), "\n") {
+		paragraphs = append(paragraphs, []map[string]any{
+			{"tag": "text", "text": line},
+		})
+	}
+	if receiver := message.GetReceiver(); receiver != "" {
+		paragraphs = append(paragraphs, []map[string]any{
+			{"tag": "at", "user_id": receiver},
+		})
+	}
+	return map[string]any{
+		"msg_type": MsgTypePost,
+		"content": map[string]any{
+			"post": map[string]any{
+				"zh_cn": map[string]any{
+					"title":   message.GetTitle(),
+					"content": paragraphs,
+				},
+			},
+		},
+	}
+}
+
+// buildInteractive 消息卡片：extra.card 存在时原样透传，否则使用标题 + markdown 内容生成卡片
+func (f *FeishuSender) buildInteractive(message IMessage) map[string]any {
+	if card, ok := message.GetExtra()["card"]; ok && card != nil {
+		return map[string]any{
+			"msg_type": MsgTypeInteractive,
+			"card":     card,
+		}
+	}
+	content := message.GetContent()
+	if at := f.mention(message.GetReceiver()); at != "" {
+		content = strings.Join([]string{content, at}, "\n")
+	}
+	card := map[string]any{
+		"elements": []map[string]any{
+			{"tag": "markdown", "content": content},
+		},
+	}
+	if title := message.GetTitle(); title != "" {
+		card["header"] = map[string]any{
+			"title": map[string]any{
+				"tag":     "plain_text",
+				"content": title,
+			},
+		}
+	}
+	return map[string]any{
+		"msg_type": MsgTypeInteractive,
+		"card":     card,
+	}
+}
+
+func (f *FeishuSender) Send(ctx context.Context, message IMessage) (resp map[string]any, err error) {
+	var req map[string]any
+	switch message.GetMsgType() {
+	case MsgTypePost:
+		req = f.buildPost(message)
+	case MsgTypeInteractive:
+		req = f.buildInteractive(message)
+	default:
+		req = f.buildText(message)
+	}
+	if f.Secret != "" {
+		timestamp := time.Now().Unix()
+		req["timestamp"] = strconv.FormatInt(timestamp, 10)
+		req["sign"] = f.sign(timestamp)
+	}
+	response, err := clientx.PostJSON(ctx, strings.TrimSpace(f.Webhook), req)
+	if err != nil {
+		return resp, err
+	}
+	defer response.Body.Close()
+	if err = json.NewDecoder(response.Body).Decode(&resp); err != nil {
+		return resp, err
+	}
+	// 新版接口返回 code/msg，旧版接口返回 StatusCode/StatusMessage
+	if code, ok := resp["code"].(float64); ok && code != 0 {
+		msg, _ := resp["msg"].(string)
+		return resp, errors.New(msg)
+	}
+	if code, ok := resp["StatusCode"].(float64); ok && code != 0 {
+		msg, _ := resp["StatusMessage"].(string)
+		return resp, errors.New(msg)
+	}
+	return resp, nil
+}
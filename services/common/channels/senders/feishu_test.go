@@ -0,0 +1,149 @@
+package senders
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeishuSenderSign(t *testing.T) {
+	f := &FeishuSender{Secret: "s3cret"}
+	mac := hmac.New(sha256.New, []byte("1700000000\ns3cret"))
+	want := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if got := f.sign(1700000000); got != want {
+		t.Fatalf("sign = %s, want %s", got, want)
+	}
+}
+
+func TestFeishuSenderSend(t *testing.T) {
+	server := newFakeServer(t, func(w http.ResponseWriter, r capturedRequest) {
+		writeJSON(w, http.StatusOK, map[string]any{"code": 0, "msg": "success"})
+	})
+	sender := &FeishuSender{}
+	if err := sender.SetConfig(map[string]any{"webhook": server.URL + "/open-apis/bot/v2/hook/abc", "secret": "s3cret"}); err != nil {
+		t.Fatal(err)
+	}
+	before := time.Now().Unix()
+	if _, err := sender.Send(context.Background(), &testMessage{Receiver: "ou_1", Content: "hello"}); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	requests := server.Requests()
+	if len(requests) != 1 || requests[0].Path != "/open-apis/bot/v2/hook/abc" {
+		t.Fatalf("unexpected requests: %+v", requests)
+	}
+	body := requests[0].JSON(t)
+	if body["msg_type"] != MsgTypeText {
+		t.Errorf("msg_type = %v", body["msg_type"])
+	}
+	text, _ := body["content"].(map[string]any)["text"].(string)
+	if !strings.HasPrefix(text, "hello") || !strings.Contains(text, `<at user_id="ou_1"></at>`) {
+		t.Errorf("text = %q", text)
+	}
+	timestamp, err := strconv.ParseInt(body["timestamp"].(string), 10, 64)
+	if err != nil || timestamp < before || timestamp > time.Now().Unix() {
+		t.Fatalf("timestamp = %v", body["timestamp"])
+	}
+	if body["sign"] != sender.sign(timestamp) {
+		t.Errorf("sign = %v, want %s", body["sign"], sender.sign(timestamp))
+	}
+}
+
+func TestFeishuSenderPayload(t *testing.T) {
+	tests := []struct {
+		name  string
+		msg   *testMessage
+		check func(t *testing.T, body map[string]any)
+	}{
+		{
+			name: "post",
+			msg:  &testMessage{MsgType: MsgTypePost, Title: "标题", Content: "第一行\n第二行", Receiver: "all"},
+			check: func(t *testing.T, body map[string]any) {
+				post := body["content"].(map[string]any)["post"].(map[string]any)["zh_cn"].(map[string]any)
+				if post["title"] != "标题" {
+					t.Errorf("title = %v", post["title"])
+				}
+				paragraphs := post["content"].([]any)
+				if len(paragraphs) != 3 {
+					t.Fatalf("paragraphs = %v", paragraphs)
+				}
+				at := paragraphs[2].([]any)[0].(map[string]any)
+				if at["tag"] != "at" || at["user_id"] != "all" {
+					t.Errorf("at = %v", at)
+				}
+			},
+		},
+		{
+			name: "interactive card passthrough",
+			msg:  &testMessage{MsgType: MsgTypeInteractive, Content: "ignored", Extra: map[string]any{"card": map[string]any{"elements": []any{}}}},
+			check: func(t *testing.T, body map[string]any) {
+				card := body["card"].(map[string]any)
+				if _, ok := card["elements"]; !ok || len(card) != 1 {
+					t.Errorf("card = %v", card)
+				}
+			},
+		},
+		{
+			name: "interactive generated",
+			msg:  &testMessage{MsgType: MsgTypeInteractive, Title: "告警", Content: "**CPU**"},
+			check: func(t *testing.T, body map[string]any) {
+				card := body["card"].(map[string]any)
+				header := card["header"].(map[string]any)["title"].(map[string]any)
+				if header["content"] != "告警" {
+					t.Errorf("header = %v", header)
+				}
+				element := card["elements"].([]any)[0].(map[string]any)
+				if element["tag"] != "markdown" || element["content"] != "**CPU**" {
+					t.Errorf("element = %v", element)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t, func(w http.ResponseWriter, r capturedRequest) {
+				writeJSON(w, http.StatusOK, map[string]any{"code": 0})
+			})
+			sender := &FeishuSender{Webhook: server.URL}
+			if _, err := sender.Send(context.Background(), tt.msg); err != nil {
+				t.Fatalf("send failed: %v", err)
+			}
+			body := server.Requests()[0].JSON(t)
+			if _, ok := body["sign"]; ok {
+				t.Errorf("sign should be omitted without secret")
+			}
+			tt.check(t, body)
+		})
+	}
+}
+
+func TestFeishuSenderErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply map[string]any
+		want  string
+	}{
+		{"code", map[string]any{"code": 19021, "msg": "sign match fail or timestamp is not within one hour from current time"}, "sign match fail"},
+		{"legacy status code", map[string]any{"StatusCode": 9499, "StatusMessage": "Bad Request"}, "Bad Request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t, func(w http.ResponseWriter, r capturedRequest) {
+				writeJSON(w, http.StatusOK, tt.reply)
+			})
+			sender := &FeishuSender{Webhook: server.URL}
+			resp, err := sender.Send(context.Background(), &testMessage{Content: "hello"})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+			if resp == nil {
+				t.Errorf("response should be returned with the error")
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package senders
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// testMessage 测试用消息
+type testMessage struct {
+	Receiver   string
+	Signature  string
+	VendorCode string
+	MsgType    string
+	Title      string
+	Content    string
+	Variables  map[string]any
+	Extra      map[string]any
+}
+
+func (m *testMessage) GetReceiver() string          { return m.Receiver }
+func (m *testMessage) GetSignature() string         { return m.Signature }
+func (m *testMessage) GetVendorCode() string        { return m.VendorCode }
+func (m *testMessage) GetMsgType() string           { return m.MsgType }
+func (m *testMessage) GetTitle() string             { return m.Title }
+func (m *testMessage) GetContent() string           { return m.Content }
+func (m *testMessage) GetVariables() map[string]any { return m.Variables }
+func (m *testMessage) GetExtra() map[string]any {
+	if m.Extra == nil {
+		return map[string]any{}
+	}
+	return m.Extra
+}
+
+// capturedRequest 假服务端收到的请求
+type capturedRequest struct {
+	Method string
+	Path   string
+	Query  map[string][]string
+	Header http.Header
+	Body   []byte
+}
+
+// JSON 按 JSON 解析请求体
+func (c capturedRequest) JSON(t *testing.T) map[string]any {
+	t.Helper()
+	var body map[string]any
+	if err := json.Unmarshal(c.Body, &body); err != nil {
+		t.Fatalf("request body is not json: %v, body: %s", err, c.Body)
+	}
+	return body
+}
+
+// fakeServer 记录收到的请求并返回 reply 生成的响应
+type fakeServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []capturedRequest
+}
+
+func newFakeServer(t *testing.T, reply func(w http.ResponseWriter, r capturedRequest)) *fakeServer {
+	t.Helper()
+	f := &fakeServer{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req := capturedRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Header: r.Header.Clone(), Body: body}
+		f.mu.Lock()
+		f.requests = append(f.requests, req)
+		f.mu.Unlock()
+		reply(w, req)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// Requests 已收到的请求
+func (f *fakeServer) Requests() []capturedRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]capturedRequest(nil), f.requests...)
+}
+
+// writeJSON 以 JSON 写入响应
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -11,11 +11,18 @@ var (
 )
 
 func init() {
-	_ = Register("dingtalk", "钉钉机器人", &DingTalkSender{})
-	_ = Register("workwx", "企业微信机器人", &WorkWxSender{})
+	_ = Register("dingtalk", "钉钉机器人", func() ISender { return &DingTalkSender{} })
+	_ = Register("workwx", "企业微信机器人", func() ISender { return &WorkWxSender{} })
+	_ = Register("feishu", "飞书/Lark机器人", func() ISender { return &FeishuSender{} })
+	_ = Register("email", "SMTP邮件", func() ISender { return &EmailSender{} })
+	_ = Register("webhook", "通用Webhook", func() ISender { return &WebhookSender{} })
+	_ = Register("slack", "Slack", func() ISender { return &SlackSender{} })
+	_ = Register("telegram", "Telegram机器人", func() ISender { return &TelegramSender{} })
+	_ = Register("aliyun_sms", "阿里云短信", func() ISender { return &AliyunSmsSender{} })
+	_ = Register("tencent_sms", "腾讯云短信", func() ISender { return &TencentSmsSender{} })
 }
-func Register(name, label string, sender ISender) error {
-	return _senders.Register(name, label, sender)
+func Register(name, label string, factory Factory) error {
+	return _senders.Register(name, label, factory)
 }
 
 func Get(name string) (*SenderForm, bool) {
@@ -36,16 +43,16 @@ func NewSenders() *Senders {
 	return &Senders{senders: make(map[string]*SenderForm), order: make([]string, 0)}
 }
 
-func (r *Senders) Register(name, label string, sender ISender) error {
+func (r *Senders) Register(name, label string, factory Factory) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	if _, exists := r.senders[name]; exists {
 		return fmt.Errorf("sender %s already registered", name)
 	}
 	r.senders[name] = &SenderForm{
-		Name:   name,
-		Label:  label,
-		Sender: sender,
+		Name:  name,
+		Label: label,
+		New:   factory,
 	}
 	r.order = append(r.order, name)
 	return nil
@@ -67,12 +74,24 @@ func (r *Senders) List() []*SenderForm {
 	return list
 }
 
+// Factory 创建发送器实例，注册表只保存工厂，每次发送都使用独立的实例，避免并发发送时互相覆盖配置
+type Factory func() ISender
+
 type SenderForm struct {
-	Name   string
-	Label  string
-	Sender ISender
+	Name  string
+	Label string
+	New   Factory
+}
+
+// Sender 创建一个新的发送器实例并写入通道配置
+func (s *SenderForm) Sender(config map[string]any) (ISender, error) {
+	sender := s.New()
+	if err := sender.SetConfig(config); err != nil {
+		return nil, err
+	}
+	return sender, nil
 }
 
 func (s *SenderForm) FormFields() []htmlx.FormField {
-	return htmlx.ToFormFields(s.Sender)
+	return htmlx.ToFormFields(s.New())
 }
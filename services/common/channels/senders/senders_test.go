@@ -0,0 +1,81 @@
+package senders
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// TestSenderFormConcurrentConfigs 两个同类型、不同配置的通道并发发送时，每次发送都使用独立的实例，
+// 请求只会到达各自配置的地址；需配合 go test -race 运行以检测共享实例上的数据竞争
+func TestSenderFormConcurrentConfigs(t *testing.T) {
+	form, ok := Get("webhook")
+	if !ok {
+		t.Fatal("webhook sender is not registered")
+	}
+	newServer := func() *fakeServer {
+		return newFakeServer(t, func(w http.ResponseWriter, r capturedRequest) {
+			writeJSON(w, http.StatusOK, map[string]any{"code": 0})
+		})
+	}
+	servers := map[string]*fakeServer{"a": newServer(), "b": newServer()}
+	const sends = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*sends)
+	for i := 0; i < sends; i++ {
+		for name, server := range servers {
+			wg.Add(1)
+			go func(name string, server *fakeServer, i int) {
+				defer wg.Done()
+				sender, err := form.Sender(map[string]any{
+					"url":          server.URL + "/" + name,
+					"content_type": "application/json",
+					"body":         `{"channel":"` + name + `","content":"${content}"}`,
+				})
+				if err != nil {
+					errs <- err
+					return
+				}
+				if _, err := sender.Send(context.Background(), &testMessage{Receiver: "u1", Content: fmt.Sprintf("%s-%d", name, i)}); err != nil {
+					errs <- err
+				}
+			}(name, server, i)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("send failed: %v", err)
+	}
+	for name, server := range servers {
+		requests := server.Requests()
+		if len(requests) != sends {
+			t.Errorf("channel %s received %d requests, want %d", name, len(requests), sends)
+		}
+		for _, request := range requests {
+			body := request.JSON(t)
+			if request.Path != "/"+name || body["channel"] != name {
+				t.Errorf("channel %s received request for %v at %s", name, body["channel"], request.Path)
+			}
+		}
+	}
+}
+
+// TestSenderFormNewInstance 每次创建的发送器互不共享配置
+func TestSenderFormNewInstance(t *testing.T) {
+	for _, form := range List() {
+		first, err := form.Sender(map[string]any{})
+		if err != nil {
+			t.Fatalf("%s: %v", form.Name, err)
+		}
+		second, err := form.Sender(map[string]any{})
+		if err != nil {
+			t.Fatalf("%s: %v", form.Name, err)
+		}
+		if first == second {
+			t.Errorf("%s: Sender returned a shared instance", form.Name)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package senders
+
+import (
+	"chihqiang/msgbox-go/pkg/clientx"
+	"chihqiang/msgbox-go/pkg/htmlx"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Slack 发送模式
+const (
+	SlackModeWebhook = "webhook" // Incoming Webhook
+	SlackModeBot     = "bot"     // Bot Token 调用 chat.postMessage
+)
+
+var (
+	slackBoldPattern   = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	slackStrikePattern = regexp.MustCompile(`~~(.+?)~~`)
+	slackLinkPattern   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	slackHeadPattern   = regexp.MustCompile(`(?m)^#{1,6}\s+(.+)$`)
+)
+
+type SlackSender struct {
+	Mode       string `json:"mode" ui:"label=发送模式;type=text;placeholder=webhook 或 bot;default=webhook"`
+	WebhookURL string `json:"webhook_url" ui:"label=Webhook地址;type=text;placeholder=webhook 模式必填，如 https://hooks.slack.com/services/xxx"`
+	Token      string `json:"token" ui:"label=Bot Token;type=text;placeholder=bot 模式必填，如 xoxb-xxx"`
+	APIBase    string `json:"api_base" ui:"label=API地址;type=text;placeholder=请输入 Slack API 地址;default=https://slack.com/api"`
+}
+
+func (s *SlackSender) SetConfig(config map[string]any) error {
+	return htmlx.MapSet(s, config)
+}
+
+// mrkdwn 将模板中常见的 Markdown 语法转换为 Slack mrkdwn，并转义控制字符
+func (s *SlackSender) mrkdwn(content string) string {
+	text := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(content)
+	text = slackLinkPattern.ReplaceAllString(text, "<$2|$1>")
+	text = slackHeadPattern.ReplaceAllString(text, "*$1*")
+	text = slackBoldPattern.ReplaceAllString(text, "*$1*")
+	text = slackStrikePattern.ReplaceAllString(text, "~$1~")
+	return text
+}
+
+// blocks 读取 extra.blocks 中的 Block Kit 内容，支持数组或 JSON 字符串
+func (s *SlackSender) blocks(message IMessage) (any, error) {
+	blocks, ok := message.GetExtra()["blocks"]
+	if !ok || blocks == nil {
+		return nil, nil
+	}
+	if raw, ok := blocks.(string); ok {
+		var parsed []any
+		if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+			return nil, fmt.Errorf("invalid slack blocks: %w", err)
+		}
+		return parsed, nil
+	}
+	return blocks, nil
+}
+
+func (s *SlackSender) payload(message IMessage) (map[string]any, error) {
+	text := s.mrkdwn(message.GetContent())
+	if title := message.GetTitle(); title != "" {
+		text = fmt.Sprintf("*%s*\n%s", s.mrkdwn(title), text)
+	}
+	req := map[string]any{
+		"text":   text,
+		"mrkdwn": true,
+	}
+	blocks, err := s.blocks(message)
+	if err != nil {
+		return nil, err
+	}
+	if blocks != nil {
+		req["blocks"] = blocks
+	}
+	return req, nil
+}
+
+// sendWebhook Incoming Webhook 成功时返回纯文本 ok，失败时返回错误描述
+func (s *SlackSender) sendWebhook(ctx context.Context, req map[string]any) (resp map[string]any, err error) {
+	response, err := clientx.PostJSON(ctx, strings.TrimSpace(s.WebhookURL), req)
+	if err != nil {
+		return resp, err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return resp, err
+	}
+	resp = map[string]any{"body": string(body)}
+	if strings.TrimSpace(string(body)) != "ok" {
+		return resp, errors.New(string(body))
+	}
+	return resp, nil
+}
+
+// sendBot 调用 chat.postMessage，接收者为频道 ID 或用户 ID
+func (s *SlackSender) sendBot(ctx context.Context, channel string, req map[string]any) (resp map[string]any, err error) {
+	if channel == "" {
+		return resp, errors.New("slack channel is empty")
+	}
+	req["channel"] = channel
+	endpoint := strings.TrimRight(strings.TrimSpace(s.APIBase), "/") + "/chat.postMessage"
+	response, err := clientx.PostJSON(ctx, endpoint, req, clientx.WithHeaders(map[string]string{
+		"Authorization": "Bearer " + strings.TrimSpace(s.Token),
+	}))
+	if err != nil {
+		return resp, err
+	}
+	defer response.Body.Close()
+	if err = json.NewDecoder(response.Body).Decode(&resp); err != nil {
+		return resp, err
+	}
+	if ok, _ := resp["ok"].(bool); !ok {
+		msg, _ := resp["error"].(string)
+		return resp, errors.New(msg)
+	}
+	return resp, nil
+}
+
+func (s *SlackSender) Send(ctx context.Context, message IMessage) (resp map[string]any, err error) {
+	req, err := s.payload(message)
+	if err != nil {
+		return resp, err
+	}
+	if s.Mode == SlackModeBot {
+		return s.sendBot(ctx, message.GetReceiver(), req)
+	}
+	return s.sendWebhook(ctx, req)
+}
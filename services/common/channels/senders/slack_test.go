@@ -0,0 +1,128 @@
+package senders
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSlackSenderMrkdwn(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"**bold** and ~~gone~~", "*bold* and ~gone~"},
+		{"## Title", "*Title*"},
+		{"see [docs](https://example.com/a?b=1&c=2)", "see <https://example.com/a?b=1&amp;c=2|docs>"},
+		{"a < b & c > d", "a &lt; b &amp; c &gt; d"},
+	}
+	s := &SlackSender{}
+	for _, tt := range tests {
+		if got := s.mrkdwn(tt.in); got != tt.want {
+			t.Errorf("mrkdwn(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSlackSenderWebhook(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		status  int
+		wantErr string
+	}{
+		{"ok", "ok", http.StatusOK, ""},
+		{"ok with error body", "no_text", http.StatusOK, "no_text"},
+		{"invalid payload", "invalid_payload", http.StatusBadRequest, "invalid_payload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t, func(w http.ResponseWriter, r capturedRequest) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.reply))
+			})
+			sender := &SlackSender{}
+			if err := sender.SetConfig(map[string]any{"mode": SlackModeWebhook, "webhook_url": server.URL + "/services/T/B/X"}); err != nil {
+				t.Fatal(err)
+			}
+			resp, err := sender.Send(context.Background(), &testMessage{Title: "Alert", Content: "**cpu** high", Extra: map[string]any{
+				"blocks": `[{"type":"section","text":{"type":"mrkdwn","text":"hi"}}]`,
+			}})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("send failed: %v", err)
+			}
+			// 非 2xx 响应由 clientx 返回错误，错误中保留 Slack 的错误描述
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("err = %v, want %s", err, tt.wantErr)
+			}
+			if err == nil && resp["body"] != tt.reply {
+				t.Errorf("resp = %v", resp)
+			}
+			body := server.Requests()[0].JSON(t)
+			if body["text"] != "*Alert*\n*cpu* high" || body["mrkdwn"] != true {
+				t.Errorf("payload = %v", body)
+			}
+			if blocks, _ := body["blocks"].([]any); len(blocks) != 1 {
+				t.Errorf("blocks = %v", body["blocks"])
+			}
+		})
+	}
+}
+
+func TestSlackSenderBot(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   map[string]any
+		wantErr string
+	}{
+		{"ok", map[string]any{"ok": true, "channel": "C1", "ts": "1700000000.000100"}, ""},
+		{"channel not found", map[string]any{"ok": false, "error": "channel_not_found"}, "channel_not_found"},
+		{"invalid auth", map[string]any{"ok": false, "error": "invalid_auth"}, "invalid_auth"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// chat.postMessage 业务失败时仍返回 200，只能通过 ok 字段判断
+			server := newFakeServer(t, func(w http.ResponseWriter, r capturedRequest) {
+				writeJSON(w, http.StatusOK, tt.reply)
+			})
+			sender := &SlackSender{}
+			if err := sender.SetConfig(map[string]any{"mode": SlackModeBot, "token": " xoxb-1 ", "api_base": server.URL + "/api/"}); err != nil {
+				t.Fatal(err)
+			}
+			resp, err := sender.Send(context.Background(), &testMessage{Receiver: "C1", Content: "hello"})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("send failed: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("err = %v, want %s", err, tt.wantErr)
+			}
+			if resp["ok"] != tt.reply["ok"] {
+				t.Errorf("resp = %v", resp)
+			}
+			request := server.Requests()[0]
+			if request.Path != "/api/chat.postMessage" {
+				t.Errorf("path = %s", request.Path)
+			}
+			if got := request.Header.Get("Authorization"); got != "Bearer xoxb-1" {
+				t.Errorf("authorization = %q", got)
+			}
+			if body := request.JSON(t); body["channel"] != "C1" || body["text"] != "hello" {
+				t.Errorf("payload = %v", body)
+			}
+		})
+	}
+}
+
+func TestSlackSenderBotEmptyChannel(t *testing.T) {
+	sender := &SlackSender{Mode: SlackModeBot, APIBase: "http://127.0.0.1:0"}
+	if _, err := sender.Send(context.Background(), &testMessage{Content: "hello"}); err == nil {
+		t.Fatal("empty channel should be rejected")
+	}
+}
+
+func TestSlackSenderInvalidBlocks(t *testing.T) {
+	sender := &SlackSender{Mode: SlackModeWebhook, WebhookURL: "http://127.0.0.1:0"}
+	if _, err := sender.Send(context.Background(), &testMessage{Content: "hello", Extra: map[string]any{"blocks": "{"}}); err == nil {
+		t.Fatal("invalid blocks should be rejected")
+	}
+}
@@ -0,0 +1,294 @@
+package senders
+
+import (
+	"chihqiang/msgbox-go/pkg/clientx"
+	"chihqiang/msgbox-go/pkg/htmlx"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// TelegramMaxMessageLength 单条消息最大长度（按 UTF-16 计），超出后拆分为多条发送
+const TelegramMaxMessageLength = 4096
+
+// Telegram 解析模式
+const (
+	TelegramParseModeHTML       = "HTML"
+	TelegramParseModeMarkdown   = "Markdown"
+	TelegramParseModeMarkdownV2 = "MarkdownV2"
+)
+
+// telegramEntity 拆分位置仍未闭合的格式实体，open 为开始标记原文，close 为结束标记
+type telegramEntity struct {
+	open, close string
+}
+
+type TelegramSender struct {
+	Token                 string `json:"token" ui:"label=Bot Token;type=text;required;placeholder=请输入 BotFather 分配的 Token"`
+	APIBase               string `json:"api_base" ui:"label=API地址;type=text;placeholder=自建 Bot API 服务时填写;default=https://api.telegram.org"`
+	ParseMode             string `json:"parse_mode" ui:"label=解析模式;type=text;placeholder=MarkdownV2、HTML 或留空（纯文本）"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview" ui:"label=禁用链接预览;type=text;placeholder=true 或 false"`
+}
+
+func (t *TelegramSender) SetConfig(config map[string]any) error {
+	return htmlx.MapSet(t, config)
+}
+
+// split 按最大长度拆分消息，依次优先在格式实体之外的换行、空白处断开，不会断在 HTML 标签、
+// HTML 字符实体、转义序列与 Markdown 链接中间；无法在实体外断开时，在本段末尾闭合未结束的实体，
+// 并在下一段开头重新打开
+func (t *TelegramSender) split(content string) []string {
+	runes := []rune(content)
+	chunks := make([]string, 0, len(runes)/TelegramMaxMessageLength+1)
+	for {
+		limit := t.limit(runes)
+		if limit == len(runes) {
+			break
+		}
+		var (
+			chunk string
+			open  []telegramEntity
+			cut   int
+		)
+		// 为闭合标记预留长度，保证补齐后的原文也不超过最大长度
+		for reserve := 0; ; {
+			cuttable, depth, _ := t.scan(runes, limit-reserve)
+			cut = t.cut(runes, limit-reserve, cuttable, depth)
+			_, _, open = t.scan(runes, cut)
+			closing := ""
+			for i := len(open) - 1; i >= 0; i-- {
+				closing += open[i].close
+			}
+			chunk = string(runes[:cut]) + closing
+			// 闭合标记均为 ASCII，字节数即长度
+			if len(closing) <= reserve || reserve >= limit/2 {
+				break
+			}
+			reserve = len(closing)
+		}
+		reopen := ""
+		for _, entity := range open {
+			reopen += entity.open
+		}
+		if len([]rune(reopen)) >= cut {
+			// 开始标记过长时不再重新打开，保证每轮都有进展
+			reopen = ""
+		}
+		chunks = append(chunks, chunk)
+		runes = append([]rune(reopen), runes[cut:]...)
+	}
+	if len(runes) > 0 || len(chunks) == 0 {
+		chunks = append(chunks, string(runes))
+	}
+	return chunks
+}
+
+// limit 不超过最大长度的最长前缀的字符数，Telegram 按 UTF-16 计算长度
+func (t *TelegramSender) limit(runes []rune) int {
+	size := 0
+	for i, r := range runes {
+		if size += utf16.RuneLen(r); size > TelegramMaxMessageLength {
+			return i
+		}
+	}
+	return len(runes)
+}
+
+// cut 在 (limit/2, limit] 内选择断开位置，依次优先：实体外的换行、实体外的空白、换行、空白、任意可断开位置
+func (t *TelegramSender) cut(runes []rune, limit int, cuttable []bool, depth []int) int {
+	rules := []func(p int) bool{
+		func(p int) bool { return depth[p] == 0 && runes[p-1] == '\n' },
+		func(p int) bool { return depth[p] == 0 && unicode.IsSpace(runes[p-1]) },
+		func(p int) bool { return runes[p-1] == '\n' },
+		func(p int) bool { return unicode.IsSpace(runes[p-1]) },
+		func(p int) bool { return true },
+	}
+	for _, rule := range rules {
+		for p := limit; p > limit/2; p-- {
+			if cuttable[p] && rule(p) {
+				return p
+			}
+		}
+	}
+	return limit
+}
+
+// scan 扫描 runes[:stop] 的格式标记，cuttable[p] 表示能否在 runes[p] 之前断开，depth[p] 为该位置未闭合的实体数，
+// open 为 stop 处仍未闭合的实体；纯文本模式下任意位置均可断开
+func (t *TelegramSender) scan(runes []rune, stop int) (cuttable []bool, depth []int, open []telegramEntity) {
+	cuttable = make([]bool, stop+1)
+	depth = make([]int, stop+1)
+	for i := range cuttable {
+		cuttable[i] = true
+	}
+	// block 标记 (from, to] 内的位置不可断开
+	block := func(from, to int) {
+		for p := from + 1; p <= to && p <= stop; p++ {
+			cuttable[p] = false
+		}
+	}
+	toggle := func(marker string) {
+		if n := len(open); n > 0 && open[n-1].close == marker {
+			open = open[:n-1]
+			return
+		}
+		open = append(open, telegramEntity{open: marker, close: marker})
+	}
+	mode := strings.ToLower(strings.TrimSpace(t.ParseMode))
+	for i := 0; i < stop; i++ {
+		depth[i] = len(open)
+		next := func(n int) string {
+			return string(runes[i:min(i+n, len(runes))])
+		}
+		switch mode {
+		case strings.ToLower(TelegramParseModeHTML):
+			switch runes[i] {
+			case '<':
+				end := indexRune(runes, i, '>', len(runes))
+				if end < 0 {
+					continue
+				}
+				tag := string(runes[i : end+1])
+				fields := strings.FieldsFunc(strings.Trim(tag, "</>"), func(r rune) bool {
+					return unicode.IsSpace(r) || r == '/'
+				})
+				if len(fields) == 0 {
+					continue
+				}
+				name := strings.ToLower(fields[0])
+				block(i, end)
+				if strings.HasPrefix(tag, "</") {
+					for n := len(open) - 1; n >= 0; n-- {
+						if open[n].close == "</"+name+">" {
+							open = open[:n]
+							break
+						}
+					}
+				} else if !strings.HasSuffix(tag, "/>") {
+					open = append(open, telegramEntity{open: tag, close: "</" + name + ">"})
+				}
+				i = end
+			case '&':
+				if end := indexRune(runes, i, ';', i+10); end > 0 {
+					block(i, end)
+					i = end
+				}
+			}
+		case strings.ToLower(TelegramParseModeMarkdown), strings.ToLower(TelegramParseModeMarkdownV2):
+			code := len(open) > 0 && strings.HasPrefix(open[len(open)-1].close, "`")
+			switch {
+			case runes[i] == '\\':
+				block(i, i+1)
+				i++
+			case next(3) == "```":
+				if code {
+					open = open[:len(open)-1]
+					block(i, i+2)
+					i += 2
+					continue
+				}
+				// 代码块开头的语言行随开始标记一起重新打开
+				end := indexRune(runes, i, '\n', len(runes))
+				if end < 0 {
+					end = i + 2
+				}
+				block(i, end)
+				open = append(open, telegramEntity{open: string(runes[i : end+1]), close: "```"})
+				i = end
+			case runes[i] == '`':
+				toggle("`")
+			case code:
+			case runes[i] == '[':
+				// 链接整体不可断开
+				if mid := indexRune(runes, i, ']', len(runes)); mid > 0 && mid+1 < len(runes) && runes[mid+1] == '(' {
+					if end := indexRune(runes, mid, ')', len(runes)); end > 0 {
+						block(i, end)
+					}
+				}
+			case next(2) == "||" || next(2) == "__":
+				toggle(next(2))
+				block(i, i+1)
+				i++
+			case runes[i] == '*' || runes[i] == '_' || runes[i] == '~':
+				toggle(string(runes[i]))
+			}
+		}
+	}
+	depth[stop] = len(open)
+	return cuttable, depth, open
+}
+
+// indexRune 在 runes[from:to) 中查找 r，未找到时返回 -1
+func indexRune(runes []rune, from int, r rune, to int) int {
+	for i := from; i < min(to, len(runes)); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t *TelegramSender) sendMessage(ctx context.Context, chatID, text string) (resp map[string]any, err error) {
+	req := map[string]any{
+		"chat_id": chatID,
+		"text":    text,
+	}
+	if t.ParseMode != "" {
+		req["parse_mode"] = t.ParseMode
+	}
+	if t.DisableWebPagePreview {
+		req["disable_web_page_preview"] = true
+		req["link_preview_options"] = map[string]any{"is_disabled": true}
+	}
+	endpoint := fmt.Sprintf("%s/bot%s/sendMessage", strings.TrimRight(strings.TrimSpace(t.APIBase), "/"), strings.TrimSpace(t.Token))
+	response, err := clientx.PostJSON(ctx, endpoint, req)
+	if err != nil {
+		return resp, err
+	}
+	defer response.Body.Close()
+	if err = json.NewDecoder(response.Body).Decode(&resp); err != nil {
+		return resp, err
+	}
+	if ok, _ := resp["ok"].(bool); !ok {
+		msg, _ := resp["description"].(string)
+		return resp, errors.New(msg)
+	}
+	return resp, nil
+}
+
+// Send 接收者为 chat_id 或 @channel 名称，超长消息拆分发送后合并各次响应；
+// 任一分段失败即停止发送剩余分段并返回错误，已有分段送达时响应中标记 partial 与已送达的分段数
+func (t *TelegramSender) Send(ctx context.Context, message IMessage) (resp map[string]any, err error) {
+	chatID := strings.TrimSpace(message.GetReceiver())
+	if chatID == "" {
+		return resp, errors.New("telegram chat id is empty")
+	}
+	chunks := t.split(message.GetContent())
+	results := make([]any, 0, len(chunks))
+	messageIDs := make([]any, 0, len(chunks))
+	for i, chunk := range chunks {
+		result, err := t.sendMessage(ctx, chatID, chunk)
+		results = append(results, result)
+		if err != nil {
+			if i == 0 {
+				return map[string]any{"ok": false, "message_ids": messageIDs, "results": results}, err
+			}
+			return map[string]any{
+				"ok":          false,
+				"partial":     true,
+				"delivered":   i,
+				"total":       len(chunks),
+				"message_ids": messageIDs,
+				"results":     results,
+			}, fmt.Errorf("telegram chunk %d of %d failed, %d delivered: %w", i+1, len(chunks), i, err)
+		}
+		if msg, ok := result["result"].(map[string]any); ok {
+			messageIDs = append(messageIDs, msg["message_id"])
+		}
+	}
+	return map[string]any{"ok": true, "message_ids": messageIDs, "results": results}, nil
+}
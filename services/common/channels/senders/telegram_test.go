@@ -0,0 +1,193 @@
+package senders
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// utf16Len Telegram 计算消息长度的方式
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+func TestTelegramSenderSplit(t *testing.T) {
+	line := strings.Repeat("a", 99) + "\n"
+	tests := []struct {
+		name      string
+		parseMode string
+		content   string
+		check     func(t *testing.T, chunks []string)
+	}{
+		{
+			name:    "short",
+			content: "hello",
+			check: func(t *testing.T, chunks []string) {
+				if len(chunks) != 1 || chunks[0] != "hello" {
+					t.Errorf("chunks = %q", chunks)
+				}
+			},
+		},
+		{
+			name:    "line boundary",
+			content: strings.Repeat(line, 50),
+			check: func(t *testing.T, chunks []string) {
+				for _, chunk := range chunks[:len(chunks)-1] {
+					if !strings.HasSuffix(chunk, "\n") {
+						t.Errorf("chunk does not end at a line: %q", chunk[len(chunk)-10:])
+					}
+				}
+			},
+		},
+		{
+			name:    "word boundary",
+			content: strings.Repeat("word ", 1000),
+			check: func(t *testing.T, chunks []string) {
+				if !strings.HasSuffix(chunks[0], " ") {
+					t.Errorf("chunk does not end at a space: %q", chunks[0][len(chunks[0])-10:])
+				}
+			},
+		},
+		{
+			name:    "utf16 length",
+			content: strings.Repeat("😀", 3000),
+			check: func(t *testing.T, chunks []string) {
+				if len(chunks) != 2 {
+					t.Errorf("chunks = %d, want 2", len(chunks))
+				}
+			},
+		},
+		{
+			name:      "html keeps entities whole",
+			parseMode: TelegramParseModeHTML,
+			content:   strings.Repeat(`<a href="https://example.com/?a=1&amp;b=2">link</a> &amp; `, 200),
+			check: func(t *testing.T, chunks []string) {
+				for _, chunk := range chunks {
+					if strings.Count(chunk, "<a ") != strings.Count(chunk, "</a>") {
+						t.Errorf("unbalanced tags in chunk ending %q", chunk[len(chunk)-20:])
+					}
+					if i := strings.LastIndex(chunk, "&"); i >= 0 && !strings.Contains(chunk[i:], ";") {
+						t.Errorf("html entity cut in chunk ending %q", chunk[len(chunk)-20:])
+					}
+				}
+			},
+		},
+		{
+			name:      "html reopens long tag",
+			parseMode: TelegramParseModeHTML,
+			content:   "<b><i>" + strings.Repeat("x\n", 3000) + "</i></b>",
+			check: func(t *testing.T, chunks []string) {
+				if len(chunks) != 2 {
+					t.Fatalf("chunks = %d, want 2", len(chunks))
+				}
+				if !strings.HasSuffix(chunks[0], "x\n</i></b>") || !strings.HasPrefix(chunks[1], "<b><i>x\n") {
+					t.Errorf("tags not closed and reopened: %q ... %q", chunks[0][len(chunks[0])-12:], chunks[1][:12])
+				}
+			},
+		},
+		{
+			name:      "markdown prefers boundary outside entities",
+			parseMode: TelegramParseModeMarkdownV2,
+			content:   strings.Repeat("a", 3000) + "\n*" + strings.Repeat("b", 1500) + "*",
+			check: func(t *testing.T, chunks []string) {
+				if len(chunks) != 2 || !strings.HasPrefix(chunks[1], "*b") {
+					t.Errorf("chunks = %d, second starts %q", len(chunks), chunks[len(chunks)-1][:2])
+				}
+			},
+		},
+		{
+			name:      "markdown reopens code block",
+			parseMode: TelegramParseModeMarkdownV2,
+			content:   "```go\n" + strings.Repeat("fmt.Println(1)\n", 400) + "```",
+			check: func(t *testing.T, chunks []string) {
+				if len(chunks) != 2 {
+					t.Fatalf("chunks = %d, want 2", len(chunks))
+				}
+				if !strings.HasSuffix(chunks[0], "\n```") || !strings.HasPrefix(chunks[1], "```go\n") {
+					t.Errorf("code block not closed and reopened")
+				}
+			},
+		},
+		{
+			name:      "markdown keeps escapes and links whole",
+			parseMode: TelegramParseModeMarkdownV2,
+			content:   strings.Repeat(`\. [site](https://example.com/path) `, 200),
+			check: func(t *testing.T, chunks []string) {
+				for _, chunk := range chunks {
+					if strings.HasSuffix(chunk, `\`) || strings.Count(chunk, "[") != strings.Count(chunk, ")") {
+						t.Errorf("escape or link cut in chunk ending %q", chunk[len(chunk)-20:])
+					}
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &TelegramSender{ParseMode: tt.parseMode}
+			chunks := sender.split(tt.content)
+			for _, chunk := range chunks {
+				if utf16Len(chunk) > TelegramMaxMessageLength {
+					t.Fatalf("chunk length %d exceeds %d", utf16Len(chunk), TelegramMaxMessageLength)
+				}
+			}
+			if tt.parseMode == "" && strings.Join(chunks, "") != tt.content {
+				t.Fatalf("plain chunks do not rebuild the content")
+			}
+			tt.check(t, chunks)
+		})
+	}
+}
+
+func TestTelegramSenderSend(t *testing.T) {
+	tests := []struct {
+		name        string
+		failAt      int // 第几次请求失败，0 表示不失败
+		wantErr     bool
+		wantPartial bool
+	}{
+		{"all delivered", 0, false, false},
+		{"first chunk fails", 1, true, false},
+		{"partial delivery", 2, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := newFakeServer(t, func(w http.ResponseWriter, r capturedRequest) {
+				calls++
+				if calls == tt.failAt {
+					writeJSON(w, http.StatusOK, map[string]any{"ok": false, "error_code": 429, "description": "Too Many Requests"})
+					return
+				}
+				writeJSON(w, http.StatusOK, map[string]any{"ok": true, "result": map[string]any{"message_id": calls}})
+			})
+			sender := &TelegramSender{}
+			if err := sender.SetConfig(map[string]any{"token": "123:abc", "api_base": server.URL, "parse_mode": TelegramParseModeHTML}); err != nil {
+				t.Fatal(err)
+			}
+			resp, err := sender.Send(context.Background(), &testMessage{Receiver: "@news", Content: strings.Repeat("line\n", 2000)})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if partial, _ := resp["partial"].(bool); partial != tt.wantPartial {
+				t.Errorf("partial = %v, want %v, resp: %v", resp["partial"], tt.wantPartial, resp)
+			}
+			// 错误需指明失败的分段
+			if tt.wantPartial && (!strings.Contains(err.Error(), "chunk 2 of") || !strings.Contains(err.Error(), "Too Many Requests")) {
+				t.Errorf("err = %v", err)
+			}
+			requests := server.Requests()
+			if requests[0].Path != "/bot123:abc/sendMessage" {
+				t.Errorf("path = %s", requests[0].Path)
+			}
+			if body := requests[0].JSON(t); body["chat_id"] != "@news" || body["parse_mode"] != TelegramParseModeHTML {
+				t.Errorf("payload = %v", body)
+			}
+			// 失败后不再发送剩余分段
+			if tt.failAt > 0 && len(requests) != tt.failAt {
+				t.Errorf("requests = %d, want %d", len(requests), tt.failAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package senders
+
+import (
+	"chihqiang/msgbox-go/pkg/clientx"
+	"chihqiang/msgbox-go/pkg/htmlx"
+	"chihqiang/msgbox-go/pkg/timex"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	tencentSmsService     = "sms"
+	tencentSmsAction      = "SendSms"
+	tencentSmsVersion     = "2021-01-11"
+	tencentSmsContentType = "application/json; charset=utf-8"
+	tencentSmsAlgorithm   = "TC3-HMAC-SHA256"
+)
+
+// TencentSmsStatus SendStatusSet 中单个手机号的发送结果
+type TencentSmsStatus struct {
+	SerialNo       string `json:"SerialNo"`
+	PhoneNumber    string `json:"PhoneNumber"`
+	Fee            int    `json:"Fee"`
+	SessionContext string `json:"SessionContext"`
+	Code           string `json:"Code"`
+	Message        string `json:"Message"`
+	IsoCode        string `json:"IsoCode"`
+}
+
+type tencentSmsResponse struct {
+	Response struct {
+		SendStatusSet []*TencentSmsStatus `json:"SendStatusSet"`
+		Error         *struct {
+			Code    string `json:"Code"`
+			Message string `json:"Message"`
+		} `json:"Error"`
+		RequestId string `json:"RequestId"`
+	} `json:"Response"`
+}
+
+type TencentSmsSender struct {
+	SecretID    string `json:"secret_id" ui:"label=SecretId;type=text;required;placeholder=请输入 SecretId"`
+	SecretKey   string `json:"secret_key" ui:"label=SecretKey;type=text;required;placeholder=请输入 SecretKey"`
+	SmsSdkAppID string `json:"sms_sdk_app_id" ui:"label=SmsSdkAppId;type=text;required;placeholder=请输入短信应用 SdkAppId"`
+	Region      string `json:"region" ui:"label=地域;type=text;placeholder=请输入地域;default=ap-guangzhou"`
+	Endpoint    string `json:"endpoint" ui:"label=接口地址;type=text;placeholder=请输入接口地址;default=https://sms.tencentcloudapi.com"`
+}
+
+func (t *TencentSmsSender) SetConfig(config map[string]any) error {
+	return htmlx.MapSet(t, config)
+}
+
+func (t *TencentSmsSender) hmacSHA256(key []byte, msg string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msg))
+	return mac.Sum(nil)
+}
+
+func (t *TencentSmsSender) sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// authorization 按 TC3-HMAC-SHA256 签名方法 v3 生成 Authorization 请求头
+func (t *TencentSmsSender) authorization(host string, timestamp int64, payload string) string {
+	date := time.Unix(timestamp, 0).UTC().Format("2006-01-02")
+	canonicalRequest := strings.Join([]string{
+		"POST",
+		"/",
+		"",
+		fmt.Sprintf("content-type:%s\nhost:%s\n", tencentSmsContentType, host),
+		"content-type;host",
+		t.sha256Hex(payload),
+	}, "\n")
+	credentialScope := fmt.Sprintf("%s/%s/tc3_request", date, tencentSmsService)
+	stringToSign := strings.Join([]string{
+		tencentSmsAlgorithm,
+		strconv.FormatInt(timestamp, 10),
+		credentialScope,
+		t.sha256Hex(canonicalRequest),
+	}, "\n")
+	secretDate := t.hmacSHA256([]byte("TC3"+t.SecretKey), date)
+	secretService := t.hmacSHA256(secretDate, tencentSmsService)
+	secretSigning := t.hmacSHA256(secretService, "tc3_request")
+	signature := hex.EncodeToString(t.hmacSHA256(secretSigning, stringToSign))
+	return fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=content-type;host, Signature=%s",
+		tencentSmsAlgorithm, t.SecretID, credentialScope, signature)
+}
+
+// paramOrder 模板参数顺序：优先使用 extra.param_order 声明的顺序，否则按变量名（数字优先）排序
+func (t *TencentSmsSender) paramOrder(message IMessage, variables map[string]any) []string {
+	switch order := message.GetExtra()["param_order"].(type) {
+	case []any:
+		keys := make([]string, 0, len(order))
+		for _, key := range order {
+			keys = append(keys, fmt.Sprint(key))
+		}
+		return keys
+	case string:
+		if order != "" {
+			return strings.Split(order, ",")
+		}
+	}
+	keys := make([]string, 0, len(variables))
+	for key := range variables {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, errA := strconv.Atoi(keys[i])
+		b, errB := strconv.Atoi(keys[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+func (t *TencentSmsSender) templateParamSet(message IMessage) []string {
+	variables := message.GetVariables()
+	params := make([]string, 0, len(variables))
+	for _, key := range t.paramOrder(message, variables) {
+		params = append(params, paramString(variables[strings.TrimSpace(key)]))
+	}
+	return params
+}
+
+// phones 拆分接收者，支持逗号分隔的多个手机号
+func (t *TencentSmsSender) phones(receiver string) []string {
+	phones := make([]string, 0)
+	for _, phone := range strings.Split(receiver, ",") {
+		if phone = strings.TrimSpace(phone); phone != "" {
+			phones = append(phones, phone)
+		}
+	}
+	return phones
+}
+
+// statuses 将 SendStatusSet 按手机号归类，手机号统一去掉 +86 前缀
+func (t *TencentSmsSender) statuses(statusSet []*TencentSmsStatus) map[string]*TencentSmsStatus {
+	statuses := make(map[string]*TencentSmsStatus, len(statusSet))
+	for _, status := range statusSet {
+		statuses[strings.TrimPrefix(status.PhoneNumber, "+86")] = status
+	}
+	return statuses
+}
+
+func (t *TencentSmsSender) Send(ctx context.Context, message IMessage) (resp map[string]any, err error) {
+	phones := t.phones(message.GetReceiver())
+	if len(phones) == 0 {
+		return resp, errors.New("tencent sms phone number is empty")
+	}
+	endpoint := strings.TrimRight(strings.TrimSpace(t.Endpoint), "/")
+	uri, err := url.Parse(endpoint)
+	if err != nil {
+		return resp, err
+	}
+	payload, err := json.Marshal(map[string]any{
+		"PhoneNumberSet":   phones,
+		"SmsSdkAppId":      t.SmsSdkAppID,
+		"SignName":         message.GetSignature(),
+		"TemplateId":       message.GetVendorCode(),
+		"TemplateParamSet": t.templateParamSet(message),
+	})
+	if err != nil {
+		return resp, err
+	}
+	timestamp := time.Now().Unix()
+	response, err := clientx.Post(ctx, endpoint+"/", payload, clientx.WithHeaders(map[string]string{
+		"Authorization":  t.authorization(uri.Host, timestamp, string(payload)),
+		"Content-Type":   tencentSmsContentType,
+		"X-TC-Action":    tencentSmsAction,
+		"X-TC-Timestamp": strconv.FormatInt(timestamp, 10),
+		"X-TC-Version":   tencentSmsVersion,
+		"X-TC-Region":    t.Region,
+	}))
+	if err != nil {
+		return resp, err
+	}
+	defer response.Body.Close()
+	var result tencentSmsResponse
+	if err = json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return resp, err
+	}
+	b, _ := json.Marshal(result)
+	_ = json.Unmarshal(b, &resp)
+	if result.Response.Error != nil {
+		return resp, fmt.Errorf("%s: %s", result.Response.Error.Code, result.Response.Error.Message)
+	}
+	statuses := t.statuses(result.Response.SendStatusSet)
+	failed := make([]string, 0)
+	serialNos := make(map[string]any, len(phones))
+	for _, phone := range phones {
+		status, ok := statuses[strings.TrimPrefix(phone, "+86")]
+		if !ok {
+			failed = append(failed, fmt.Sprintf("%s: no send status", phone))
+			continue
+		}
+		serialNos[phone] = status.SerialNo
+		if status.Code != "Ok" {
+			failed = append(failed, fmt.Sprintf("%s: %s %s", phone, status.Code, status.Message))
+		}
+	}
+	if len(phones) == 1 {
+		resp["serial_no"] = serialNos[phones[0]]
+	} else {
+		resp["serial_no"] = serialNos
+	}
+	if len(failed) > 0 {
+		return resp, errors.New(strings.Join(failed, "; "))
+	}
+	return resp, nil
+}
+
+// tencentSmsReport 短信下发状态回调
+type tencentSmsReport struct {
+	UserReceiveTime string `json:"user_receive_time"`
+	NationCode      string `json:"nationcode"`
+	Mobile          string `json:"mobile"`
+	ReportStatus    string `json:"report_status"`
+	ErrMsg          string `json:"errmsg"`
+	Description     string `json:"description"`
+	Sid             string `json:"sid"`
+}
+
+// MessageID 回执通过 SerialNo（回调中的 sid）匹配发送记录，单个手机号发送时有效
+func (t *TencentSmsSender) MessageID(response map[string]any) string {
+	serialNo, _ := response["serial_no"].(string)
+	return serialNo
+}
+
+// ParseReceipts 解析下发状态回调，请求体为 JSON 数组
+func (t *TencentSmsSender) ParseReceipts(r *http.Request) ([]Receipt, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	var reports []tencentSmsReport
+	if err := json.Unmarshal(body, &reports); err != nil {
+		return nil, fmt.Errorf("invalid tencent sms report: %w", err)
+	}
+	var raws []map[string]any
+	_ = json.Unmarshal(body, &raws)
+	receipts := make([]Receipt, 0, len(reports))
+	for i, report := range reports {
+		receipt := Receipt{
+			MessageID: report.Sid,
+			Receiver:  report.Mobile,
+			Delivered: report.ReportStatus == "SUCCESS",
+			Time:      time.Now(),
+		}
+		if !receipt.Delivered {
+			receipt.Error = fmt.Sprintf("%s: %s", report.ErrMsg, report.Description)
+		}
+		if at, err := timex.ParseDateTime(report.UserReceiveTime); err == nil {
+			receipt.Time = at
+		}
+		if i < len(raws) {
+			receipt.Raw = raws[i]
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, nil
+}
+
+// ReceiptAck 应答 result=0 表示接收成功
+func (t *TencentSmsSender) ReceiptAck(err error) any {
+	if err != nil {
+		return map[string]any{"result": 1, "errmsg": err.Error()}
+	}
+	return map[string]any{"result": 0, "errmsg": "OK"}
+}
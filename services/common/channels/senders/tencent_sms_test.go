@@ -0,0 +1,199 @@
+package senders
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// verifyTC3Signature 按腾讯云签名方法 v3 的文档步骤独立计算 Authorization 并与请求比较
+func verifyTC3Signature(t *testing.T, r capturedRequest, host, secretID, secretKey string) {
+	t.Helper()
+	sha := func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+	mac := func(key []byte, msg string) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(msg))
+		return h.Sum(nil)
+	}
+	timestamp, err := strconv.ParseInt(r.Header.Get("X-TC-Timestamp"), 10, 64)
+	if err != nil {
+		t.Fatalf("X-TC-Timestamp = %q", r.Header.Get("X-TC-Timestamp"))
+	}
+	date := time.Unix(timestamp, 0).UTC().Format("2006-01-02")
+	canonicalRequest := "POST\n/\n\ncontent-type:" + r.Header.Get("Content-Type") + "\nhost:" + host + "\n\ncontent-type;host\n" + sha(string(r.Body))
+	scope := date + "/sms/tc3_request"
+	stringToSign := "TC3-HMAC-SHA256\n" + strconv.FormatInt(timestamp, 10) + "\n" + scope + "\n" + sha(canonicalRequest)
+	key := mac(mac(mac([]byte("TC3"+secretKey), date), "sms"), "tc3_request")
+	want := fmt.Sprintf("TC3-HMAC-SHA256 Credential=%s/%s, SignedHeaders=content-type;host, Signature=%s", secretID, scope, hex.EncodeToString(mac(key, stringToSign)))
+	if got := r.Header.Get("Authorization"); got != want {
+		t.Errorf("authorization = %s\nwant %s", got, want)
+	}
+}
+
+func newTencentSmsSender(t *testing.T, server *fakeServer) *TencentSmsSender {
+	t.Helper()
+	sender := &TencentSmsSender{}
+	err := sender.SetConfig(map[string]any{
+		"secret_id":      "AKIDtest",
+		"secret_key":     "secret",
+		"sms_sdk_app_id": "1400000000",
+		"region":         "ap-guangzhou",
+		"endpoint":       server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sender
+}
+
+func TestTencentSmsSenderSend(t *testing.T) {
+	server := newFakeServer(t, func(w http.ResponseWriter, r capturedRequest) {
+		writeJSON(w, http.StatusOK, map[string]any{"Response": map[string]any{
+			"SendStatusSet": []any{map[string]any{"SerialNo": "sn-1", "PhoneNumber": "+8613800000000", "Code": "Ok", "Message": "send success"}},
+			"RequestId":     "r1",
+		}})
+	})
+	sender := newTencentSmsSender(t, server)
+	resp, err := sender.Send(context.Background(), &testMessage{
+		Receiver:   "13800000000",
+		Signature:  "腾讯云",
+		VendorCode: "449739",
+		Variables:  map[string]any{"2": "5", "1": "1234", "10": "x"},
+	})
+	if err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	if sender.MessageID(resp) != "sn-1" {
+		t.Errorf("message id = %q", sender.MessageID(resp))
+	}
+	request := server.Requests()[0]
+	serverURL, _ := url.Parse(server.URL)
+	verifyTC3Signature(t, request, serverURL.Host, "AKIDtest", "secret")
+	for key, want := range map[string]string{"X-TC-Action": "SendSms", "X-TC-Version": "2021-01-11", "X-TC-Region": "ap-guangzhou"} {
+		if got := request.Header.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	body := request.JSON(t)
+	if body["SmsSdkAppId"] != "1400000000" || body["SignName"] != "腾讯云" || body["TemplateId"] != "449739" {
+		t.Errorf("payload = %v", body)
+	}
+	// 数字变量名按数值排序
+	if params := fmt.Sprint(body["TemplateParamSet"]); params != "[1234 5 x]" {
+		t.Errorf("TemplateParamSet = %s", params)
+	}
+}
+
+// 请求变量经 JSON 解码后数字为 float64，7 位验证码不能格式化为科学计数法
+func TestTencentSmsSenderNumberParam(t *testing.T) {
+	server := newFakeServer(t, func(w http.ResponseWriter, r capturedRequest) {
+		writeJSON(w, http.StatusOK, map[string]any{"Response": map[string]any{
+			"SendStatusSet": []any{map[string]any{"SerialNo": "sn-1", "PhoneNumber": "+8613800000000", "Code": "Ok"}},
+			"RequestId":     "r1",
+		}})
+	})
+	var variables map[string]any
+	if err := json.Unmarshal([]byte(`{"1":1234567,"2":5}`), &variables); err != nil {
+		t.Fatal(err)
+	}
+	sender := newTencentSmsSender(t, server)
+	if _, err := sender.Send(context.Background(), &testMessage{Receiver: "13800000000", VendorCode: "449739", Variables: variables}); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	body := server.Requests()[0].JSON(t)
+	if params := fmt.Sprint(body["TemplateParamSet"]); params != "[1234567 5]" {
+		t.Errorf("TemplateParamSet = %s", params)
+	}
+}
+
+func TestTencentSmsSenderStatusSet(t *testing.T) {
+	tests := []struct {
+		name       string
+		receiver   string
+		reply      map[string]any
+		wantErr    []string
+		wantSerial any
+	}{
+		{
+			name:     "request error",
+			receiver: "13800000000",
+			reply:    map[string]any{"Error": map[string]any{"Code": "AuthFailure.SignatureFailure", "Message": "signature mismatch"}, "RequestId": "r1"},
+			wantErr:  []string{"AuthFailure.SignatureFailure: signature mismatch"},
+		},
+		{
+			name:     "one number fails",
+			receiver: "13800000000,+8613800000001",
+			reply: map[string]any{"SendStatusSet": []any{
+				map[string]any{"SerialNo": "sn-1", "PhoneNumber": "+8613800000000", "Code": "Ok"},
+				map[string]any{"SerialNo": "", "PhoneNumber": "+8613800000001", "Code": "LimitExceeded.PhoneNumberDailyLimit", "Message": "daily limit"},
+			}},
+			wantErr:    []string{"+8613800000001: LimitExceeded.PhoneNumberDailyLimit daily limit"},
+			wantSerial: map[string]any{"13800000000": "sn-1", "+8613800000001": ""},
+		},
+		{
+			name:     "missing status",
+			receiver: "13800000000, 13800000002",
+			reply: map[string]any{"SendStatusSet": []any{
+				map[string]any{"SerialNo": "sn-1", "PhoneNumber": "+8613800000000", "Code": "Ok"},
+			}},
+			wantErr:    []string{"13800000002: no send status"},
+			wantSerial: map[string]any{"13800000000": "sn-1"},
+		},
+		{
+			name:     "all numbers succeed",
+			receiver: "13800000000,13800000001",
+			reply: map[string]any{"SendStatusSet": []any{
+				map[string]any{"SerialNo": "sn-1", "PhoneNumber": "+8613800000000", "Code": "Ok"},
+				map[string]any{"SerialNo": "sn-2", "PhoneNumber": "+8613800000001", "Code": "Ok"},
+			}},
+			wantSerial: map[string]any{"13800000000": "sn-1", "13800000001": "sn-2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t, func(w http.ResponseWriter, r capturedRequest) {
+				writeJSON(w, http.StatusOK, map[string]any{"Response": tt.reply})
+			})
+			resp, err := newTencentSmsSender(t, server).Send(context.Background(), &testMessage{Receiver: tt.receiver, VendorCode: "449739"})
+			if len(tt.wantErr) == 0 && err != nil {
+				t.Fatalf("send failed: %v", err)
+			}
+			for _, want := range tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("err = %v, want %s", err, want)
+				}
+			}
+			if tt.wantSerial != nil && fmt.Sprint(resp["serial_no"]) != fmt.Sprint(tt.wantSerial) {
+				t.Errorf("serial_no = %v, want %v", resp["serial_no"], tt.wantSerial)
+			}
+		})
+	}
+}
+
+func TestTencentSmsSenderParseReceipts(t *testing.T) {
+	body := `[{"user_receive_time":"2024-01-02 03:04:05","nationcode":"86","mobile":"13800000000","report_status":"SUCCESS","errmsg":"DELIVRD","sid":"sn-1"},` +
+		`{"nationcode":"86","mobile":"13800000001","report_status":"FAIL","errmsg":"MK:0001","description":"用户关机","sid":"sn-2"}]`
+	request, _ := http.NewRequest(http.MethodPost, "/receipt/tencent_sms", strings.NewReader(body))
+	receipts, err := (&TencentSmsSender{}).ParseReceipts(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receipts) != 2 || !receipts[0].Delivered || receipts[0].MessageID != "sn-1" || receipts[0].Time.Year() != 2024 {
+		t.Fatalf("receipts = %+v", receipts)
+	}
+	if receipts[1].Delivered || receipts[1].Error != "MK:0001: 用户关机" {
+		t.Errorf("receipt 1 = %+v", receipts[1])
+	}
+}
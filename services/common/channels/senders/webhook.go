@@ -0,0 +1,249 @@
+package senders
+
+import (
+	"bytes"
+	"chihqiang/msgbox-go/pkg/clientx"
+	"chihqiang/msgbox-go/pkg/htmlx"
+	"chihqiang/msgbox-go/pkg/templatex"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// webhookBuiltinVariables 请求体模板的内置变量，发送时由消息填充
+var webhookBuiltinVariables = []string{"receiver", "content", "title"}
+
+const (
+	// WebhookTimestampHeader 签名时间戳请求头，签名内容为 timestamp + "." + body
+	WebhookTimestampHeader = "X-Msgbox-Timestamp"
+	// webhookMaxBodySize 读取响应体的最大字节数
+	webhookMaxBodySize = 1 << 20
+)
+
+type WebhookSender struct {
+	Method          string `json:"method" ui:"label=请求方法;type=text;placeholder=GET、POST、PUT 等;default=POST"`
+	URL             string `json:"url" ui:"label=请求地址;type=text;required;placeholder=请输入接口请求地址"`
+	Headers         string `json:"headers" ui:"label=请求头;type=text;placeholder=JSON 对象，如 {\"Authorization\":\"Bearer xxx\"}（可选）"`
+	ContentType     string `json:"content_type" ui:"label=Content-Type;type=text;placeholder=请输入请求体类型;default=application/json"`
+	Body            string `json:"body" ui:"label=请求体模板;type=text;placeholder=支持 ${receiver}、${content}、${title} 及模板变量;default={\"receiver\":\"${receiver}\",\"title\":\"${title}\",\"content\":\"${content}\"}"`
+	Secret          string `json:"secret" ui:"label=签名Secret;type=text;placeholder=配置后使用 HmacSHA256 签名请求体（可选）"`
+	SignatureHeader string `json:"signature_header" ui:"label=签名请求头;type=text;placeholder=签名所在的请求头;default=X-Msgbox-Signature"`
+	SuccessStatus   string `json:"success_status" ui:"label=成功状态码;type=text;placeholder=状态码或范围，多个用逗号分隔，如 200-299,302;default=200-299"`
+	SuccessPath     string `json:"success_path" ui:"label=成功判定字段;type=text;placeholder=响应 JSON 路径，如 data.code（可选）"`
+	SuccessValue    string `json:"success_value" ui:"label=成功判定值;type=text;placeholder=成功判定字段的期望值，如 0（可选）"`
+}
+
+func (w *WebhookSender) SetConfig(config map[string]any) error {
+	return htmlx.MapSet(w, config)
+}
+
+// headers 解析请求头配置，支持 JSON 对象或按行书写的 "Key: Value"
+func (w *WebhookSender) headers() (map[string]string, error) {
+	headers := make(map[string]string)
+	raw := strings.TrimSpace(w.Headers)
+	if raw == "" {
+		return headers, nil
+	}
+	if strings.HasPrefix(raw, "{") {
+		if err := json.Unmarshal([]byte(raw), &headers); err != nil {
+			return nil, fmt.Errorf("invalid webhook headers: %w", err)
+		}
+		return headers, nil
+	}
+	for _, line := range strings.Split(raw, "\n") {
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return headers, nil
+}
+
+// escape 根据请求体类型返回输出转义函数，避免内容破坏 JSON/表单结构，其他类型不转义
+func (w *WebhookSender) escape() func(string) string {
+	contentType := strings.ToLower(w.ContentType)
+	switch {
+	case strings.Contains(contentType, "json"):
+		return func(value string) string {
+			b, _ := json.Marshal(value)
+			return string(b[1 : len(b)-1])
+		}
+	case strings.Contains(contentType, "x-www-form-urlencoded"):
+		return url.QueryEscape
+	default:
+		return nil
+	}
+}
+
+// body 渲染请求体模板，内置变量 receiver、content、title 优先于模板变量
+// 变量以原始值参与渲染，列表、对象、循环与过滤器均可使用，输出时按请求体类型转义一次；
+// 模板语法同消息模板，引用缺失的变量时返回错误
+func (w *WebhookSender) body(message IMessage) (string, error) {
+	variables := make(map[string]any)
+	for key, value := range message.GetVariables() {
+		variables[key] = value
+	}
+	variables["receiver"] = message.GetReceiver()
+	variables["content"] = message.GetContent()
+	variables["title"] = message.GetTitle()
+	body, err := templatex.RenderEscape(w.Body, variables, w.escape())
+	if err != nil {
+		return "", fmt.Errorf("render webhook body failed: %w", err)
+	}
+	return body, nil
+}
+
+// Variables 请求体模板引用的模板变量，receiver、content、title 为内置变量不包括在内
+func (w *WebhookSender) Variables() ([]templatex.Variable, error) {
+	t, err := templatex.Parse(w.Body)
+	if err != nil {
+		return nil, fmt.Errorf("parse webhook body failed: %w", err)
+	}
+	variables := make([]templatex.Variable, 0)
+	for _, v := range t.Variables() {
+		if !slices.Contains(webhookBuiltinVariables, v.Name) {
+			variables = append(variables, v)
+		}
+	}
+	return variables, nil
+}
+
+func (w *WebhookSender) sign(timestamp int64, body string) string {
+	mac := hmac.New(sha256.New, []byte(w.Secret))
+	mac.Write([]byte(fmt.Sprintf("%d.%s", timestamp, body)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// statusOK 判断状态码是否命中成功规则，规则形如 "200-299,302"
+func (w *WebhookSender) statusOK(code int) bool {
+	rule := strings.TrimSpace(w.SuccessStatus)
+	if rule == "" {
+		rule = "200-299"
+	}
+	for _, part := range strings.Split(rule, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		low, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			continue
+		}
+		high := low
+		if len(bounds) == 2 {
+			if high, err = strconv.Atoi(strings.TrimSpace(bounds[1])); err != nil {
+				continue
+			}
+		}
+		if code >= low && code <= high {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup 按点分路径读取 JSON 字段，数组使用数字下标，如 data.items.0.code
+func (w *WebhookSender) lookup(data any, path string) (any, bool) {
+	current := data
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]any:
+			value, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func (w *WebhookSender) Send(ctx context.Context, message IMessage) (resp map[string]any, err error) {
+	method := strings.ToUpper(strings.TrimSpace(w.Method))
+	if method == "" {
+		method = http.MethodPost
+	}
+	headers, err := w.headers()
+	if err != nil {
+		return resp, err
+	}
+	var reader io.Reader
+	body := ""
+	if method != http.MethodGet && method != http.MethodHead {
+		if body, err = w.body(message); err != nil {
+			return resp, err
+		}
+		reader = bytes.NewReader([]byte(body))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSpace(w.URL), reader)
+	if err != nil {
+		return resp, err
+	}
+	if reader != nil && w.ContentType != "" {
+		req.Header.Set("Content-Type", w.ContentType)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if w.Secret != "" {
+		timestamp := time.Now().Unix()
+		req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(w.SignatureHeader, "sha256="+w.sign(timestamp, body))
+	}
+	response, err := clientx.GetClient().Do(req)
+	if err != nil {
+		return resp, err
+	}
+	defer response.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(response.Body, webhookMaxBodySize))
+	if err != nil {
+		return resp, err
+	}
+	resp = map[string]any{"status_code": response.StatusCode}
+	var data any
+	if json.Unmarshal(raw, &data) == nil {
+		resp["body"] = data
+	} else {
+		resp["body"] = string(raw)
+	}
+	if !w.statusOK(response.StatusCode) {
+		// 返回 HTTPError 保留状态码，5xx 与 429 才能被识别为可重试的失败
+		return resp, &clientx.HTTPError{
+			StatusCode: response.StatusCode,
+			Method:     method,
+			URL:        req.URL.String(),
+			Body:       raw[:min(len(raw), 512)],
+			Err:        fmt.Errorf("webhook response status %d not in %s", response.StatusCode, w.SuccessStatus),
+		}
+	}
+	if path := strings.TrimSpace(w.SuccessPath); path != "" {
+		value, ok := w.lookup(data, path)
+		if !ok {
+			return resp, fmt.Errorf("webhook response field %s not found", path)
+		}
+		if fmt.Sprint(value) != w.SuccessValue {
+			return resp, fmt.Errorf("webhook response field %s is %v, expected %s", path, value, w.SuccessValue)
+		}
+	}
+	return resp, nil
+}
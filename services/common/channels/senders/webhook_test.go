@@ -0,0 +1,99 @@
+package senders
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	"chihqiang/msgbox-go/pkg/templatex"
+)
+
+func TestWebhookSenderBody(t *testing.T) {
+	message := &testMessage{
+		Receiver: "u1",
+		Title:    `Say "hi"`,
+		Content:  "a&b\nc",
+		Variables: map[string]any{
+			"items": []any{map[string]any{"name": `x"1`}, map[string]any{"name": "y&2"}},
+			"tags":  []any{"a", "b"},
+			"user":  map[string]any{"name": "Tom"},
+		},
+	}
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"json builtin", "application/json", `{"title":"${title}","content":"${content}"}`, `{"title":"Say \"hi\"","content":"a\u0026b\nc"}`},
+		{"json loop", "application/json", `{"names":"${for item in items}${item.name}${if not loop.last},${end}${end}"}`, `{"names":"x\"1,y\u00262"}`},
+		{"json join and path", "application/json", `{"tags":"${tags|join:"|"}","user":"${user.name|upper}"}`, `{"tags":"a|b","user":"TOM"}`},
+		{"json default literal", "application/json", `{"v":"${missing|default:"a\"b"}"}`, `{"v":"a\"b"}`},
+		{"form", "application/x-www-form-urlencoded", `title=${title}&content=${content}`, "title=" + url.QueryEscape(`Say "hi"`) + "&content=" + url.QueryEscape("a&b\nc")},
+		{"plain", "text/plain", `${title}: ${content}`, "Say \"hi\": a&b\nc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &WebhookSender{ContentType: tt.contentType, Body: tt.body}
+			got, err := w.body(message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookSenderSend(t *testing.T) {
+	server := newFakeServer(t, func(w http.ResponseWriter, r capturedRequest) {
+		writeJSON(w, http.StatusOK, map[string]any{"code": 0})
+	})
+	sender := &WebhookSender{}
+	err := sender.SetConfig(map[string]any{
+		"url":           server.URL + "/hook",
+		"content_type":  "application/json",
+		"body":          `{"receiver":"${receiver}","items":"${items|join}"}`,
+		"secret":        "s3cret",
+		"success_path":  "code",
+		"success_value": "0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender.SignatureHeader = "X-Msgbox-Signature"
+	if _, err := sender.Send(context.Background(), &testMessage{Receiver: "u1", Variables: map[string]any{"items": []any{1, 2}}}); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	request := server.Requests()[0]
+	body := request.JSON(t)
+	if body["receiver"] != "u1" || body["items"] != "1,2" {
+		t.Errorf("body = %s", request.Body)
+	}
+	if request.Header.Get("X-Msgbox-Signature") == "" || request.Header.Get(WebhookTimestampHeader) == "" {
+		t.Errorf("signature headers missing: %v", request.Header)
+	}
+}
+
+// TestWebhookSenderVariables 请求体模板引用的模板变量，不包括内置变量
+func TestWebhookSenderVariables(t *testing.T) {
+	w := &WebhookSender{Body: `{"to":"${receiver}","text":"${content}","order":"${order_id}","vip":"${if vip}${level}${end}","title":"${title}"}`}
+	got, err := w.Variables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []templatex.Variable{
+		{Name: "order_id", Type: templatex.VariableTypeString, Required: true},
+		{Name: "vip", Type: templatex.VariableTypeString},
+		{Name: "level", Type: templatex.VariableTypeString},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Variables() = %+v, want %+v", got, want)
+	}
+	if _, err := (&WebhookSender{Body: "${order"}).Variables(); err == nil {
+		t.Error("invalid body template: want error")
+	}
+}
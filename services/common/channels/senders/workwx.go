@@ -36,21 +36,130 @@ func (w *WorkWxSender) buildWebhookURL() string {
 		return fmt.Sprintf("%s?key=%s", webhook, key)
 	}
 }
-func (w *WorkWxSender) Send(message IMessage) (resp map[string]any, err error) {
-	req := map[string]any{
-		"msgtype": "text",
-		"text": map[string]any{
-			"content":               message.GetContent(),
-			"mentioned_mobile_list": []string{message.GetReceiver()},
+
+// news 图文消息：extra.articles 存在时原样透传，否则使用标题、内容与 extra.url/pic_url 生成单条图文
+func (w *WorkWxSender) news(message IMessage) (map[string]any, error) {
+	if articles, ok := message.GetExtra()["articles"]; ok && articles != nil {
+		return map[string]any{"articles": articles}, nil
+	}
+	articleURL := extraString(message, "url")
+	if articleURL == "" {
+		return nil, errors.New("workwx news message requires extra.url or extra.articles")
+	}
+	return map[string]any{
+		"articles": []map[string]any{
+			{
+				"title":       titleOf(message),
+				"description": message.GetContent(),
+				"url":         articleURL,
+				"picurl":      extraString(message, "pic_url"),
+			},
+		},
+	}, nil
+}
+
+// templateCard 模版卡片：extra.template_card 存在时原样透传，否则生成文本通知卡片
+func (w *WorkWxSender) templateCard(message IMessage) (map[string]any, error) {
+	if card, ok := message.GetExtra()["template_card"].(map[string]any); ok {
+		return card, nil
+	}
+	cardURL := extraString(message, "url")
+	if cardURL == "" {
+		return nil, errors.New("workwx template_card message requires extra.url or extra.template_card")
+	}
+	return map[string]any{
+		"card_type": "text_notice",
+		"main_title": map[string]any{
+			"title": titleOf(message),
+		},
+		"sub_title_text": message.GetContent(),
+		"card_action": map[string]any{
+			"type": 1,
+			"url":  cardURL,
 		},
+	}, nil
+}
+
+// mentions 构建 mentioned_mobile_list，接收者 all 对应 @all
+func (w *WorkWxSender) mentions(receivers []string) []string {
+	mobiles := make([]string, 0, len(receivers))
+	for _, receiver := range receivers {
+		switch receiver {
+		case "":
+		case ReceiverAll:
+			mobiles = append(mobiles, "@all")
+		default:
+			mobiles = append(mobiles, receiver)
+		}
 	}
-	response, err := clientx.PostJSON(context.Background(), w.buildWebhookURL(), req)
+	return mobiles
+}
+
+// build 按消息类型构建请求体，markdown/news/template_card 不支持按手机号 @
+func (w *WorkWxSender) build(message IMessage, receivers []string) (map[string]any, error) {
+	switch msgType := message.GetMsgType(); msgType {
+	case MsgTypeText, "":
+		return map[string]any{
+			"msgtype": MsgTypeText,
+			"text": map[string]any{
+				"content":               message.GetContent(),
+				"mentioned_mobile_list": w.mentions(receivers),
+			},
+		}, nil
+	case MsgTypeMarkdown:
+		return map[string]any{
+			"msgtype": MsgTypeMarkdown,
+			"markdown": map[string]any{
+				"content": message.GetContent(),
+			},
+		}, nil
+	case MsgTypeNews:
+		news, err := w.news(message)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{
+			"msgtype": MsgTypeNews,
+			"news":    news,
+		}, nil
+	case MsgTypeTemplateCard:
+		card, err := w.templateCard(message)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{
+			"msgtype":       MsgTypeTemplateCard,
+			"template_card": card,
+		}, nil
+	default:
+		return nil, fmt.Errorf("workwx does not support msg type %s", msgType)
+	}
+}
+
+func (w *WorkWxSender) Send(ctx context.Context, message IMessage) (resp map[string]any, err error) {
+	return w.SendMention(ctx, message, []string{message.GetReceiver()})
+}
+
+// SendMention 发送一条群消息并 @全部接收者
+func (w *WorkWxSender) SendMention(ctx context.Context, message IMessage, receivers []string) (resp map[string]any, err error) {
+	req, err := w.build(message, receivers)
 	if err != nil {
 		return resp, err
 	}
-	_ = json.NewDecoder(response.Body).Decode(&resp)
+	response, err := clientx.PostJSON(ctx, w.buildWebhookURL(), req)
+	if err != nil {
+		return resp, err
+	}
+	defer response.Body.Close()
+	if err = json.NewDecoder(response.Body).Decode(&resp); err != nil {
+		return resp, err
+	}
 	if errCode, ok := resp["errcode"].(float64); ok && errCode != 0 {
-		err = errors.New(resp["errmsg"].(string))
+		msg, _ := resp["errmsg"].(string)
+		if msg == "" {
+			msg = fmt.Sprintf("workwx errcode %v", errCode)
+		}
+		return resp, errors.New(msg)
 	}
-	return resp, err
+	return resp, nil
 }
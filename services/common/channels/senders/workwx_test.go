@@ -0,0 +1,45 @@
+package senders
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestWorkWxSenderSendMention(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   map[string]any
+		wantErr string
+	}{
+		{"ok", map[string]any{"errcode": 0, "errmsg": "ok"}, ""},
+		{"errmsg", map[string]any{"errcode": 93000, "errmsg": "invalid webhook url"}, "invalid webhook url"},
+		{"no errmsg", map[string]any{"errcode": 45009}, "workwx errcode 45009"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t, func(w http.ResponseWriter, r capturedRequest) {
+				writeJSON(w, http.StatusOK, tt.reply)
+			})
+			sender := &WorkWxSender{}
+			if err := sender.SetConfig(map[string]any{"url": server.URL + "/cgi-bin/webhook/send", "key": "k1"}); err != nil {
+				t.Fatal(err)
+			}
+			_, err := sender.SendMention(context.Background(), &testMessage{Content: "hello"}, []string{"13800000000", "13900000000"})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("send failed: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("err = %v, want %s", err, tt.wantErr)
+			}
+			request := server.Requests()[0]
+			if key := request.Query["key"]; len(key) != 1 || key[0] != "k1" {
+				t.Errorf("query = %v", request.Query)
+			}
+			text, _ := request.JSON(t)["text"].(map[string]any)
+			if mobiles, _ := text["mentioned_mobile_list"].([]any); len(mobiles) != 2 {
+				t.Errorf("text = %v", text)
+			}
+		})
+	}
+}
@@ -29,17 +29,40 @@ const (
 	ErrCodeAuthMissing     = 2000 // 缺少认证头：请求未携带 Authorization 头信息
 	ErrCodeAuthInvalidForm = 2001 // 认证格式/解码错误：Authorization 格式错误或 Base64 解码失败
 	ErrCodeAuthInvalid     = 2002 // 认证凭证无效：账号或密码错误，验证未通过
+	ErrCodeAgentDisabled   = 2003 // 代理商已被禁用
 )
 
 const (
 	ErrCodeTemplateMissing        = 3000
 	ErrCodeTemplateChannelMissing = 3001
+	ErrCodeTemplateDisabled       = 3002 // 模版已被禁用
+	ErrCodeChannelDisabled        = 3003 // 模版的主通道与备用通道均已被禁用
+	ErrCodeTemplateRender         = 3004 // 模版语法错误或渲染变量缺失
+	ErrCodeVariableInvalid        = 3005 // 请求变量不符合模版的变量声明
 )
 
 const (
 	ErrCodeDB = 4000
 )
 
+// 发送批次错误码（5000 段）
+const (
+	ErrCodeSendAtInvalid = 5000 // 计划发送时间格式错误或早于当前时间
+	ErrCodeBatchMissing  = 5001 // 批次不存在
+	ErrCodeBatchStarted  = 5002 // 批次已开始发送或已取消，不能再修改
+
+	ErrCodeIdempotencyConflict   = 5003 // 幂等键已被内容不同的请求使用
+	ErrCodeIdempotencyProcessing = 5004 // 幂等键的首次请求仍在处理中
+	ErrCodeBatchTooLarge         = 5005 // 单次请求的接收者数量超过上限
+)
+
+// 回执错误码（6000 段）
+const (
+	ErrCodeReceiptUnsupported  = 6000 // 服务商不存在或不支持回执回调
+	ErrCodeReceiptTokenInvalid = 6001 // 回执令牌与服务商通道不匹配
+	ErrCodeReceiptPending      = 6002 // 回执对应的发送记录尚未受理
+)
+
 // errorMap 错误码-提示信息映射表
 // 说明：
 // 1. 严格与上方错误码常量一一对应，禁止出现无码的消息或无消息的码
@@ -58,12 +81,31 @@ var errorMap = map[int]string{
 	ErrCodeAuthMissing:     "缺少Authorization认证头，请在请求头中携带认证信息",
 	ErrCodeAuthInvalidForm: "认证信息不合法，正确格式：Basic <base64(账号:密码)>，需确保是「账号:密码」的Base64编码",
 	ErrCodeAuthInvalid:     "账号或密码错误，认证失败，请核对后重试",
+	ErrCodeAgentDisabled:   "账号已被禁用，请联系管理员",
 
 	//模版错误
 	ErrCodeTemplateMissing:        "缺少模版code",
 	ErrCodeTemplateChannelMissing: "模版没有配置通道",
+	ErrCodeTemplateDisabled:       "模版已被禁用",
+	ErrCodeChannelDisabled:        "模版的通道已被禁用",
+	ErrCodeTemplateRender:         "模版渲染失败，请检查模版语法与变量",
+	ErrCodeVariableInvalid:        "模版变量缺失或不合法",
 
 	ErrCodeDB: "内部错误",
+
+	// 批次错误
+	ErrCodeSendAtInvalid: "计划发送时间无效，格式为 2006-01-02 15:04:05 或 RFC3339，且不能早于当前时间",
+	ErrCodeBatchMissing:  "批次不存在",
+	ErrCodeBatchStarted:  "批次已开始发送或已取消，无法修改",
+
+	ErrCodeIdempotencyConflict:   "幂等键已被其他请求使用，请求内容不一致",
+	ErrCodeIdempotencyProcessing: "相同幂等键的请求正在处理中，请稍后重试",
+	ErrCodeBatchTooLarge:         "单次请求的接收者数量超过上限，请拆分后发送",
+
+	// 回执错误
+	ErrCodeReceiptUnsupported:  "该服务商不支持回执回调",
+	ErrCodeReceiptTokenInvalid: "回执令牌无效",
+	ErrCodeReceiptPending:      "发送记录尚未受理，请稍后重推",
 }
 
 // 预定义错误对象：全局复用，避免重复创建
@@ -78,10 +120,27 @@ var (
 	ErrAuthMissing     = GetErr(ErrCodeAuthMissing)     // 缺少Authorization认证头
 	ErrAuthInvalidForm = GetErr(ErrCodeAuthInvalidForm) // 认证信息格式/解码错误
 	ErrAuthInvalid     = GetErr(ErrCodeAuthInvalid)     // 账号或密码错误
+	ErrAgentDisabled   = GetErr(ErrCodeAgentDisabled)   // 代理商已被禁用
 
 	ErrTemplateCodeMissing    = GetErr(ErrCodeTemplateMissing) // 缺少模版code
 	ErrTemplateChannelMissing = GetErr(ErrCodeTemplateChannelMissing)
+	ErrTemplateDisabled       = GetErr(ErrCodeTemplateDisabled) // 模版已被禁用
+	ErrChannelDisabled        = GetErr(ErrCodeChannelDisabled)  // 模版的通道均已被禁用
+	ErrTemplateRender         = GetErr(ErrCodeTemplateRender)   // 模版渲染失败
+	ErrVariableInvalid        = GetErr(ErrCodeVariableInvalid)  // 模版变量缺失或不合法
 	ErrDB                     = GetErr(ErrCodeDB)
+
+	ErrSendAtInvalid = GetErr(ErrCodeSendAtInvalid) // 计划发送时间无效
+	ErrBatchMissing  = GetErr(ErrCodeBatchMissing)  // 批次不存在
+	ErrBatchStarted  = GetErr(ErrCodeBatchStarted)  // 批次已开始发送或已取消
+
+	ErrIdempotencyConflict   = GetErr(ErrCodeIdempotencyConflict)   // 幂等键请求内容不一致
+	ErrIdempotencyProcessing = GetErr(ErrCodeIdempotencyProcessing) // 幂等键首次请求处理中
+	ErrBatchTooLarge         = GetErr(ErrCodeBatchTooLarge)         // 接收者数量超过上限
+
+	ErrReceiptUnsupported  = GetErr(ErrCodeReceiptUnsupported)  // 服务商不支持回执回调
+	ErrReceiptTokenInvalid = GetErr(ErrCodeReceiptTokenInvalid) // 回执令牌无效
+	ErrReceiptPending      = GetErr(ErrCodeReceiptPending)      // 发送记录尚未受理
 )
 
 // GetErr 根据错误码获取对应的错误对象
@@ -99,3 +158,13 @@ func GetErr(code int) error {
 	}
 	return errors.New(code, msg)
 }
+
+// GetErrDetail 根据错误码获取错误对象，并在提示信息后附加具体原因
+// 适用于需要告知调用方出错位置的场景，如模版渲染失败的行号与变量名
+func GetErrDetail(code int, detail string) error {
+	msg, ok := errorMap[code]
+	if !ok {
+		return GetErr(code)
+	}
+	return errors.New(code, msg+": "+detail)
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+const (
+	SendAttemptStatusSuccess = 1 // 服务商受理成功
+	SendAttemptStatusFailed  = 2 // 失败
+)
+
+// SendAttempt 发送记录的每次发送尝试，用于排查重试过程中每次失败的原因
+type SendAttempt struct {
+	ID         int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	RecordID   int64          `gorm:"column:record_id;not null;index;comment:发送记录ID" json:"record_id"`
+	BatchID    int64          `gorm:"column:batch_id;not null;comment:所属批次ID" json:"batch_id"`
+	AgentID    int64          `gorm:"column:agent_id;not null;index;comment:代理商ID" json:"agent_id"`
+	ChannelID  int64          `gorm:"column:channel_id;not null;default:0;comment:本次尝试使用的通道ID" json:"channel_id"`
+	VendorName string         `gorm:"column:vendor_name;size:50;default:'';comment:本次尝试使用的服务商" json:"vendor_name"`
+	Attempt    int            `gorm:"column:attempt;not null;comment:第几次发送" json:"attempt"`
+	Status     int            `gorm:"column:status;not null;comment:发送结果(1=成功,2=失败)" json:"status"`
+	Error      string         `gorm:"column:error;size:255;default:'';comment:错误内容" json:"error"`
+	FailReason string         `gorm:"column:fail_reason;size:32;default:'';comment:失败原因分类" json:"fail_reason"`
+	Response   datatypes.JSON `gorm:"column:response;type:json;comment:服务商原始响应" json:"response"`
+	StartTime  time.Time      `gorm:"column:start_time;comment:开始发送时间" json:"start_time"`
+	EndTime    time.Time      `gorm:"column:end_time;comment:结束发送时间" json:"end_time"`
+	// NextAttemptTime 本次失败后安排的重试时间，为空表示不再重试
+	NextAttemptTime *time.Time `gorm:"column:next_attempt_time;comment:下次重试时间" json:"next_attempt_time"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime:nano" json:"created_at"`
+}
+
+func (a *SendAttempt) StatusMsg() string {
+	if a.Status == SendAttemptStatusSuccess {
+		return "成功"
+	}
+	return "失败"
+}
+
+func (a *SendAttempt) TableName() string {
+	return "msgbox_send_attempts"
+}
@@ -1,27 +1,85 @@
 package models
 
 import (
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
-	"time"
 )
 
+// 重试退避策略
+const (
+	ChannelRetryBackoffFixed       = "fixed"       // 固定间隔
+	ChannelRetryBackoffExponential = "exponential" // 指数退避，间隔按 2 的幂递增
+)
+
+// channelRetryMaxInterval 指数退避的最大重试间隔
+const channelRetryMaxInterval = 24 * time.Hour
+
 type Channel struct {
-	ID         int64          `gorm:"primaryKey;autoIncrement" json:"id"`
-	AgentID    int64          `gorm:"column:agent_id;uniqueIndex:idx_agent_code;not null;comment:代理商ID" json:"agent_id"`
-	Code       string         `gorm:"column:code;uniqueIndex:idx_agent_code;size:50;not null;comment:通道编码" json:"code"`
-	Name       string         `gorm:"column:name;size:50;default:'';comment:通道名称" json:"name"`
-	VendorName string         `gorm:"column:vendor_name;size:50;not null;comment:服务商名称" json:"vendor_name"`
-	Config     datatypes.JSON `gorm:"column:config;type:JSON;not null;comment:通道配置" json:"config"`
-	Status     bool           `gorm:"column:status;not null;comment:状态（true=启用，false=禁用）" json:"status"`
-	CreatedAt  time.Time      `gorm:"autoCreateTime:nano" json:"created_at"`
-	UpdatedAt  time.Time      `gorm:"autoUpdateTime:nano" json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	AgentID          int64          `gorm:"column:agent_id;uniqueIndex:idx_agent_code;not null;comment:代理商ID" json:"agent_id"`
+	Code             string         `gorm:"column:code;uniqueIndex:idx_agent_code;size:50;not null;comment:通道编码" json:"code"`
+	Name             string         `gorm:"column:name;size:50;default:'';comment:通道名称" json:"name"`
+	VendorName       string         `gorm:"column:vendor_name;size:50;not null;comment:服务商名称" json:"vendor_name"`
+	Config           datatypes.JSON `gorm:"column:config;type:JSON;not null;comment:通道配置" json:"config"`
+	Timeout          int            `gorm:"column:timeout;not null;default:0;comment:发送超时时间（秒，0=使用默认值）" json:"timeout"`
+	RetryMaxAttempts int            `gorm:"column:retry_max_attempts;not null;default:1;comment:最大发送次数（含首次，1=不重试）" json:"retry_max_attempts"`
+	RetryBackoff     string         `gorm:"column:retry_backoff;size:16;not null;default:'exponential';comment:重试退避策略(fixed/exponential)" json:"retry_backoff"`
+	RetryInterval    int            `gorm:"column:retry_interval;not null;default:60;comment:重试基础间隔（秒）" json:"retry_interval"`
+	RetryCodes       string         `gorm:"column:retry_codes;size:255;default:'';comment:服务商限流错误码，多个用逗号分隔，命中时允许重试" json:"retry_codes"`
+	MergeMention     bool           `gorm:"column:merge_mention;not null;default:false;comment:合并提醒（群机器人同一批次只发送一条消息并@全部接收者）" json:"merge_mention"`
+	ReceiptToken     string         `gorm:"column:receipt_token;size:32;default:'';index;comment:回执回调令牌，拼接在回调地址中校验来源" json:"receipt_token"`
+	Status           bool           `gorm:"column:status;not null;comment:状态（true=启用，false=禁用）" json:"status"`
+	CreatedAt        time.Time      `gorm:"autoCreateTime:nano" json:"created_at"`
+	UpdatedAt        time.Time      `gorm:"autoUpdateTime:nano" json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 
 	Agent     *Agent      `gorm:"foreignKey:AgentID" json:"agent,omitempty"`
 	Templates []*Template `gorm:"foreignKey:ChannelID" json:"templates"`
 }
 
+// BeforeCreate 生成回执回调令牌
+func (a *Channel) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ReceiptToken == "" {
+		a.ReceiptToken = NewReceiptToken()
+	}
+	return nil
+}
+
+// NewReceiptToken 生成随机的回执回调令牌
+func NewReceiptToken() string {
+	return lo.RandomString(32, lo.AlphanumericCharset)
+}
+
+// RateLimited 错误信息是否包含配置的服务商限流错误码
+func (a *Channel) RateLimited(errMsg string) bool {
+	for _, code := range strings.Split(a.RetryCodes, ",") {
+		if code = strings.TrimSpace(code); code != "" && strings.Contains(errMsg, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextAttemptTime 第 attempts 次发送失败后的下次重试时间，失败原因不可重试或已达最大次数时返回 nil
+func (a *Channel) NextAttemptTime(attempts int, reason string, now time.Time) *time.Time {
+	if attempts >= a.RetryMaxAttempts || !RetryableFailReason(reason) {
+		return nil
+	}
+	interval := time.Duration(max(a.RetryInterval, 1)) * time.Second
+	if a.RetryBackoff != ChannelRetryBackoffFixed {
+		for i := 1; i < attempts && interval < channelRetryMaxInterval; i++ {
+			interval *= 2
+		}
+		interval = min(interval, channelRetryMaxInterval)
+	}
+	next := now.Add(interval)
+	return &next
+}
+
 func (a Channel) TableName() string {
 	return "msgbox_channels"
 }
@@ -12,14 +12,62 @@ import (
 	"time"
 )
 
-func Migrate(db *gorm.DB) error {
-	return db.Migrator().AutoMigrate(
+// Tables 需要自动迁移的数据表模型
+func Tables() []any {
+	return []any{
 		&Agent{},
 		&Channel{},
 		&Template{},
+		&TemplateFanout{},
+		&TemplateVersion{},
+		&TemplateLocale{},
 		&SendBatch{},
 		&SendRecord{},
-	)
+		&SendAttempt{},
+		&Idempotency{},
+	}
+}
+
+func Migrate(db *gorm.DB) error {
+	if err := db.Migrator().AutoMigrate(Tables()...); err != nil {
+		return err
+	}
+	if err := backfillReceiptTokens(db); err != nil {
+		return err
+	}
+	return backfillTemplateVersions(db)
+}
+
+// backfillReceiptTokens 为新增回执令牌字段之前创建的通道逐条生成令牌
+func backfillReceiptTokens(db *gorm.DB) error {
+	var ids []int64
+	if err := db.Model(&Channel{}).Unscoped().Where("receipt_token = ?", "").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := db.Model(&Channel{}).Unscoped().Where("id = ?", id).Update("receipt_token", NewReceiptToken()).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillTemplateVersions 为版本功能上线前创建、尚无版本的模板以当前内容生成初始版本，
+// 发送时只读取模板的当前版本，不再写入版本
+func backfillTemplateVersions(db *gorm.DB) error {
+	var templates []*Template
+	if err := db.Where("version_id = ?", 0).
+		Preload("Fanouts", func(db *gorm.DB) *gorm.DB { return db.Order("sort, id") }).
+		Preload("Locales", func(db *gorm.DB) *gorm.DB { return db.Order("channel_id, locale") }).
+		Find(&templates).Error; err != nil {
+		return err
+	}
+	for _, template := range templates {
+		if _, err := EnsureTemplateVersion(db, template); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 type Config struct {
@@ -44,7 +92,7 @@ func Connect(cfg Config, plugin ...gorm.Plugin) (*gorm.DB, error) {
 			cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.Database)
 		dialector = mysql.Open(dsn)
 	case "postgres":
-		dsn := fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s",
+		dsn := fmt.Sprintf("host=%s port=%d user=%s dbname=%s password=%s sslmode=%s",
 			cfg.Host, cfg.Port, cfg.Username, cfg.Database, cfg.Password, cfg.SSLMode)
 		dialector = postgres.Open(dsn)
 	default:
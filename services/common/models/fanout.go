@@ -0,0 +1,120 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TemplateFanout 模板的扇出通道：同一次发送同时投递到模板主通道与全部扇出通道，
+// 每个扇出通道可配置独立的内容变体，字段为空时沿用模板的对应字段
+type TemplateFanout struct {
+	ID         int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	AgentID    int64          `gorm:"column:agent_id;not null;index;comment:代理商ID" json:"agent_id"`
+	TemplateID int64          `gorm:"column:template_id;not null;index;comment:模板ID" json:"template_id"`
+	ChannelID  int64          `gorm:"column:channel_id;not null;comment:扇出通道ID" json:"channel_id"`
+	VendorCode string         `gorm:"column:vendor_code;size:100;default:'';comment:厂商模板编码（空=沿用模板）" json:"vendor_code"`
+	Signature  string         `gorm:"column:signature;size:64;default:'';comment:签名（空=沿用模板）" json:"signature"`
+	MsgType    string         `gorm:"column:msg_type;size:32;default:'';comment:消息类型（空=沿用模板）" json:"msg_type"`
+	Title      string         `gorm:"column:title;size:255;default:'';comment:消息标题（空=沿用模板）" json:"title"`
+	Content    string         `gorm:"column:content;type:text;comment:模板内容（空=沿用模板）" json:"content"`
+	Sort       int            `gorm:"column:sort;not null;default:0;comment:排序" json:"sort"`
+	CreatedAt  time.Time      `gorm:"autoCreateTime:nano" json:"created_at"`
+	UpdatedAt  time.Time      `gorm:"autoUpdateTime:nano" json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Channel *Channel `gorm:"foreignKey:ChannelID" json:"channel,omitempty"`
+}
+
+func (f TemplateFanout) TableName() string {
+	return "msgbox_template_fanouts"
+}
+
+// Variant 以模板为基础合并扇出通道的内容变体，返回的模板副本指向扇出通道
+func (f *TemplateFanout) Variant(t *Template) *Template {
+	variant := *t
+	variant.ChannelID = f.ChannelID
+	variant.Channel = f.Channel
+	variant.FallbackChannelIDs = nil
+	variant.Fanouts = nil
+	if f.VendorCode != "" {
+		variant.VendorCode = f.VendorCode
+	}
+	if f.Signature != "" {
+		variant.Signature = f.Signature
+	}
+	if f.MsgType != "" {
+		variant.MsgType = f.MsgType
+	}
+	if f.Title != "" {
+		variant.Title = f.Title
+	}
+	if f.Content != "" {
+		variant.Content = f.Content
+	}
+	return &variant
+}
+
+// overridesLocalized 扇出通道是否覆盖了语言变体可配置的字段
+func (f *TemplateFanout) overridesLocalized() bool {
+	return f.VendorCode != "" || f.Signature != "" || f.Title != "" || f.Content != ""
+}
+
+// Localize 按接收者语言选择扇出通道的内容：优先使用扇出通道自己的语言变体；
+// 扇出通道没有覆盖可本地化的字段时沿用模板主内容的语言变体；
+// 否则扇出通道的内容只有默认语言，返回的语言为空。模板需预加载 Locales
+func (f *TemplateFanout) Localize(t *Template, locale string) (*Template, string) {
+	if l := t.findLocale(f.ChannelID, locale); l != nil {
+		return l.Variant(f.Variant(t)), l.Locale
+	}
+	if !f.overridesLocalized() {
+		localized, selected := t.Localize(locale)
+		return f.Variant(localized), selected
+	}
+	return f.Variant(t), ""
+}
+
+// TemplateVariant 按接收者语言选中的通道内容变体
+type TemplateVariant struct {
+	*Template
+	// Locale 选中的语言，空表示使用默认内容
+	Locale string
+}
+
+// Variants 按接收者语言选择模板主通道与全部扇出通道的内容变体，主通道在前
+func (t *Template) Variants(locale string) []*TemplateVariant {
+	localized, selected := t.Localize(locale)
+	variants := []*TemplateVariant{{Template: localized, Locale: selected}}
+	for _, fanout := range t.Fanouts {
+		if fanout.Channel != nil {
+			variant, selected := fanout.Localize(t, locale)
+			variants = append(variants, &TemplateVariant{Template: variant, Locale: selected})
+		}
+	}
+	return variants
+}
+
+// RouteReceiver 按接收者前缀选择通道变体：前缀为通道编码或服务商名称时（如 email:foo@x.com、aliyun_sms:138xxxx）
+// 投递到匹配的通道并去掉前缀，通道编码优先；无前缀或前缀不匹配时投递到主通道
+func RouteReceiver(variants []*TemplateVariant, receiver string) ([]*TemplateVariant, string) {
+	prefix, value, ok := strings.Cut(receiver, ":")
+	if !ok || prefix == "" || value == "" {
+		return variants[:1], receiver
+	}
+	for _, variant := range variants {
+		if variant.Channel != nil && strings.EqualFold(variant.Channel.Code, prefix) {
+			return []*TemplateVariant{variant}, value
+		}
+	}
+	matched := make([]*TemplateVariant, 0)
+	for _, variant := range variants {
+		if variant.Channel != nil && strings.EqualFold(variant.Channel.VendorName, prefix) {
+			matched = append(matched, variant)
+		}
+	}
+	if len(matched) == 0 {
+		return variants[:1], receiver
+	}
+	return matched, value
+}
@@ -0,0 +1,63 @@
+package models
+
+import "testing"
+
+func TestTemplateVariantsLocale(t *testing.T) {
+	template := &Template{
+		ChannelID: 1,
+		Content:   "default",
+		Channel:   &Channel{ID: 1, Code: "sms"},
+		Fanouts: []*TemplateFanout{
+			{ChannelID: 2, Content: "email default", Channel: &Channel{ID: 2, Code: "email"}},
+			{ChannelID: 3, Content: "ding default", Channel: &Channel{ID: 3, Code: "ding"}},
+			{ChannelID: 4, MsgType: "markdown", Channel: &Channel{ID: 4, Code: "hook"}},
+		},
+		Locales: []*TemplateLocale{
+			{Locale: "zh", Content: "默认"},
+			{ChannelID: 2, Locale: "zh", Content: "邮件"},
+		},
+	}
+	tests := []struct {
+		name        string
+		locale      string
+		wantContent []string
+		wantLocale  []string
+	}{
+		{
+			name:        "no locale",
+			wantContent: []string{"default", "email default", "ding default", "default"},
+			wantLocale:  []string{"", "", "", ""},
+		},
+		{
+			// 扇出通道自己的语言变体优先；覆盖了内容但没有对应语言的扇出通道使用自己的默认内容；
+			// 没有覆盖内容的扇出通道沿用模板主内容的语言变体
+			name:        "fallback to zh",
+			locale:      "zh-TW",
+			wantContent: []string{"默认", "邮件", "ding default", "默认"},
+			wantLocale:  []string{"zh", "zh", "", "zh"},
+		},
+		{
+			name:        "unknown locale",
+			locale:      "en",
+			wantContent: []string{"default", "email default", "ding default", "default"},
+			wantLocale:  []string{"", "", "", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variants := template.Variants(tt.locale)
+			if len(variants) != len(tt.wantContent) {
+				t.Fatalf("got %d variants, want %d", len(variants), len(tt.wantContent))
+			}
+			for i, variant := range variants {
+				if variant.Content != tt.wantContent[i] || variant.Locale != tt.wantLocale[i] {
+					t.Errorf("variant %d (channel %d) = %q/%q, want %q/%q",
+						i, variant.ChannelID, variant.Content, variant.Locale, tt.wantContent[i], tt.wantLocale[i])
+				}
+			}
+			if variants[3].MsgType != "markdown" {
+				t.Errorf("fanout msg_type = %q, want markdown", variants[3].MsgType)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Idempotency 发送接口的幂等键，同一代理商同一键在有效期内只创建一个批次，重放时返回首次请求的响应或错误
+type Idempotency struct {
+	ID          int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	AgentID     int64          `gorm:"column:agent_id;not null;uniqueIndex:idx_agent_key;comment:代理商ID" json:"agent_id"`
+	Key         string         `gorm:"column:idempotency_key;size:128;not null;uniqueIndex:idx_agent_key;comment:幂等键" json:"key"`
+	RequestHash string         `gorm:"column:request_hash;size:64;not null;comment:请求内容摘要，同一键的请求内容不同时拒绝" json:"request_hash"`
+	BatchNo     string         `gorm:"column:batch_no;size:64;default:'';comment:首次请求创建的批次编号，批次创建后立即写入，为空表示尚未创建批次" json:"batch_no"`
+	Response    datatypes.JSON `gorm:"column:response;type:json;comment:首次请求的响应，为空表示首次请求仍在发送中" json:"response"`
+	ErrCode     int            `gorm:"column:err_code;not null;default:0;comment:首次请求创建批次后失败的错误码，0 表示成功" json:"err_code"`
+	ErrMsg      string         `gorm:"column:err_msg;type:text;comment:首次请求创建批次后失败的错误信息" json:"err_msg"`
+	LeaseUntil  *time.Time     `gorm:"column:lease_until;comment:首次请求处理中的租约到期时间，发送期间定时续期，到期仍未完成时视为首次请求已中断" json:"lease_until"`
+	ExpireTime  time.Time      `gorm:"column:expire_time;not null;index;comment:过期时间，过期后同一键可重新使用" json:"expire_time"`
+	CreatedAt   time.Time      `gorm:"autoCreateTime:nano" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"autoUpdateTime:nano" json:"updated_at"`
+}
+
+func (i Idempotency) TableName() string {
+	return "msgbox_idempotency_keys"
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// localePattern 语言标签，如 en、zh-TW、zh-Hant-TW，大小写与下划线在比较前统一
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,8}(-[A-Za-z0-9]{1,8})*$`)
+
+// TemplateLocale 模板的语言变体：按接收者的语言选择服务商模板编码、签名、标题与内容，
+// 字段为空时沿用模板的对应字段；ChannelID 不为 0 时是该扇出通道的语言变体，字段为空时沿用扇出通道的对应字段
+type TemplateLocale struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	AgentID    int64     `gorm:"column:agent_id;not null;index;comment:代理商ID" json:"agent_id"`
+	TemplateID int64     `gorm:"column:template_id;not null;uniqueIndex:idx_template_channel_locale;comment:模板ID" json:"template_id"`
+	ChannelID  int64     `gorm:"column:channel_id;not null;default:0;uniqueIndex:idx_template_channel_locale;comment:扇出通道ID（0=模板主内容）" json:"channel_id"`
+	Locale     string    `gorm:"column:locale;size:35;not null;uniqueIndex:idx_template_channel_locale;comment:语言（如 en、zh-TW）" json:"locale"`
+	VendorCode string    `gorm:"column:vendor_code;size:100;default:'';comment:厂商模板编码（空=沿用模板）" json:"vendor_code"`
+	Signature  string    `gorm:"column:signature;size:64;default:'';comment:签名（空=沿用模板）" json:"signature"`
+	Title      string    `gorm:"column:title;size:255;default:'';comment:消息标题（空=沿用模板）" json:"title"`
+	Content    string    `gorm:"column:content;type:text;comment:模板内容（空=沿用模板）" json:"content"`
+	CreatedAt  time.Time `gorm:"autoCreateTime:nano" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime:nano" json:"updated_at"`
+}
+
+func (l TemplateLocale) TableName() string {
+	return "msgbox_template_locales"
+}
+
+// NormalizeLocale 统一语言标签的写法：下划线换为连字符，语言小写、地区大写、文字首字母大写，
+// 如 zh_tw → zh-TW、zh-hant-tw → zh-Hant-TW；不是合法的语言标签时返回 false
+func NormalizeLocale(locale string) (string, bool) {
+	locale = strings.ReplaceAll(strings.TrimSpace(locale), "_", "-")
+	if locale == "" || !localePattern.MatchString(locale) {
+		return "", false
+	}
+	parts := strings.Split(locale, "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		switch len(parts[i]) {
+		case 2:
+			parts[i] = strings.ToUpper(parts[i])
+		case 4:
+			parts[i] = strings.ToUpper(parts[i][:1]) + strings.ToLower(parts[i][1:])
+		default:
+			parts[i] = strings.ToLower(parts[i])
+		}
+	}
+	return strings.Join(parts, "-"), true
+}
+
+// LocaleChain 语言的回退顺序，逐级去掉最后一段，如 zh-Hant-TW → zh-Hant → zh
+func LocaleChain(locale string) []string {
+	locale, ok := NormalizeLocale(locale)
+	if !ok {
+		return nil
+	}
+	chain := []string{locale}
+	for {
+		i := strings.LastIndex(locale, "-")
+		if i < 0 {
+			return chain
+		}
+		locale = locale[:i]
+		chain = append(chain, locale)
+	}
+}
+
+// Localize 按回退顺序选择模板主内容的语言变体，返回合并后的模板副本与选中的语言；
+// 未传语言或没有匹配的变体时返回模板本身，语言为空表示使用默认内容。模板需预加载 Locales
+func (t *Template) Localize(locale string) (*Template, string) {
+	if l := t.findLocale(0, locale); l != nil {
+		return l.Variant(t), l.Locale
+	}
+	return t, ""
+}
+
+// findLocale 按回退顺序查找指定通道的语言变体，channelID 为 0 时查找模板主内容的语言变体
+func (t *Template) findLocale(channelID int64, locale string) *TemplateLocale {
+	for _, candidate := range LocaleChain(locale) {
+		for _, l := range t.Locales {
+			if l.ChannelID == channelID && strings.EqualFold(l.Locale, candidate) {
+				return l
+			}
+		}
+	}
+	return nil
+}
+
+// Variant 以模板为基础合并语言变体的内容，扇出通道沿用模板的配置
+func (l *TemplateLocale) Variant(t *Template) *Template {
+	variant := *t
+	if l.VendorCode != "" {
+		variant.VendorCode = l.VendorCode
+	}
+	if l.Signature != "" {
+		variant.Signature = l.Signature
+	}
+	if l.Title != "" {
+		variant.Title = l.Title
+	}
+	if l.Content != "" {
+		variant.Content = l.Content
+	}
+	return &variant
+}
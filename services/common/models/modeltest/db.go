@@ -0,0 +1,65 @@
+// Package modeltest 测试辅助：基于 SQLite 的临时数据库，表结构由 models.Migrate 创建，
+// 命名规则与 models.Connect 一致，供各服务的单元测试使用
+package modeltest
+
+import (
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"chihqiang/msgbox-go/services/common/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// NewDB 创建只属于当前测试的数据库，测试结束时自动关闭；
+// 使用临时文件而非内存库，并设置忙等待，保证并发测试可以同时读写
+func NewDB(t testing.TB) *gorm.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "msgbox.db") + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		DisableForeignKeyConstraintWhenMigrating: true,
+		NamingStrategy: schema.NamingStrategy{
+			SingularTable: true,
+		},
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get sql db failed: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if err := prefixIndexes(db); err != nil {
+		t.Fatalf("prefix indexes failed: %v", err)
+	}
+	if err := models.Migrate(db); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	return db
+}
+
+// explicitIndex 模型标签中显式命名的索引
+var explicitIndex = regexp.MustCompile(`(?i)((?:uniqueIndex|index):)(idx_\w+)`)
+
+// prefixIndexes SQLite 的索引名在整个库内唯一，而通道与模版共用 idx_agent_code，
+// 迁移前在当前测试库的模型缓存中为显式命名的索引加上表名前缀，不影响正式库的表结构
+func prefixIndexes(db *gorm.DB) error {
+	for _, model := range models.Tables() {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		for _, field := range stmt.Schema.Fields {
+			tag := explicitIndex.ReplaceAllString(string(field.Tag), "${1}"+stmt.Schema.Table+"_${2}")
+			field.Tag = reflect.StructTag(tag)
+		}
+	}
+	return nil
+}
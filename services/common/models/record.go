@@ -1,19 +1,44 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
+// 发送记录状态，状态转换见 status.go
 const (
-	SendRecordStatusPending = 1 // 待发送
-	SendRecordStatusSending = 2 // 发送中
-	SendRecordStatusSuccess = 3 // 成功
-	SendRecordStatusFailed  = 4 // 失败
+	SendRecordStatusPending   = 1 // 待发送
+	SendRecordStatusSending   = 2 // 发送中（正在调用服务商）
+	SendRecordStatusAccepted  = 3 // 服务商已受理，等待回执（沿用原「成功」的取值，历史数据无需迁移）
+	SendRecordStatusFailed    = 4 // 失败
+	SendRecordStatusCancel    = 5 // 已取消
+	SendRecordStatusDelivered = 6 // 已送达（服务商回执确认）
 )
 
+const (
+	SendRecordFailReasonSender    = "sender"     // 发送器不存在或通道配置错误
+	SendRecordFailReasonVendor    = "vendor"     // 服务商调用失败
+	SendRecordFailReasonTimeout   = "timeout"    // 服务商调用超时
+	SendRecordFailReasonCancel    = "cancel"     // 请求被取消
+	SendRecordFailReasonNetwork   = "network"    // 网络错误（连接失败、连接重置等）
+	SendRecordFailReasonServer    = "server"     // 服务商返回 5xx
+	SendRecordFailReasonRateLimit = "rate_limit" // 服务商限流（HTTP 429 或命中通道配置的限流错误码）
+	SendRecordFailReasonReceipt   = "receipt"    // 服务商回执报告发送失败
+)
+
+// RetryableFailReason 失败原因是否允许重试，服务商业务错误与配置错误重试无意义
+func RetryableFailReason(reason string) bool {
+	switch reason {
+	case SendRecordFailReasonTimeout, SendRecordFailReasonNetwork, SendRecordFailReasonServer, SendRecordFailReasonRateLimit:
+		return true
+	default:
+		return false
+	}
+}
+
 type SendBatch struct {
 	ID            int64          `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
 	AgentID       int64          `gorm:"column:agent_id;not null;index;comment:代理商ID" json:"agent_id"`
@@ -24,9 +49,12 @@ type SendBatch struct {
 	TotalCount    int            `gorm:"column:total_count;default:0;comment:总消息条数" json:"total_count"`
 	SuccessCount  int            `gorm:"column:success_count;default:0;comment:发送成功条数" json:"success_count"`
 	FailCount     int            `gorm:"column:fail_count;default:0;comment:发送失败条数" json:"fail_count"`
+	Async         bool           `gorm:"column:async;not null;default:false;comment:是否异步发送（由 worker 从队列领取发送）" json:"async"`
+	Test          bool           `gorm:"column:test;not null;default:false;comment:是否为管理后台的测试发送（不计入统计）" json:"test"`
 	ScheduledTime *time.Time     `gorm:"column:scheduled_time;comment:计划发送时间" json:"scheduled_time"`
 	SendStartTime *time.Time     `gorm:"column:send_start_time;comment:实际开始发送时间" json:"send_start_time"`
 	SendEndTime   *time.Time     `gorm:"column:send_end_time;comment:实际结束发送时间" json:"send_end_time"`
+	CancelTime    *time.Time     `gorm:"column:cancel_time;comment:取消时间" json:"cancel_time"`
 	CreatedAt     time.Time      `gorm:"column:created_at;autoCreateTime:nano" json:"created_at"`
 	UpdatedAt     time.Time      `gorm:"column:updated_at;autoUpdateTime:nano" json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"column:deleted_at;index" json:"-"`
@@ -36,49 +64,104 @@ type SendBatch struct {
 	Records       []*SendRecord  `gorm:"foreignKey:BatchID" json:"records,omitempty"`
 }
 
+// Editable 异步（含定时）批次开始发送前可以改期或取消
+func (b *SendBatch) Editable() bool {
+	return b.SendStartTime == nil && b.CancelTime == nil
+}
+
 func (b *SendBatch) TableName() string {
 	return "msgbox_send_batches"
 }
 
+// SendBatchChannelStat 批次按通道统计的发送结果，通道为记录最终使用的通道（故障转移后为备用通道）
+type SendBatchChannelStat struct {
+	BatchID      int64  `json:"batch_id"`
+	ChannelID    int64  `json:"channel_id"`
+	VendorName   string `json:"vendor_name"`
+	TotalCount   int    `json:"total_count"`
+	SuccessCount int    `json:"success_count"`
+	FailCount    int    `json:"fail_count"`
+}
+
+// QuerySendBatchChannelStats 按发送记录实时统计批次在各通道的总数、成功（已受理或已送达）与失败条数
+func QuerySendBatchChannelStats(db *gorm.DB, batchIDs []int64) ([]*SendBatchChannelStat, error) {
+	stats := make([]*SendBatchChannelStat, 0)
+	if len(batchIDs) == 0 {
+		return stats, nil
+	}
+	err := db.Model(&SendRecord{}).Scopes(WithoutTestSends).
+		Select("batch_id, channel_id, vendor_name, COUNT(*) AS total_count, "+
+			"SUM(CASE WHEN status IN ? THEN 1 ELSE 0 END) AS success_count, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS fail_count",
+			[]int{SendRecordStatusAccepted, SendRecordStatusDelivered}, SendRecordStatusFailed).
+		Where("batch_id IN ?", batchIDs).
+		Group("batch_id, channel_id, vendor_name").
+		Order("batch_id, channel_id").
+		Scan(&stats).Error
+	return stats, err
+}
+
+// WithoutTestSends 排除管理后台测试发送的记录，测试发送不出现在发送记录列表与统计中
+func WithoutTestSends(db *gorm.DB) *gorm.DB {
+	tests := db.Session(&gorm.Session{NewDB: true}).Model(&SendBatch{}).Select("id").Where("test = ?", true)
+	return db.Where("batch_id NOT IN (?)", tests)
+}
+
 type SendRecord struct {
-	ID            int64          `gorm:"primaryKey;autoIncrement" json:"id"`
-	BatchID       int64          `gorm:"column:batch_id;index;comment:所属批次ID" json:"batch_id"`
-	AgentID       int64          `gorm:"column:agent_id;not null;index;comment:代理商ID" json:"agent_id"`
-	ChannelID     int64          `gorm:"column:channel_id;not null;index;comment:通道ID" json:"channel_id"`
-	TemplateID    int64          `gorm:"column:template_id;not null;comment:模板ID，可空" json:"template_id"`
-	TraceID       string         `gorm:"column:trace_id;size:100;not null;comment:链路ID" json:"trace_id"`
-	Receiver      string         `gorm:"column:receiver;size:100;not null;comment:发送目标（手机号/邮箱）" json:"receiver"`
-	VendorName    string         `gorm:"column:vendor_name;size:50;not null;comment:服务商名称" json:"vendor_name"`
-	ChannelConfig datatypes.JSON `gorm:"column:channel_config;type:JSON;not null;comment:通道配置" json:"channel_config"`
-	VendorCode    string         `gorm:"column:vendor_code;size:100;default:'';comment:厂商模板编码" json:"vendor_code"`
-	Signature     string         `gorm:"column:signature;size:64;default:'';comment:签名" json:"signature"`
-	Title         string         `gorm:"column:title;size:255;default:'';comment:消息标题" json:"title"`
-	Content       string         `gorm:"column:content;type:text;not null;comment:最终发送内容" json:"content"`
-	Variables     datatypes.JSON `gorm:"column:variables;type:json;comment:模板渲染参数" json:"variables"`
-	Extra         datatypes.JSON `gorm:"column:extra;type:json;comment:扩展参数" json:"extra"`
-	Status        int            `gorm:"column:status;not null;default:1;comment:消息状态(1=待发送,2=发送中,3=成功,4=失败)" json:"status"`
-	SendTime      *time.Time     `gorm:"column:send_time;comment:发送动作时间" json:"send_time"`
-	Error         string         `gorm:"column:error;size:255;default:'';comment:错误内容" json:"error"`
-	Response      datatypes.JSON `gorm:"column:response;type:json;comment:服务商原始响应" json:"response"`
-	DeliveryTime  *time.Time     `gorm:"column:delivery_time;comment:回执回调时间" json:"delivery_time"`
-	DeliveryRaw   datatypes.JSON `gorm:"column:delivery_raw;type:json;comment:回执原始内容" json:"delivery_raw"`
-	CreatedAt     time.Time      `gorm:"autoCreateTime:nano" json:"created_at"`
-	UpdatedAt     time.Time      `gorm:"autoUpdateTime:nano" json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
-	Batch         *SendBatch     `gorm:"foreignKey:BatchID" json:"batch,omitempty"`
-	Agent         *Agent         `gorm:"foreignKey:AgentID" json:"agent,omitempty"`
-	Channel       *Channel       `gorm:"foreignKey:ChannelID" json:"channel,omitempty"`
-	Template      *Template      `gorm:"foreignKey:TemplateID" json:"template,omitempty"`
+	ID         int64 `gorm:"primaryKey;autoIncrement" json:"id"`
+	BatchID    int64 `gorm:"column:batch_id;index;comment:所属批次ID" json:"batch_id"`
+	AgentID    int64 `gorm:"column:agent_id;not null;index;comment:代理商ID" json:"agent_id"`
+	ChannelID  int64 `gorm:"column:channel_id;not null;index;comment:通道ID（故障转移后为最终发送的通道）" json:"channel_id"`
+	TemplateID int64 `gorm:"column:template_id;not null;comment:模板ID，可空" json:"template_id"`
+	// TemplateVersionID 发送时使用的模板版本，用于追溯发送内容来自哪一次模板修改
+	TemplateVersionID int64          `gorm:"column:template_version_id;not null;default:0;index;comment:模板版本ID" json:"template_version_id"`
+	TraceID           string         `gorm:"column:trace_id;size:100;not null;comment:链路ID" json:"trace_id"`
+	Receiver          string         `gorm:"column:receiver;size:100;not null;comment:发送目标（手机号/邮箱）" json:"receiver"`
+	VendorName        string         `gorm:"column:vendor_name;size:50;not null;comment:服务商名称" json:"vendor_name"`
+	ChannelConfig     datatypes.JSON `gorm:"column:channel_config;type:JSON;not null;comment:通道配置" json:"channel_config"`
+	// Locale 渲染时选中的模板语言，空表示使用模板的默认内容
+	Locale          string           `gorm:"column:locale;size:35;default:'';comment:模板语言" json:"locale"`
+	VendorCode      string           `gorm:"column:vendor_code;size:100;default:'';comment:厂商模板编码" json:"vendor_code"`
+	Signature       string           `gorm:"column:signature;size:64;default:'';comment:签名" json:"signature"`
+	MsgType         string           `gorm:"column:msg_type;size:32;default:'';comment:消息类型" json:"msg_type"`
+	Title           string           `gorm:"column:title;size:255;default:'';comment:消息标题" json:"title"`
+	Content         string           `gorm:"column:content;type:text;not null;comment:最终发送内容" json:"content"`
+	Variables       datatypes.JSON   `gorm:"column:variables;type:json;comment:模板渲染参数" json:"variables"`
+	Extra           datatypes.JSON   `gorm:"column:extra;type:json;comment:扩展参数" json:"extra"`
+	Status          int              `gorm:"column:status;not null;default:1;index:idx_queue;comment:消息状态(1=待发送,2=发送中,3=已受理,4=失败,5=已取消,6=已送达)" json:"status"`
+	QueueTime       *time.Time       `gorm:"column:queue_time;index:idx_queue;comment:入队时间（异步发送时 worker 可领取的最早时间，为空表示同步发送）" json:"queue_time"`
+	LockedUntil     *time.Time       `gorm:"column:locked_until;comment:worker 领取锁过期时间" json:"locked_until"`
+	SendTime        *time.Time       `gorm:"column:send_time;comment:发送动作时间" json:"send_time"`
+	Attempts        int              `gorm:"column:attempts;not null;default:0;comment:已发送次数" json:"attempts"`
+	NextAttemptTime *time.Time       `gorm:"column:next_attempt_time;comment:下次重试时间" json:"next_attempt_time"`
+	Error           string           `gorm:"column:error;size:255;default:'';comment:错误内容" json:"error"`
+	FailReason      string           `gorm:"column:fail_reason;size:32;default:'';comment:失败原因分类(sender/vendor/timeout/cancel/network/server/rate_limit/receipt)" json:"fail_reason"`
+	Response        datatypes.JSON   `gorm:"column:response;type:json;comment:服务商原始响应" json:"response"`
+	VendorMsgID     string           `gorm:"column:vendor_msg_id;size:100;default:'';index;comment:服务商消息ID，用于匹配回执" json:"vendor_msg_id"`
+	DeliveryTime    *time.Time       `gorm:"column:delivery_time;comment:回执回调时间" json:"delivery_time"`
+	DeliveryRaw     datatypes.JSON   `gorm:"column:delivery_raw;type:json;comment:回执原始内容" json:"delivery_raw"`
+	CreatedAt       time.Time        `gorm:"autoCreateTime:nano" json:"created_at"`
+	UpdatedAt       time.Time        `gorm:"autoUpdateTime:nano" json:"updated_at"`
+	DeletedAt       gorm.DeletedAt   `gorm:"index" json:"-"`
+	Batch           *SendBatch       `gorm:"foreignKey:BatchID" json:"batch,omitempty"`
+	Agent           *Agent           `gorm:"foreignKey:AgentID" json:"agent,omitempty"`
+	Channel         *Channel         `gorm:"foreignKey:ChannelID" json:"channel,omitempty"`
+	Template        *Template        `gorm:"foreignKey:TemplateID" json:"template,omitempty"`
+	TemplateVersion *TemplateVersion `gorm:"foreignKey:TemplateVersionID" json:"template_version,omitempty"`
 }
 
 func (sr *SendRecord) StatusMsg() string {
 	switch sr.Status {
 	case SendRecordStatusSending:
 		return "发送中"
-	case SendRecordStatusSuccess:
-		return "成功"
+	case SendRecordStatusAccepted:
+		return "已受理"
+	case SendRecordStatusDelivered:
+		return "已送达"
 	case SendRecordStatusFailed:
 		return "失败"
+	case SendRecordStatusCancel:
+		return "已取消"
 	default:
 		return "待发送"
 	}
@@ -96,6 +179,14 @@ func (sr *SendRecord) GetVendorCode() string {
 	return sr.VendorCode
 }
 
+// GetMsgType 消息类型，请求 extra.msg_type 优先于模板配置
+func (sr *SendRecord) GetMsgType() string {
+	if msgType, ok := sr.GetExtra()["msg_type"].(string); ok && msgType != "" {
+		return msgType
+	}
+	return sr.MsgType
+}
+
 func (sr *SendRecord) GetTitle() string {
 	return sr.Title
 }
@@ -112,6 +203,13 @@ func (sr *SendRecord) GetExtra() map[string]any {
 	return DataTypesToMap(sr.Extra)
 }
 
+// MentionKey 合并提醒的分组键：同一批次、同一通道且渲染后的标题、内容、消息类型、签名、服务商编码与扩展参数
+// 都相同的记录才能合并为一条群消息，接收者各自的变量或语言导致内容不同时分别发送
+func (sr *SendRecord) MentionKey() string {
+	b, _ := json.Marshal([]any{sr.BatchID, sr.ChannelID, sr.Title, sr.Content, sr.GetMsgType(), sr.Signature, sr.VendorCode, sr.GetExtra()})
+	return string(b)
+}
+
 func (sr *SendRecord) TableName() string {
 	return "msgbox_send_records"
 }
@@ -0,0 +1,20 @@
+package models
+
+import "testing"
+
+// TestSendRecordStatusValues 状态取值已写入历史数据，调整取值需同时迁移数据
+func TestSendRecordStatusValues(t *testing.T) {
+	values := map[string][2]int{
+		"pending":   {SendRecordStatusPending, 1},
+		"sending":   {SendRecordStatusSending, 2},
+		"accepted":  {SendRecordStatusAccepted, 3},
+		"failed":    {SendRecordStatusFailed, 4},
+		"cancel":    {SendRecordStatusCancel, 5},
+		"delivered": {SendRecordStatusDelivered, 6},
+	}
+	for name, v := range values {
+		if v[0] != v[1] {
+			t.Errorf("%s status = %d, want %d", name, v[0], v[1])
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// sendRecordTransitions 发送记录允许的状态转换，所有状态更新都需经过 TransitionSendRecords
+//
+//	待发送 → 发送中 → 已受理 → 已送达
+//	   ↓        ↓         ↓
+//	已取消   失败/待发送   失败
+//
+// 发送中 → 待发送 为失败后按重试策略重新入队
+var sendRecordTransitions = map[int][]int{
+	SendRecordStatusPending:  {SendRecordStatusSending, SendRecordStatusCancel},
+	SendRecordStatusSending:  {SendRecordStatusAccepted, SendRecordStatusFailed, SendRecordStatusPending},
+	SendRecordStatusAccepted: {SendRecordStatusDelivered, SendRecordStatusFailed},
+}
+
+// CanTransition 判断发送记录能否从 from 状态转换到 to 状态
+func CanTransition(from, to int) bool {
+	for _, next := range sendRecordTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionSources 可以转换到 to 状态的全部来源状态
+func transitionSources(to int) []int {
+	sources := make([]int, 0)
+	for from := range sendRecordTransitions {
+		if CanTransition(from, to) {
+			sources = append(sources, from)
+		}
+	}
+	return sources
+}
+
+// TransitionSendRecords 将记录转换到 to 状态并更新其余字段，
+// 仅更新当前状态允许转换到 to 的记录，返回实际更新条数；并发处理同一记录时只有一方会成功
+func TransitionSendRecords(db *gorm.DB, ids []int64, to int, updates map[string]interface{}) (int64, error) {
+	sources := transitionSources(to)
+	if len(sources) == 0 {
+		return 0, fmt.Errorf("send record status %d is not reachable", to)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	values := map[string]interface{}{"status": to}
+	for key, value := range updates {
+		values[key] = value
+	}
+	result := db.Model(&SendRecord{}).Where("id IN ? AND status IN ?", ids, sources).Updates(values)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,136 @@
+package models_test
+
+import (
+	"testing"
+
+	"chihqiang/msgbox-go/services/common/models"
+	"chihqiang/msgbox-go/services/common/models/modeltest"
+
+	"gorm.io/datatypes"
+)
+
+var statuses = []int{
+	models.SendRecordStatusPending,
+	models.SendRecordStatusSending,
+	models.SendRecordStatusAccepted,
+	models.SendRecordStatusFailed,
+	models.SendRecordStatusCancel,
+	models.SendRecordStatusDelivered,
+}
+
+func TestCanTransition(t *testing.T) {
+	allowed := map[[2]int]bool{
+		{models.SendRecordStatusPending, models.SendRecordStatusSending}:    true,
+		{models.SendRecordStatusPending, models.SendRecordStatusCancel}:     true,
+		{models.SendRecordStatusSending, models.SendRecordStatusAccepted}:   true,
+		{models.SendRecordStatusSending, models.SendRecordStatusFailed}:     true,
+		{models.SendRecordStatusSending, models.SendRecordStatusPending}:    true,
+		{models.SendRecordStatusAccepted, models.SendRecordStatusDelivered}: true,
+		{models.SendRecordStatusAccepted, models.SendRecordStatusFailed}:    true,
+	}
+	// 逐一检查全部状态组合，失败、已取消、已送达为终态
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := allowed[[2]int{from, to}]
+			if got := models.CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%d, %d) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
+
+func TestTransitionSendRecords(t *testing.T) {
+	tests := []struct {
+		name      string
+		from      []int
+		to        int
+		wantRows  int64
+		wantAfter []int
+		wantErr   bool
+	}{
+		{
+			name:      "only sending records become accepted",
+			from:      []int{models.SendRecordStatusPending, models.SendRecordStatusSending, models.SendRecordStatusCancel},
+			to:        models.SendRecordStatusAccepted,
+			wantRows:  1,
+			wantAfter: []int{models.SendRecordStatusPending, models.SendRecordStatusAccepted, models.SendRecordStatusCancel},
+		},
+		{
+			name:      "failed from sending or accepted",
+			from:      []int{models.SendRecordStatusSending, models.SendRecordStatusAccepted, models.SendRecordStatusDelivered},
+			to:        models.SendRecordStatusFailed,
+			wantRows:  2,
+			wantAfter: []int{models.SendRecordStatusFailed, models.SendRecordStatusFailed, models.SendRecordStatusDelivered},
+		},
+		{
+			name:      "terminal states are kept",
+			from:      []int{models.SendRecordStatusFailed, models.SendRecordStatusCancel, models.SendRecordStatusDelivered},
+			to:        models.SendRecordStatusPending,
+			wantRows:  0,
+			wantAfter: []int{models.SendRecordStatusFailed, models.SendRecordStatusCancel, models.SendRecordStatusDelivered},
+		},
+		{
+			name:      "unreachable status",
+			from:      []int{models.SendRecordStatusPending},
+			to:        0,
+			wantAfter: []int{models.SendRecordStatusPending},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := modeltest.NewDB(t)
+			ids := make([]int64, 0, len(tt.from))
+			for _, status := range tt.from {
+				record := &models.SendRecord{AgentID: 1, ChannelID: 1, TraceID: "T1", Receiver: "u1", VendorName: "webhook",
+					ChannelConfig: datatypes.JSON(`{}`), Content: "hi", Status: status}
+				if err := db.Create(record).Error; err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, record.ID)
+			}
+			rows, err := models.TransitionSendRecords(db, ids, tt.to, map[string]interface{}{"error": "changed"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if rows != tt.wantRows {
+				t.Errorf("rows = %d, want %d", rows, tt.wantRows)
+			}
+			var records []*models.SendRecord
+			if err := db.Where("id IN ?", ids).Order("id").Find(&records).Error; err != nil {
+				t.Fatal(err)
+			}
+			for i, record := range records {
+				if record.Status != tt.wantAfter[i] {
+					t.Errorf("record %d status = %d, want %d", i, record.Status, tt.wantAfter[i])
+				}
+				// 其余字段只随状态转换一起更新
+				if changed := record.Error == "changed"; changed != (record.Status != tt.from[i]) {
+					t.Errorf("record %d error = %q with status %d -> %d", i, record.Error, tt.from[i], record.Status)
+				}
+			}
+		})
+	}
+}
+
+// TestTransitionSendRecordsOnce 并发处理同一记录时只有一方转换成功
+func TestTransitionSendRecordsOnce(t *testing.T) {
+	db := modeltest.NewDB(t)
+	record := &models.SendRecord{AgentID: 1, ChannelID: 1, TraceID: "T1", Receiver: "u1", VendorName: "webhook",
+		ChannelConfig: datatypes.JSON(`{}`), Content: "hi", Status: models.SendRecordStatusPending}
+	if err := db.Create(record).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []int64{1, 0} {
+		rows, err := models.TransitionSendRecords(db, []int64{record.ID}, models.SendRecordStatusSending, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rows != want {
+			t.Errorf("attempt %d rows = %d, want %d", i+1, rows, want)
+		}
+	}
+	if rows, err := models.TransitionSendRecords(db, nil, models.SendRecordStatusSending, nil); rows != 0 || err != nil {
+		t.Errorf("empty ids = %d, %v", rows, err)
+	}
+}
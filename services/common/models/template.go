@@ -1,26 +1,65 @@
 package models
 
 import (
+	"chihqiang/msgbox-go/pkg/templatex"
+	"fmt"
+
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 	"time"
 )
 
 type Template struct {
-	ID         int64          `gorm:"primaryKey;autoIncrement" json:"id"`
-	AgentID    int64          `gorm:"column:agent_id;uniqueIndex:idx_agent_code;not null;comment:代理商ID" json:"agent_id"`
-	ChannelID  int64          `gorm:"column:channel_id;not null;comment:所属通道ID" json:"channel_id"`
-	Name       string         `gorm:"column:name;size:100;not null;comment:模版名称" json:"name"`
-	Code       string         `gorm:"column:code;uniqueIndex:idx_agent_code;size:50;not null;comment:模版编码" json:"code"`
-	VendorCode string         `gorm:"column:vendor_code;size:100;default:'';comment:厂商模板编码" json:"vendor_code"`
-	Signature  string         `gorm:"column:signature;size:64;default:'';comment:签名" json:"signature"`
-	Content    string         `gorm:"column:content;type:text;not null;comment:模板内容（含变量占位符）" json:"content"`
-	Status     bool           `gorm:"column:status;not null;default:true;comment:是否启用（true=启用，false=禁用）" json:"status"`
-	UsedCount  int64          `gorm:"column:used_count;default:0;comment:使用次数" json:"used_count"`
-	CreatedAt  time.Time      `gorm:"autoCreateTime:nano" json:"created_at"`
-	UpdatedAt  time.Time      `gorm:"autoUpdateTime:nano" json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+	ID        int64 `gorm:"primaryKey;autoIncrement" json:"id"`
+	AgentID   int64 `gorm:"column:agent_id;uniqueIndex:idx_agent_code;not null;comment:代理商ID" json:"agent_id"`
+	ChannelID int64 `gorm:"column:channel_id;not null;comment:所属通道ID" json:"channel_id"`
+	// FallbackChannelIDs 备用通道，主通道发送失败或被禁用时按顺序依次尝试
+	FallbackChannelIDs datatypes.JSONSlice[int64] `gorm:"column:fallback_channel_ids;type:json;comment:备用通道ID（按顺序故障转移）" json:"fallback_channel_ids"`
+	Name               string                     `gorm:"column:name;size:100;not null;comment:模版名称" json:"name"`
+	Code               string                     `gorm:"column:code;uniqueIndex:idx_agent_code;size:50;not null;comment:模版编码" json:"code"`
+	VendorCode         string                     `gorm:"column:vendor_code;size:100;default:'';comment:厂商模板编码" json:"vendor_code"`
+	Signature          string                     `gorm:"column:signature;size:64;default:'';comment:签名" json:"signature"`
+	MsgType            string                     `gorm:"column:msg_type;size:32;default:'';comment:消息类型（text/markdown/link 等，空=text）" json:"msg_type"`
+	Title              string                     `gorm:"column:title;size:255;default:'';comment:消息标题（含变量占位符）" json:"title"`
+	Content            string                     `gorm:"column:content;type:text;not null;comment:模板内容（含变量占位符）" json:"content"`
+	// Variables 变量声明，保存时从标题与内容中提取，发送前按声明校验请求变量
+	Variables datatypes.JSONSlice[TemplateVariable] `gorm:"column:variables;type:json;comment:变量声明" json:"variables"`
+	// VersionID 当前版本，修改发送内容时生成新版本，发送记录保存发送时的版本
+	VersionID int64          `gorm:"column:version_id;not null;default:0;comment:当前版本ID" json:"version_id"`
+	Status    bool           `gorm:"column:status;not null;default:true;comment:是否启用（true=启用，false=禁用）" json:"status"`
+	UsedCount int64          `gorm:"column:used_count;default:0;comment:使用次数" json:"used_count"`
+	CreatedAt time.Time      `gorm:"autoCreateTime:nano" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime:nano" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Channel        *Channel          `json:"channel,omitempty"`
+	Fanouts        []*TemplateFanout `gorm:"foreignKey:TemplateID" json:"fanouts,omitempty"`
+	Locales        []*TemplateLocale `gorm:"foreignKey:TemplateID" json:"locales,omitempty"`
+	CurrentVersion *TemplateVersion  `gorm:"foreignKey:VersionID" json:"current_version,omitempty"`
+}
+
+// ChannelIDs 主通道与备用通道，按故障转移顺序排列并去重
+func (t *Template) ChannelIDs() []int64 {
+	ids := []int64{t.ChannelID}
+	seen := map[int64]bool{t.ChannelID: true}
+	for _, id := range t.FallbackChannelIDs {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
 
-	Channel *Channel `json:"channel,omitempty"`
+// Render 使用变量渲染标题与内容，内容前拼接签名，结果与发送记录保存的标题、内容一致
+func (t *Template) Render(variables map[string]any) (title string, content string, err error) {
+	if title, err = templatex.Render(t.Title, variables); err != nil {
+		return "", "", fmt.Errorf("title %w", err)
+	}
+	if content, err = templatex.Render(t.Content, variables); err != nil {
+		return "", "", fmt.Errorf("content %w", err)
+	}
+	return title, t.Signature + content, nil
 }
 
 func (t Template) TableName() string {
@@ -0,0 +1,234 @@
+package models
+
+import (
+	"chihqiang/msgbox-go/pkg/templatex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 模板变量类型
+const (
+	VariableTypeString  = templatex.VariableTypeString
+	VariableTypeNumber  = "number"
+	VariableTypeBoolean = "boolean"
+	VariableTypeList    = templatex.VariableTypeList
+	VariableTypeObject  = templatex.VariableTypeObject
+)
+
+var variableTypes = map[string]bool{
+	VariableTypeString:  true,
+	VariableTypeNumber:  true,
+	VariableTypeBoolean: true,
+	VariableTypeList:    true,
+	VariableTypeObject:  true,
+}
+
+// TemplateVariable 模板变量声明，保存模板时从标题与内容中提取，可在管理后台修改类型与校验规则
+type TemplateVariable struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`       // string、number、boolean、list、object
+	Required    bool   `json:"required"`   // 必填，缺失或为空字符串时拒绝请求
+	Pattern     string `json:"pattern"`    // 正则，校验字符串、数字与布尔值
+	MinLength   int    `json:"min_length"` // 字符串的最小字符数或列表的最少元素数
+	MaxLength   int    `json:"max_length"` // 字符串的最大字符数或列表的最多元素数，0 表示不限制
+	Description string `json:"description"`
+	// Manual 手动添加的变量，模板内容与通道配置均未引用时保存模板也不会移除，用于短信服务商模板参数等只在服务商侧使用的变量
+	Manual bool `json:"manual"`
+}
+
+// Validate 校验声明本身是否合法
+func (v TemplateVariable) Validate() error {
+	if !variableTypes[v.Type] {
+		return fmt.Errorf("变量 %s 的类型 %q 无效", v.Name, v.Type)
+	}
+	if v.Pattern != "" {
+		if _, err := regexp.Compile(v.Pattern); err != nil {
+			return fmt.Errorf("变量 %s 的正则无效: %v", v.Name, err)
+		}
+	}
+	if v.MinLength < 0 || v.MaxLength < 0 || (v.MaxLength > 0 && v.MinLength > v.MaxLength) {
+		return fmt.Errorf("变量 %s 的长度限制无效", v.Name)
+	}
+	return nil
+}
+
+// Check 校验请求中的变量值，exists 为 false 表示请求未传该变量
+func (v TemplateVariable) Check(value any, exists bool) error {
+	if !exists || value == nil || value == "" {
+		if v.Required {
+			return fmt.Errorf("variable %s is required", v.Name)
+		}
+		return nil
+	}
+	var size int
+	switch v.Type {
+	case VariableTypeNumber:
+		if _, ok := variableNumber(value); !ok {
+			return fmt.Errorf("variable %s must be a number", v.Name)
+		}
+	case VariableTypeBoolean:
+		if _, ok := variableBool(value); !ok {
+			return fmt.Errorf("variable %s must be a boolean", v.Name)
+		}
+	case VariableTypeList:
+		list, ok := variableList(value)
+		if !ok {
+			return fmt.Errorf("variable %s must be a list", v.Name)
+		}
+		size = len(list)
+	case VariableTypeObject:
+		if _, ok := value.(map[string]any); !ok {
+			return fmt.Errorf("variable %s must be an object", v.Name)
+		}
+	default:
+		switch value.(type) {
+		case map[string]any, []any:
+			return fmt.Errorf("variable %s must be a string", v.Name)
+		}
+	}
+	if v.Type != VariableTypeList && v.Type != VariableTypeObject {
+		s := fmt.Sprint(value)
+		if f, ok := value.(float64); ok {
+			s = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		size = len([]rune(s))
+		if v.Pattern != "" {
+			re, err := regexp.Compile(v.Pattern)
+			if err != nil {
+				return fmt.Errorf("variable %s has an invalid pattern", v.Name)
+			}
+			if !re.MatchString(s) {
+				return fmt.Errorf("variable %s does not match pattern %s", v.Name, v.Pattern)
+			}
+		}
+	}
+	if v.Type == VariableTypeObject {
+		return nil
+	}
+	if size < v.MinLength {
+		return fmt.Errorf("variable %s is shorter than %d", v.Name, v.MinLength)
+	}
+	if v.MaxLength > 0 && size > v.MaxLength {
+		return fmt.Errorf("variable %s is longer than %d", v.Name, v.MaxLength)
+	}
+	return nil
+}
+
+// variableNumber 数字或数字字符串
+func variableNumber(value any) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case int:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// variableBool 布尔值或 true/false/1/0 字符串
+func variableBool(value any) (bool, bool) {
+	switch value := value.(type) {
+	case bool:
+		return value, true
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		return b, err == nil
+	}
+	return false, false
+}
+
+// variableList 列表或 JSON 数组字符串
+func variableList(value any) ([]any, bool) {
+	switch value := value.(type) {
+	case []any:
+		return value, true
+	case string:
+		var list []any
+		err := json.Unmarshal([]byte(value), &list)
+		return list, err == nil
+	}
+	return nil, false
+}
+
+// CheckVariables 按声明校验请求变量，未声明的变量同样拒绝，避免短信服务商因多余参数拒绝发送；
+// 只在服务商模板或通道配置中使用的变量需在声明中手动添加或由通道配置提取。模板没有变量声明时不校验
+func CheckVariables(schema []TemplateVariable, values map[string]any) error {
+	if len(schema) == 0 {
+		return nil
+	}
+	declared := make(map[string]bool, len(schema))
+	for _, v := range schema {
+		declared[v.Name] = true
+		value, exists := values[v.Name]
+		if err := v.Check(value, exists); err != nil {
+			return err
+		}
+	}
+	for name := range values {
+		if !declared[name] {
+			return fmt.Errorf("variable %s is not declared by the template", name)
+		}
+	}
+	return nil
+}
+
+// ExtractVariables 从模板标题与内容中提取变量声明，并合并 refs 中模板之外引用的变量（如 webhook 请求体模板），
+// declared 中同名变量的类型与校验规则保持不变；未被引用的声明会被移除，手动添加的变量除外；
+// 新出现的变量按用法推断类型与是否必填
+func ExtractVariables(declared []TemplateVariable, refs []templatex.Variable, sources ...string) ([]TemplateVariable, error) {
+	existing := make(map[string]TemplateVariable, len(declared))
+	for _, v := range declared {
+		existing[v.Name] = v
+	}
+	all := make([]templatex.Variable, 0, len(refs))
+	for _, source := range sources {
+		t, err := templatex.Parse(source)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, t.Variables()...)
+	}
+	all = append(all, refs...)
+	vars := make([]TemplateVariable, 0)
+	index := make(map[string]int)
+	for _, ref := range all {
+		if i, ok := index[ref.Name]; ok {
+			if _, ok := existing[ref.Name]; !ok {
+				vars[i].Required = vars[i].Required || ref.Required
+			}
+			continue
+		}
+		v, ok := existing[ref.Name]
+		if !ok {
+			v = TemplateVariable{Name: ref.Name, Type: ref.Type, Required: ref.Required}
+		}
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+		index[ref.Name] = len(vars)
+		vars = append(vars, v)
+	}
+	for _, v := range declared {
+		if _, ok := index[v.Name]; ok || !v.Manual {
+			continue
+		}
+		if strings.TrimSpace(v.Name) == "" {
+			return nil, errors.New("手动添加的变量名不能为空")
+		}
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+		index[v.Name] = len(vars)
+		vars = append(vars, v)
+	}
+	return vars, nil
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"chihqiang/msgbox-go/pkg/templatex"
+)
+
+func TestExtractVariables(t *testing.T) {
+	tests := []struct {
+		name     string
+		declared []TemplateVariable
+		refs     []templatex.Variable
+		sources  []string
+		want     []TemplateVariable
+		wantErr  string
+	}{
+		{
+			name:    "inferred from content",
+			sources: []string{"${title}", "hi ${name}${if vip}${user.level}${end}${for item in items}${item}${end}"},
+			want: []TemplateVariable{
+				{Name: "title", Type: VariableTypeString, Required: true},
+				{Name: "name", Type: VariableTypeString, Required: true},
+				{Name: "vip", Type: VariableTypeString},
+				{Name: "user", Type: VariableTypeObject},
+				{Name: "items", Type: VariableTypeList},
+			},
+		},
+		{
+			name:     "declared rules kept and unreferenced removed",
+			declared: []TemplateVariable{{Name: "name", Type: VariableTypeString, Pattern: "^a", MaxLength: 5}, {Name: "old", Type: VariableTypeNumber}},
+			sources:  []string{"hi ${name}"},
+			want:     []TemplateVariable{{Name: "name", Type: VariableTypeString, Pattern: "^a", MaxLength: 5}},
+		},
+		{
+			// 通道配置（如 webhook 请求体模板）引用的变量与内容中的变量合并，同名变量任一处必填即为必填
+			name:    "channel refs merged",
+			refs:    []templatex.Variable{{Name: "name", Type: VariableTypeString, Required: true}, {Name: "order_id", Type: VariableTypeString, Required: true}},
+			sources: []string{"${if name}${name}${end}"},
+			want: []TemplateVariable{
+				{Name: "name", Type: VariableTypeString, Required: true},
+				{Name: "order_id", Type: VariableTypeString, Required: true},
+			},
+		},
+		{
+			name: "manual variables kept",
+			declared: []TemplateVariable{
+				{Name: "code", Type: VariableTypeNumber, Required: true, Manual: true},
+				{Name: "name", Type: VariableTypeString, Manual: true},
+			},
+			sources: []string{"${name}"},
+			want: []TemplateVariable{
+				{Name: "name", Type: VariableTypeString, Manual: true},
+				{Name: "code", Type: VariableTypeNumber, Required: true, Manual: true},
+			},
+		},
+		{
+			name:     "manual variable without name",
+			declared: []TemplateVariable{{Name: " ", Type: VariableTypeString, Manual: true}},
+			wantErr:  "变量名不能为空",
+		},
+		{
+			name:     "manual variable with invalid type",
+			declared: []TemplateVariable{{Name: "code", Type: "date", Manual: true}},
+			wantErr:  "类型",
+		},
+		{
+			name:    "syntax error",
+			sources: []string{"${name"},
+			wantErr: "unclosed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractVariables(tt.declared, tt.refs, tt.sources...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("variable %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckVariables(t *testing.T) {
+	schema := []TemplateVariable{
+		{Name: "name", Type: VariableTypeString, Required: true, MaxLength: 5},
+		{Name: "code", Type: VariableTypeNumber, Manual: true},
+		{Name: "tags", Type: VariableTypeList, MinLength: 1},
+	}
+	tests := []struct {
+		name    string
+		schema  []TemplateVariable
+		values  map[string]any
+		wantErr string
+	}{
+		{"valid", schema, map[string]any{"name": "Tom", "code": "1234", "tags": []any{"a"}}, ""},
+		{"missing required", schema, map[string]any{"code": 1}, "variable name is required"},
+		{"too long", schema, map[string]any{"name": "Thomas"}, "variable name is longer than 5"},
+		{"not a number", schema, map[string]any{"name": "Tom", "code": "x"}, "variable code must be a number"},
+		{"list too short", schema, map[string]any{"name": "Tom", "tags": "[]"}, "variable tags is shorter than 1"},
+		{"undeclared", schema, map[string]any{"name": "Tom", "extra": "x"}, "variable extra is not declared"},
+		{"no schema", nil, map[string]any{"anything": "x"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckVariables(tt.schema, tt.values)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("err = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// TemplateVersionFanout 版本快照中的扇出通道
+type TemplateVersionFanout struct {
+	ChannelID  int64  `json:"channel_id"`
+	VendorCode string `json:"vendor_code"`
+	Signature  string `json:"signature"`
+	MsgType    string `json:"msg_type"`
+	Title      string `json:"title"`
+	Content    string `json:"content"`
+}
+
+// TemplateVersionLocale 版本快照中的语言变体
+type TemplateVersionLocale struct {
+	ChannelID  int64  `json:"channel_id"`
+	Locale     string `json:"locale"`
+	VendorCode string `json:"vendor_code"`
+	Signature  string `json:"signature"`
+	Title      string `json:"title"`
+	Content    string `json:"content"`
+}
+
+// TemplateVersion 模板版本，每次修改模板的发送内容都会新增一条不可修改的快照，
+// 发送记录通过 TemplateVersionID 指向发送时使用的版本
+type TemplateVersion struct {
+	ID                 int64                                      `gorm:"primaryKey;autoIncrement" json:"id"`
+	AgentID            int64                                      `gorm:"column:agent_id;not null;index;comment:代理商ID" json:"agent_id"`
+	TemplateID         int64                                      `gorm:"column:template_id;not null;uniqueIndex:idx_template_version;comment:模板ID" json:"template_id"`
+	Version            int                                        `gorm:"column:version;not null;uniqueIndex:idx_template_version;comment:版本号（模板内从 1 递增）" json:"version"`
+	ChannelID          int64                                      `gorm:"column:channel_id;not null;comment:主通道ID" json:"channel_id"`
+	FallbackChannelIDs datatypes.JSONSlice[int64]                 `gorm:"column:fallback_channel_ids;type:json;comment:备用通道ID" json:"fallback_channel_ids"`
+	Fanouts            datatypes.JSONSlice[TemplateVersionFanout] `gorm:"column:fanouts;type:json;comment:扇出通道" json:"fanouts"`
+	Locales            datatypes.JSONSlice[TemplateVersionLocale] `gorm:"column:locales;type:json;comment:语言变体" json:"locales"`
+	VendorCode         string                                     `gorm:"column:vendor_code;size:100;default:'';comment:厂商模板编码" json:"vendor_code"`
+	Signature          string                                     `gorm:"column:signature;size:64;default:'';comment:签名" json:"signature"`
+	MsgType            string                                     `gorm:"column:msg_type;size:32;default:'';comment:消息类型" json:"msg_type"`
+	Title              string                                     `gorm:"column:title;size:255;default:'';comment:消息标题" json:"title"`
+	Content            string                                     `gorm:"column:content;type:text;not null;comment:模板内容" json:"content"`
+	Variables          datatypes.JSONSlice[TemplateVariable]      `gorm:"column:variables;type:json;comment:变量声明" json:"variables"`
+	AuthorID           int64                                      `gorm:"column:author_id;not null;default:0;comment:修改人（代理商ID，0=系统）" json:"author_id"`
+	Author             string                                     `gorm:"column:author;size:100;default:'';comment:修改人名称" json:"author"`
+	Remark             string                                     `gorm:"column:remark;size:255;default:'';comment:版本说明" json:"remark"`
+	CreatedAt          time.Time                                  `gorm:"autoCreateTime:nano" json:"created_at"`
+}
+
+func (v TemplateVersion) TableName() string {
+	return "msgbox_template_versions"
+}
+
+// NewTemplateVersion 以模板当前的发送内容生成版本快照，模板需预加载 Fanouts 与 Locales
+func NewTemplateVersion(t *Template) *TemplateVersion {
+	fanouts := make([]TemplateVersionFanout, 0, len(t.Fanouts))
+	for _, fanout := range t.Fanouts {
+		fanouts = append(fanouts, TemplateVersionFanout{
+			ChannelID:  fanout.ChannelID,
+			VendorCode: fanout.VendorCode,
+			Signature:  fanout.Signature,
+			MsgType:    fanout.MsgType,
+			Title:      fanout.Title,
+			Content:    fanout.Content,
+		})
+	}
+	locales := make([]TemplateVersionLocale, 0, len(t.Locales))
+	for _, locale := range t.Locales {
+		locales = append(locales, TemplateVersionLocale{
+			ChannelID:  locale.ChannelID,
+			Locale:     locale.Locale,
+			VendorCode: locale.VendorCode,
+			Signature:  locale.Signature,
+			Title:      locale.Title,
+			Content:    locale.Content,
+		})
+	}
+	fallbackChannelIDs := t.FallbackChannelIDs
+	if fallbackChannelIDs == nil {
+		fallbackChannelIDs = []int64{}
+	}
+	variables := t.Variables
+	if variables == nil {
+		variables = []TemplateVariable{}
+	}
+	return &TemplateVersion{
+		AgentID:            t.AgentID,
+		TemplateID:         t.ID,
+		ChannelID:          t.ChannelID,
+		FallbackChannelIDs: fallbackChannelIDs,
+		Fanouts:            fanouts,
+		Locales:            locales,
+		VendorCode:         t.VendorCode,
+		Signature:          t.Signature,
+		MsgType:            t.MsgType,
+		Title:              t.Title,
+		Content:            t.Content,
+		Variables:          variables,
+	}
+}
+
+// snapshot 版本中影响发送结果的字段，用于判断两个版本内容是否相同
+func (v *TemplateVersion) snapshot() string {
+	// 语言变体上线前的版本没有 locales 字段，按空列表比较
+	locales := v.Locales
+	if locales == nil {
+		locales = []TemplateVersionLocale{}
+	}
+	b, _ := json.Marshal([]any{v.ChannelID, v.FallbackChannelIDs, v.Fanouts, locales, v.VendorCode, v.Signature, v.MsgType, v.Title, v.Content, v.Variables})
+	return string(b)
+}
+
+// SaveTemplateVersion 模板发送内容与最新版本不同时写入新版本，并将模板的当前版本指向它；
+// 内容未变化时返回最新版本。模板需预加载 Fanouts 与 Locales
+func SaveTemplateVersion(db *gorm.DB, t *Template, authorID int64, author, remark string) (*TemplateVersion, error) {
+	version := NewTemplateVersion(t)
+	version.AuthorID = authorID
+	version.Author = author
+	version.Remark = remark
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var latest TemplateVersion
+		err := tx.Where(&TemplateVersion{TemplateID: t.ID}).Order("version DESC").First(&latest).Error
+		switch {
+		case err == nil:
+			if latest.snapshot() == version.snapshot() {
+				version = &latest
+				return tx.Model(t).UpdateColumn("version_id", latest.ID).Error
+			}
+			version.Version = latest.Version + 1
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			version.Version = 1
+		default:
+			return err
+		}
+		if err := tx.Create(version).Error; err != nil {
+			return err
+		}
+		return tx.Model(t).UpdateColumn("version_id", version.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.VersionID = version.ID
+	return version, nil
+}
+
+// EnsureTemplateVersion 返回模板的当前版本ID，升级前创建、尚无版本的模板以当前内容生成初始版本
+func EnsureTemplateVersion(db *gorm.DB, t *Template) (int64, error) {
+	if t.VersionID > 0 {
+		return t.VersionID, nil
+	}
+	version, err := SaveTemplateVersion(db, t, 0, "", "初始版本")
+	if err != nil {
+		return 0, err
+	}
+	return version.ID, nil
+}
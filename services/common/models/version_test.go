@@ -0,0 +1,36 @@
+package models_test
+
+import (
+	"testing"
+
+	"chihqiang/msgbox-go/services/common/models"
+	"chihqiang/msgbox-go/services/common/models/modeltest"
+)
+
+// TestBackfillTemplateVersions 迁移时为尚无版本的模板生成初始版本，重复迁移不会再生成
+func TestBackfillTemplateVersions(t *testing.T) {
+	db := modeltest.NewDB(t)
+	template := &models.Template{AgentID: 1, ChannelID: 1, Name: "notice", Code: "notice", Content: "hi", Status: true}
+	if err := db.Create(template).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := models.Migrate(db); err != nil {
+			t.Fatal(err)
+		}
+		var saved models.Template
+		if err := db.Preload("CurrentVersion").First(&saved, template.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if saved.CurrentVersion == nil || saved.CurrentVersion.Version != 1 || saved.CurrentVersion.Content != "hi" {
+			t.Fatalf("current version = %+v", saved.CurrentVersion)
+		}
+		var count int64
+		if err := db.Model(&models.TemplateVersion{}).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Errorf("migrate %d: versions = %d, want 1", i+1, count)
+		}
+	}
+}
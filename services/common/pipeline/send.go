@@ -7,6 +7,7 @@ import (
 	"chihqiang/msgbox-go/services/common/pipeline/tasks"
 	"context"
 	"fmt"
+	"maps"
 	"time"
 
 	"github.com/zeromicro/go-zero/core/logx"
@@ -24,17 +25,24 @@ type SendPipeline struct {
 	AgentSecret  string
 	TemplateCode string
 	Receivers    []string
-	Variables    map[string]string
+	Variables    map[string]interface{}
 	Extra        map[string]interface{}
+	Locale       string               // 请求级语言，Items 中未指定语言的接收者使用该语言
+	Items        []tasks.ReceiverItem // 个性化接收者，变量与扩展参数覆盖批次级的同名参数
+	MaxBatchSize int                  // 单次请求的最大接收者数量（Receivers 与 Items 合计），0 表示不限制
+	Async        bool                 // 异步发送：Check 后记录入队，由 worker 发送，无需调用 Send
+	SendAt       *time.Time           // 计划发送时间，设置后按异步发送处理，到期由 worker 发送
 	sendBatch    *models.SendBatch
 }
 
 func (p *SendPipeline) Check(ctx context.Context) error {
 	serial := workflow.NewStageSerial()
-	serial.Add(tasks.NewCheckParamTask(p.Log, p.AgentNo, p.AgentSecret, p.TemplateCode, p.Receivers, p.Variables).Task())
+	items := p.items()
+	serial.Add(tasks.NewCheckParamTask(p.Log, p.AgentNo, p.AgentSecret, p.TemplateCode, items, p.MaxBatchSize).Task())
 	serial.Add(tasks.NewCheckAgentTask(p.Log, p.DB, p.AgentNo, p.AgentSecret).Task())
 	serial.Add(tasks.NewCheckTemplateTask(p.Log, p.DB, p.TemplateCode).Task())
-	serial.Add(tasks.NewCreateRecordTask(p.Log, p.DB, p.TraceID, p.Receivers, p.Variables, p.Extra).Task())
+	serial.Add(tasks.NewCheckVariablesTask(p.Log, items).Task())
+	serial.Add(tasks.NewCreateRecordTask(p.Log, p.DB, p.TraceID, items, p.queueTime()).Task())
 	serial.Add(&workflow.Task{
 		Action: func(ctx context.Context) (context.Context, error) {
 			return ctx, nil
@@ -52,6 +60,40 @@ func (p *SendPipeline) Check(ctx context.Context) error {
 	return serial.Run(ctx)
 }
 
+// items 合并共用参数的 Receivers 与个性化的 Items，Items 中的变量、扩展参数与语言覆盖共用的同名参数
+func (p *SendPipeline) items() []tasks.ReceiverItem {
+	items := make([]tasks.ReceiverItem, 0, len(p.Receivers)+len(p.Items))
+	for _, receiver := range p.Receivers {
+		items = append(items, tasks.ReceiverItem{Receiver: receiver, Variables: p.Variables, Extra: p.Extra, Locale: p.Locale})
+	}
+	for _, item := range p.Items {
+		variables := make(map[string]interface{}, len(p.Variables)+len(item.Variables))
+		maps.Copy(variables, p.Variables)
+		maps.Copy(variables, item.Variables)
+		extra := make(map[string]interface{}, len(p.Extra)+len(item.Extra))
+		maps.Copy(extra, p.Extra)
+		maps.Copy(extra, item.Extra)
+		locale := item.Locale
+		if locale == "" {
+			locale = p.Locale
+		}
+		items = append(items, tasks.ReceiverItem{Receiver: item.Receiver, Variables: variables, Extra: extra, Locale: locale})
+	}
+	return items
+}
+
+// queueTime 异步或定时发送时的入队时间，同步发送返回 nil
+func (p *SendPipeline) queueTime() *time.Time {
+	if p.SendAt != nil {
+		return p.SendAt
+	}
+	if p.Async {
+		now := time.Now()
+		return &now
+	}
+	return nil
+}
+
 func (p *SendPipeline) Send(ctx context.Context) error {
 	if p.sendBatch == nil {
 		p.Log.Error("send batch is nil, check must be run first")
@@ -75,9 +117,19 @@ func (p *SendPipeline) Send(ctx context.Context) error {
 	serial.Add(&workflow.Task{
 		Action: func(ctx context.Context) (context.Context, error) {
 			parallel := workflow.NewStageParallel()
+			mention := make(map[string][]*models.SendRecord)
 			for _, record := range p.sendBatch.Records {
+				if record.Channel != nil && record.Channel.MergeMention {
+					key := record.MentionKey()
+					mention[key] = append(mention[key], record)
+					continue
+				}
 				parallel.Add(tasks.NewSendTask(p.Log, p.DB, record).Task())
 			}
+			// 开启合并提醒的通道，批次内该通道发送内容相同的记录只发送一条群消息
+			for _, records := range mention {
+				parallel.Add(tasks.NewMentionSendTask(p.Log, p.DB, records).Task())
+			}
 			_ = parallel.Run(ctx)
 			return ctx, nil
 		},
@@ -97,6 +149,12 @@ func (p *SendPipeline) Send(ctx context.Context) error {
 	})
 	return serial.Run(ctx)
 }
+
+// Batch Check 创建的批次，未创建时返回 nil；不查询数据库，统计数据可能不是最新
+func (p *SendPipeline) Batch() *models.SendBatch {
+	return p.sendBatch
+}
+
 func (p *SendPipeline) GetSendBatch() (*models.SendBatch, error) {
 	if p.sendBatch == nil {
 		p.Log.Error("send batch is nil, send must be run first")
@@ -0,0 +1,271 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"chihqiang/msgbox-go/services/common/errs"
+	"chihqiang/msgbox-go/services/common/models"
+	"chihqiang/msgbox-go/services/common/models/modeltest"
+	"chihqiang/msgbox-go/services/common/pipeline/tasks"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// tenant 代理商及其同名编码的通道与模版，用于验证代理商之间互相隔离
+type tenant struct {
+	agent    *models.Agent
+	channel  *models.Channel
+	template *models.Template
+}
+
+func newTenant(t *testing.T, db *gorm.DB, email string) *tenant {
+	t.Helper()
+	agent := &models.Agent{Email: email, Password: "x", Status: true}
+	if err := db.Create(agent).Error; err != nil {
+		t.Fatal(err)
+	}
+	channel := &models.Channel{AgentID: agent.ID, Code: "hook", Name: "hook", VendorName: "webhook", Config: datatypes.JSON(`{}`), Status: true}
+	if err := db.Create(channel).Error; err != nil {
+		t.Fatal(err)
+	}
+	template := &models.Template{AgentID: agent.ID, ChannelID: channel.ID, Name: "notice", Code: "notice", Content: "hi " + email, Status: true}
+	if err := db.Create(template).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &tenant{agent: agent, channel: channel, template: template}
+}
+
+// check 以代理商身份异步提交一次发送，只执行检查与入库，不调用服务商
+func (tn *tenant) check(db *gorm.DB, templateCode string, receivers ...string) (*models.SendBatch, error) {
+	p := &SendPipeline{
+		Log:          logx.WithContext(context.Background()),
+		DB:           db,
+		TraceID:      "T1",
+		AgentNo:      tn.agent.AgentNo,
+		AgentSecret:  tn.agent.AgentSecret,
+		TemplateCode: templateCode,
+		Receivers:    receivers,
+		Async:        true,
+	}
+	if err := p.Check(context.Background()); err != nil {
+		return nil, err
+	}
+	return p.GetSendBatch()
+}
+
+func TestSendPipelineTenantIsolation(t *testing.T) {
+	db := modeltest.NewDB(t)
+	a, b := newTenant(t, db, "a@example.com"), newTenant(t, db, "b@example.com")
+	only := &models.Template{AgentID: b.agent.ID, ChannelID: b.channel.ID, Name: "only-b", Code: "only-b", Content: "b", Status: true}
+	if err := db.Create(only).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 两个代理商使用同一模版编码时各自解析到自己的模版与通道
+	for _, tn := range []*tenant{a, b} {
+		batch, err := tn.check(db, "notice", "u1")
+		if err != nil {
+			t.Fatalf("agent %d: %v", tn.agent.ID, err)
+		}
+		if batch.AgentID != tn.agent.ID || batch.TemplateID != tn.template.ID || batch.ChannelID != tn.channel.ID {
+			t.Errorf("agent %d resolved batch agent=%d template=%d channel=%d", tn.agent.ID, batch.AgentID, batch.TemplateID, batch.ChannelID)
+		}
+		for _, record := range batch.Records {
+			if record.ChannelID != tn.channel.ID || record.Content != tn.template.Content {
+				t.Errorf("agent %d record channel=%d content=%q", tn.agent.ID, record.ChannelID, record.Content)
+			}
+		}
+	}
+
+	// 其他代理商的模版编码对当前代理商不存在
+	if _, err := a.check(db, "only-b", "u1"); !errors.Is(err, errs.ErrTemplateCodeMissing) {
+		t.Errorf("other agent's template: err = %v, want %v", err, errs.ErrTemplateCodeMissing)
+	}
+
+	// 模版指向其他代理商的通道时视为没有通道
+	foreign := &models.Template{AgentID: a.agent.ID, ChannelID: b.channel.ID, Name: "foreign", Code: "foreign", Content: "x", Status: true}
+	if err := db.Create(foreign).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.check(db, "foreign", "u1"); !errors.Is(err, errs.ErrTemplateChannelMissing) {
+		t.Errorf("other agent's channel: err = %v, want %v", err, errs.ErrTemplateChannelMissing)
+	}
+
+	// 扇出通道属于其他代理商时不会投递，带该通道前缀的接收者发送到主通道
+	fanout := &models.TemplateFanout{AgentID: a.agent.ID, TemplateID: a.template.ID, ChannelID: b.channel.ID}
+	if err := db.Create(fanout).Error; err != nil {
+		t.Fatal(err)
+	}
+	batch, err := a.check(db, "notice", "hook:u1", "u2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range batch.Records {
+		if record.ChannelID != a.channel.ID {
+			t.Errorf("record %s sent to channel %d, want %d", record.Receiver, record.ChannelID, a.channel.ID)
+		}
+	}
+}
+
+func TestSendPipelineDisabled(t *testing.T) {
+	tests := []struct {
+		name    string
+		disable func(t *testing.T, db *gorm.DB, a, b *tenant)
+		wantErr error
+	}{
+		{
+			name: "agent disabled",
+			disable: func(t *testing.T, db *gorm.DB, a, b *tenant) {
+				db.Model(a.agent).Update("status", false)
+			},
+			wantErr: errs.ErrAgentDisabled,
+		},
+		{
+			name: "template disabled",
+			disable: func(t *testing.T, db *gorm.DB, a, b *tenant) {
+				db.Model(a.template).Update("status", false)
+			},
+			wantErr: errs.ErrTemplateDisabled,
+		},
+		{
+			name: "channel disabled",
+			disable: func(t *testing.T, db *gorm.DB, a, b *tenant) {
+				db.Model(a.channel).Update("status", false)
+			},
+			wantErr: errs.ErrChannelDisabled,
+		},
+		{
+			// 其他代理商启用的通道不能作为备用通道
+			name: "channel disabled with other agent's fallback",
+			disable: func(t *testing.T, db *gorm.DB, a, b *tenant) {
+				db.Model(a.channel).Update("status", false)
+				db.Model(a.template).Update("fallback_channel_ids", datatypes.JSONSlice[int64]{b.channel.ID})
+			},
+			wantErr: errs.ErrChannelDisabled,
+		},
+		{
+			name: "channel disabled with enabled fallback",
+			disable: func(t *testing.T, db *gorm.DB, a, b *tenant) {
+				fallback := &models.Channel{AgentID: a.agent.ID, Code: "backup", VendorName: "webhook", Config: datatypes.JSON(`{}`), Status: true}
+				if err := db.Create(fallback).Error; err != nil {
+					t.Fatal(err)
+				}
+				db.Model(a.channel).Update("status", false)
+				db.Model(a.template).Update("fallback_channel_ids", datatypes.JSONSlice[int64]{fallback.ID})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := modeltest.NewDB(t)
+			a, b := newTenant(t, db, "a@example.com"), newTenant(t, db, "b@example.com")
+			tt.disable(t, db, a, b)
+			_, err := a.check(db, "notice", "u1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			// 其他代理商不受影响
+			if _, err := b.check(db, "notice", "u1"); err != nil {
+				t.Errorf("other agent: %v", err)
+			}
+		})
+	}
+}
+
+// TestSendPipelineTemplateVersion 发送只读取模板的当前版本，不生成版本
+func TestSendPipelineTemplateVersion(t *testing.T) {
+	db := modeltest.NewDB(t)
+	a := newTenant(t, db, "a@example.com")
+	countVersions := func() int64 {
+		var count int64
+		if err := db.Model(&models.TemplateVersion{}).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+	batch, err := a.check(db, "notice", "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := countVersions(); n != 0 || batch.Records[0].TemplateVersionID != 0 {
+		t.Fatalf("versions = %d, record version = %d, want no version written", n, batch.Records[0].TemplateVersionID)
+	}
+	version, err := models.SaveTemplateVersion(db, a.template, 0, "", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch, err = a.check(db, "notice", "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if n := countVersions(); n != 1 || batch.Records[0].TemplateVersionID != version.ID {
+		t.Errorf("versions = %d, record version = %d, want %d", n, batch.Records[0].TemplateVersionID, version.ID)
+	}
+}
+
+// TestSendPipelineMentionContent 合并提醒的接收者变量不同导致内容不同时分别发送，各自收到自己的内容
+func TestSendPipelineMentionContent(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		messages = make(map[string][]string)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text struct {
+				Content string   `json:"content"`
+				Mobiles []string `json:"mentioned_mobile_list"`
+			} `json:"text"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		messages[body.Text.Content] = append(messages[body.Text.Content], body.Text.Mobiles...)
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer server.Close()
+
+	db := modeltest.NewDB(t)
+	tn := newTenant(t, db, "a@example.com")
+	config, _ := json.Marshal(map[string]string{"url": server.URL, "key": "k"})
+	db.Model(tn.channel).Updates(map[string]any{"vendor_name": "workwx", "config": datatypes.JSON(config), "merge_mention": true})
+	db.Model(tn.template).Update("content", "hi ${name}")
+
+	p := &SendPipeline{
+		Log:          logx.WithContext(context.Background()),
+		DB:           db,
+		TraceID:      "T1",
+		AgentNo:      tn.agent.AgentNo,
+		AgentSecret:  tn.agent.AgentSecret,
+		TemplateCode: "notice",
+		Items: []tasks.ReceiverItem{
+			{Receiver: "u1", Variables: map[string]any{"name": "A"}},
+			{Receiver: "u2", Variables: map[string]any{"name": "A"}},
+			{Receiver: "u3", Variables: map[string]any{"name": "B"}},
+		},
+	}
+	if err := p.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{"hi A": {"u1", "u2"}, "hi B": {"u3"}}
+	if len(messages) != len(want) {
+		t.Fatalf("messages = %v, want %v", messages, want)
+	}
+	for content, mobiles := range want {
+		got := messages[content]
+		slices.Sort(got)
+		if !slices.Equal(got, mobiles) {
+			t.Errorf("%q mentioned %v, want %v", content, got, mobiles)
+		}
+	}
+}
@@ -39,6 +39,10 @@ func (c *CheckAgentTask) Task() *workflow.Task {
 				c.Log.Error("agent not found, agent no: %s, agent key: %s", c.AgentNo, c.AgentSecret)
 				return ctx, errs.ErrAuthInvalid
 			}
+			if !agent.Status {
+				c.Log.Error("agent is disabled, agent no: %s", c.AgentNo)
+				return ctx, errs.ErrAgentDisabled
+			}
 			ctx = context.WithValue(ctx, CtxModelAgent, &agent)
 			return ctx, nil
 		},
@@ -3,7 +3,10 @@ package tasks
 import (
 	"chihqiang/msgbox-go/pkg/workflow"
 	"chihqiang/msgbox-go/services/common/errs"
+	"chihqiang/msgbox-go/services/common/models"
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/zeromicro/go-zero/core/logx"
 )
@@ -13,18 +16,19 @@ type CheckParamTask struct {
 	AgentNo      string
 	AgentSecret  string
 	TemplateCode string
-	Receivers    []string
-	Variables    map[string]string
+	Items        []ReceiverItem
+	// MaxBatchSize 单次请求的最大接收者数量，0 表示不限制
+	MaxBatchSize int
 }
 
-func NewCheckParamTask(log logx.Logger, agentNo string, agentSecret string, templateCode string, receivers []string, variables map[string]string) *CheckParamTask {
+func NewCheckParamTask(log logx.Logger, agentNo string, agentSecret string, templateCode string, items []ReceiverItem, maxBatchSize int) *CheckParamTask {
 	cpt := &CheckParamTask{
 		Log:          log,
 		AgentNo:      agentNo,
 		AgentSecret:  agentSecret,
 		TemplateCode: templateCode,
-		Receivers:    receivers,
-		Variables:    variables,
+		Items:        items,
+		MaxBatchSize: maxBatchSize,
 	}
 	return cpt
 }
@@ -44,10 +48,24 @@ func (c *CheckParamTask) Task() *workflow.Task {
 				c.Log.Error("template code is empty")
 				return ctx, errs.ErrParamInvalid
 			}
-			if len(c.Receivers) == 0 {
+			if len(c.Items) == 0 {
 				c.Log.Error("receivers is empty")
 				return ctx, errs.ErrParamInvalid
 			}
+			if c.MaxBatchSize > 0 && len(c.Items) > c.MaxBatchSize {
+				c.Log.Error("receivers count %d exceeds max batch size %d", len(c.Items), c.MaxBatchSize)
+				return ctx, errs.ErrBatchTooLarge
+			}
+			for _, item := range c.Items {
+				if strings.TrimSpace(item.Receiver) == "" {
+					c.Log.Error("receiver is empty")
+					return ctx, errs.ErrParamInvalid
+				}
+				if _, ok := models.NormalizeLocale(item.Locale); item.Locale != "" && !ok {
+					c.Log.Errorf("receiver %s has an invalid locale %q", item.Receiver, item.Locale)
+					return ctx, errs.GetErrDetail(errs.ErrCodeParamInvalid, fmt.Sprintf("invalid locale %q", item.Locale))
+				}
+			}
 			return ctx, nil
 		},
 	}
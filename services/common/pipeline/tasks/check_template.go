@@ -20,6 +20,8 @@ func NewCheckTemplateTask(log logx.Logger, db *gorm.DB, templateCode string) *Ch
 	return &CheckTemplateTask{Log: log, DB: db, TemplateCode: templateCode}
 }
 
+// Task 按 (agent_id, code) 查询当前代理商的模版，模版编码仅在代理商内唯一，
+// 通道同样只使用当前代理商的通道，避免跨代理商使用他人的模版与通道
 func (c *CheckTemplateTask) Task() *workflow.Task {
 	return &workflow.Task{
 		Action: func(ctx context.Context) (context.Context, error) {
@@ -27,19 +29,51 @@ func (c *CheckTemplateTask) Task() *workflow.Task {
 				c.Log.Error("template code is empty")
 				return ctx, errs.ErrTemplateCodeMissing
 			}
+			agent, ok := ctx.Value(CtxModelAgent).(*models.Agent)
+			if !ok || agent == nil {
+				c.Log.Error("agent is missing, check agent must be run first")
+				return ctx, errs.ErrAuthInvalid
+			}
 			var template models.Template
-			_ = c.DB.Model(template).Preload("Channel").Where(models.Template{Code: c.TemplateCode}).First(&template).Error
+			_ = c.DB.Model(template).
+				Preload("Channel", "agent_id = ?", agent.ID).
+				Preload("Fanouts", func(db *gorm.DB) *gorm.DB { return db.Order("sort, id") }).
+				Preload("Fanouts.Channel", "agent_id = ?", agent.ID).
+				Preload("Locales", func(db *gorm.DB) *gorm.DB { return db.Order("channel_id, locale") }).
+				Where(models.Template{AgentID: agent.ID, Code: c.TemplateCode}).First(&template).Error
 			if template.ID == 0 {
-				c.Log.Error("template not found, template code: %s", c.TemplateCode)
+				c.Log.Error("template not found, agent id: %d, template code: %s", agent.ID, c.TemplateCode)
 				return ctx, errs.ErrTemplateCodeMissing
 			}
+			if !template.Status {
+				c.Log.Error("template is disabled, template code: %s", c.TemplateCode)
+				return ctx, errs.ErrTemplateDisabled
+			}
 			if template.Channel == nil {
 				c.Log.Error("template channel not found, template code: %s", c.TemplateCode)
 				return ctx, errs.ErrTemplateChannelMissing
 			}
+			// 主通道禁用时仍可故障转移到启用的备用通道
+			if !template.Channel.Status && !c.hasEnabledFallback(agent.ID, &template) {
+				c.Log.Error("template channels are disabled, template code: %s", c.TemplateCode)
+				return ctx, errs.ErrChannelDisabled
+			}
 			ctx = context.WithValue(ctx, CtxModelTemplate, &template)
 			ctx = context.WithValue(ctx, CtxModelChannel, template.Channel)
 			return ctx, nil
 		},
 	}
 }
+
+func (c *CheckTemplateTask) hasEnabledFallback(agentID int64, template *models.Template) bool {
+	ids := template.ChannelIDs()[1:]
+	if len(ids) == 0 {
+		return false
+	}
+	var count int64
+	if err := c.DB.Model(&models.Channel{}).Where("id IN ? AND agent_id = ? AND status = ?", ids, agentID, true).Count(&count).Error; err != nil {
+		c.Log.Error("count enabled fallback channels failed, err: %v", err)
+		return false
+	}
+	return count > 0
+}
@@ -0,0 +1,41 @@
+package tasks
+
+import (
+	"chihqiang/msgbox-go/pkg/workflow"
+	"chihqiang/msgbox-go/services/common/errs"
+	"chihqiang/msgbox-go/services/common/models"
+	"context"
+	"fmt"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type CheckVariablesTask struct {
+	Log   logx.Logger
+	Items []ReceiverItem
+}
+
+func NewCheckVariablesTask(log logx.Logger, items []ReceiverItem) *CheckVariablesTask {
+	return &CheckVariablesTask{Log: log, Items: items}
+}
+
+// Task 按模版的变量声明校验每个接收者的变量，任一接收者不通过时整个请求被拒绝，
+// 错误信息中包含接收者与未通过校验的变量名
+func (c *CheckVariablesTask) Task() *workflow.Task {
+	return &workflow.Task{
+		Action: func(ctx context.Context) (context.Context, error) {
+			template, ok := ctx.Value(CtxModelTemplate).(*models.Template)
+			if !ok || template == nil {
+				c.Log.Error("template is missing, check template must be run first")
+				return ctx, errs.ErrTemplateCodeMissing
+			}
+			for _, item := range c.Items {
+				if err := models.CheckVariables(template.Variables, item.Variables); err != nil {
+					c.Log.Errorf("check variables of template %d for receiver %s failed, err: %v", template.ID, item.Receiver, err)
+					return ctx, errs.GetErrDetail(errs.ErrCodeVariableInvalid, fmt.Sprintf("receiver %s: %v", item.Receiver, err))
+				}
+			}
+			return ctx, nil
+		},
+	}
+}
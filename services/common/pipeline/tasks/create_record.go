@@ -6,24 +6,32 @@ import (
 	"chihqiang/msgbox-go/services/common/errs"
 	"chihqiang/msgbox-go/services/common/models"
 	"context"
-	"strings"
 	"time"
 
 	"github.com/zeromicro/go-zero/core/logx"
 	"gorm.io/gorm"
 )
 
-type CreateRecordTask struct {
-	Log       logx.Logger
-	DB        *gorm.DB
-	TraceID   string
-	Receivers []string
-	Variables map[string]string
+// ReceiverItem 单个接收者及其渲染参数，每个接收者可以使用不同的变量、扩展参数与语言
+type ReceiverItem struct {
+	Receiver  string
+	Variables map[string]interface{}
 	Extra     map[string]interface{}
+	// Locale 接收者的语言，按 zh-TW → zh → 默认内容的顺序选择模板的语言变体
+	Locale string
 }
 
-func NewCreateRecordTask(log logx.Logger, db *gorm.DB, traceID string, receivers []string, variables map[string]string, extra map[string]interface{}) *CreateRecordTask {
-	crt := &CreateRecordTask{Log: log, DB: db, TraceID: traceID, Receivers: receivers, Variables: variables, Extra: extra}
+type CreateRecordTask struct {
+	Log     logx.Logger
+	DB      *gorm.DB
+	TraceID string
+	Items   []ReceiverItem
+	// QueueTime 入队时间，非空时记录入队由 worker 在该时间后领取发送，为空时同步发送
+	QueueTime *time.Time
+}
+
+func NewCreateRecordTask(log logx.Logger, db *gorm.DB, traceID string, items []ReceiverItem, queueTime *time.Time) *CreateRecordTask {
+	crt := &CreateRecordTask{Log: log, DB: db, TraceID: traceID, Items: items, QueueTime: queueTime}
 	return crt
 }
 
@@ -31,38 +39,41 @@ func (c *CreateRecordTask) Task() *workflow.Task {
 	return &workflow.Task{
 		Action: func(ctx context.Context) (context.Context, error) {
 			now := time.Now()
+			scheduledTime := &now
+			if c.QueueTime != nil {
+				scheduledTime = c.QueueTime
+			}
 			batch := models.SendBatch{
 				BatchNo:       stringx.UUID(),
 				TraceID:       c.TraceID,
-				TotalCount:    len(c.Receivers),
-				ScheduledTime: &now,
+				Async:         c.QueueTime != nil,
+				ScheduledTime: scheduledTime,
 				Agent:         ctx.Value(CtxModelAgent).(*models.Agent),
 				Channel:       ctx.Value(CtxModelChannel).(*models.Channel),
 				Template:      ctx.Value(CtxModelTemplate).(*models.Template),
 			}
-			for _, s := range c.Receivers {
-				content := strings.Join([]string{
-					batch.Template.Signature,
-					stringx.ReplaceVariables(batch.Template.Content, c.Variables),
-				}, "")
-				rc := &models.SendRecord{
-					TraceID:       c.TraceID,
-					Receiver:      s,
-					VendorName:    batch.Channel.VendorName,
-					ChannelConfig: batch.Channel.Config,
-					VendorCode:    batch.Template.VendorCode,
-					Signature:     batch.Template.Signature,
-					Content:       content,
-					Variables:     models.MapToDataTypesJSON(c.Variables),
-					Extra:         models.MapToDataTypesJSON(c.Extra),
-					Status:        models.SendRecordStatusPending,
-					Agent:         batch.Agent,
-					Channel:       batch.Channel,
-					Template:      batch.Template,
-					Batch:         &batch,
+			// 每个接收者先按语言选择各通道的内容变体，再按前缀路由到主通道或扇出通道，生成 通道 × 接收者 条记录
+			variants := make(map[string][]*models.TemplateVariant)
+			for _, item := range c.Items {
+				if _, ok := variants[item.Locale]; !ok {
+					variants[item.Locale] = batch.Template.Variants(item.Locale)
+				}
+				routes, receiver := models.RouteReceiver(variants[item.Locale], item.Receiver)
+				for _, variant := range routes {
+					// 扇出通道禁用时跳过，主通道禁用时由发送任务故障转移到备用通道
+					if variant != variants[item.Locale][0] && !variant.Channel.Status {
+						c.Log.Infof("fanout channel %d is disabled, skip receiver %s", variant.ChannelID, receiver)
+						continue
+					}
+					record, err := c.record(&batch, variant, receiver, item)
+					if err != nil {
+						c.Log.Errorf("render template %d for receiver %s failed, err: %v", batch.Template.ID, receiver, err)
+						return ctx, errs.GetErrDetail(errs.ErrCodeTemplateRender, err.Error())
+					}
+					batch.Records = append(batch.Records, record)
 				}
-				batch.Records = append(batch.Records, rc)
 			}
+			batch.TotalCount = len(batch.Records)
 
 			err := c.DB.Create(&batch).Error
 			if err != nil {
@@ -75,3 +86,33 @@ func (c *CreateRecordTask) Task() *workflow.Task {
 		},
 	}
 }
+
+// record 按通道内容变体与接收者的参数生成一条待发送记录，关联的模板仍为原模板，语言为该通道选中的语言
+// 模板在入库前渲染，语法错误或变量缺失时整个批次不会创建
+func (c *CreateRecordTask) record(batch *models.SendBatch, variant *models.TemplateVariant, receiver string, item ReceiverItem) (*models.SendRecord, error) {
+	title, content, err := variant.Render(item.Variables)
+	if err != nil {
+		return nil, err
+	}
+	return &models.SendRecord{
+		TraceID:           c.TraceID,
+		TemplateVersionID: batch.Template.VersionID,
+		Receiver:          receiver,
+		VendorName:        variant.Channel.VendorName,
+		ChannelConfig:     variant.Channel.Config,
+		Locale:            variant.Locale,
+		VendorCode:        variant.VendorCode,
+		Signature:         variant.Signature,
+		MsgType:           variant.MsgType,
+		Title:             title,
+		Content:           content,
+		Variables:         models.MapToDataTypesJSON(item.Variables),
+		Extra:             models.MapToDataTypesJSON(item.Extra),
+		Status:            models.SendRecordStatusPending,
+		QueueTime:         c.QueueTime,
+		Agent:             batch.Agent,
+		Channel:           variant.Channel,
+		Template:          batch.Template,
+		Batch:             batch,
+	}, nil
+}
@@ -1,12 +1,16 @@
 package tasks
 
 import (
+	"chihqiang/msgbox-go/pkg/clientx"
 	"chihqiang/msgbox-go/pkg/workflow"
 	"chihqiang/msgbox-go/services/common/channels/senders"
 	"chihqiang/msgbox-go/services/common/errs"
 	"chihqiang/msgbox-go/services/common/models"
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"time"
 
 	"github.com/zeromicro/go-zero/core/logx"
@@ -27,53 +31,254 @@ func NewSendTask(log logx.Logger, db *gorm.DB, record *models.SendRecord) *SendT
 	}
 }
 
-func (s *SendTask) getSender() (senders.ISender, error) {
-	sender, ok := senders.Get(s.record.VendorName)
-	if ok {
-		send := sender.Sender
-		if err := send.SetConfig(models.DataTypesToMap(s.record.ChannelConfig)); err != nil {
-			return nil, err
+// channels 按故障转移顺序返回通道：主通道使用记录创建时的配置快照，备用通道使用当前配置
+func (s *SendTask) channels() []*models.Channel {
+	primary := &models.Channel{ID: s.record.ChannelID, Status: true}
+	if s.record.Channel != nil {
+		channel := *s.record.Channel
+		primary = &channel
+	}
+	primary.VendorName = s.record.VendorName
+	primary.Config = s.record.ChannelConfig
+	channels := []*models.Channel{primary}
+	template := s.record.Template
+	if template == nil {
+		template = &models.Template{}
+		if err := s.DB.Where("id = ?", s.record.TemplateID).First(template).Error; err != nil {
+			s.Log.Errorf("query template of send record %d failed, err: %v", s.record.ID, err)
+			return channels
 		}
-		return send, nil
 	}
-	return nil, fmt.Errorf("sender not found for vendor: %s", s.record.VendorName)
+	// 备用通道只用于模板主通道，扇出通道的记录不做故障转移
+	ids := template.ChannelIDs()[1:]
+	if len(ids) == 0 || s.record.ChannelID != template.ChannelID {
+		return channels
+	}
+	var fallbacks []*models.Channel
+	if err := s.DB.Where("id IN ? AND agent_id = ?", ids, s.record.AgentID).Find(&fallbacks).Error; err != nil {
+		s.Log.Errorf("query fallback channels failed, err: %v", err)
+		return channels
+	}
+	byID := make(map[int64]*models.Channel, len(fallbacks))
+	for _, channel := range fallbacks {
+		byID[channel.ID] = channel
+	}
+	for _, id := range ids {
+		if channel, ok := byID[id]; ok {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// send 使用指定通道发送一次，返回服务商响应、服务商消息ID与失败原因分类
+func (s *SendTask) send(ctx context.Context, channel *models.Channel) (resp map[string]any, msgID string, reason string, err error) {
+	form, ok := senders.Get(channel.VendorName)
+	if !ok {
+		return map[string]any{}, "", models.SendRecordFailReasonSender, fmt.Errorf("sender not found for vendor: %s", channel.VendorName)
+	}
+	sender, err := form.Sender(models.DataTypesToMap(channel.Config))
+	if err != nil {
+		return map[string]any{}, "", models.SendRecordFailReasonSender, err
+	}
+	sendCtx, cancel := withTimeout(ctx, channel)
+	defer cancel()
+	resp, err = sender.Send(sendCtx, s.record)
+	if err != nil {
+		return resp, "", failReason(sendCtx, err, channel), err
+	}
+	return resp, vendorMsgID(sender, resp), "", nil
 }
 
+// Task 依次尝试主通道与备用通道，跳过已禁用的通道，任一通道成功即结束；
+// 全部失败时按主通道的重试策略决定是否重新入队，重试时仍从主通道开始
 func (s *SendTask) Task() *workflow.Task {
 	return &workflow.Task{
 		Action: func(ctx context.Context) (context.Context, error) {
-			sender, err := s.getSender()
-			if err != nil {
-				s.Log.Error("get sender failed, err: %v", err)
-				return ctx, s.fail(err, map[string]any{})
+			startTime := time.Now()
+			claimed, err := markSending(s.Log, s.DB, []*models.SendRecord{s.record})
+			if err != nil || len(claimed) == 0 {
+				// 记录已被取消或由其他进程处理
+				return ctx, err
 			}
-			resp, err := sender.Send(s.record)
-			if err != nil {
-				s.Log.Error("send message failed, err: %v", err)
-				return ctx, s.fail(err, resp)
+			var (
+				last     *models.Channel
+				lastErr  error
+				reason   string
+				response map[string]any
+			)
+			for _, channel := range s.channels() {
+				if !channel.Status {
+					s.Log.Infof("channel %d is disabled, skip", channel.ID)
+					continue
+				}
+				if last != nil {
+					// 上一个通道失败，记录该次尝试后转移到下一个通道
+					s.Log.Infof("send record %d failover from channel %d to %d", s.record.ID, last.ID, channel.ID)
+					if err := markFailover(s.Log, s.DB, s.record, last, lastErr, reason, response, startTime); err != nil {
+						return ctx, err
+					}
+					startTime = time.Now()
+				}
+				last = channel
+				var msgID string
+				response, msgID, reason, lastErr = s.send(ctx, channel)
+				if lastErr == nil {
+					return ctx, markSuccess(s.Log, s.DB, []*models.SendRecord{s.record}, channel, msgID, response, startTime)
+				}
+				s.Log.Error("send message failed, err: %v", lastErr)
+			}
+			if last == nil {
+				last = s.channels()[0]
+				lastErr = errors.New("all channels are disabled")
+				reason = models.SendRecordFailReasonSender
+				response = map[string]any{}
 			}
-			return ctx, s.success(resp)
+			return ctx, markFail(s.Log, s.DB, []*models.SendRecord{s.record}, last, lastErr, reason, response, startTime)
 		},
 	}
 }
 
-func (s *SendTask) success(response map[string]any) error {
+// withTimeout 按通道配置的超时时间限制本次服务商调用，未配置时仅继承上游 ctx
+func withTimeout(ctx context.Context, channel *models.Channel) (context.Context, context.CancelFunc) {
+	if channel != nil && channel.Timeout > 0 {
+		return context.WithTimeout(ctx, time.Duration(channel.Timeout)*time.Second)
+	}
+	return context.WithCancel(ctx)
+}
+
+// failReason 区分超时、取消、网络错误、服务商 5xx、限流与服务商返回的业务失败
+func failReason(ctx context.Context, err error, channel *models.Channel) string {
+	var (
+		netErr  net.Error
+		httpErr *clientx.HTTPError
+	)
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded), errors.Is(err, context.DeadlineExceeded):
+		return models.SendRecordFailReasonTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return models.SendRecordFailReasonTimeout
+	case errors.Is(ctx.Err(), context.Canceled):
+		return models.SendRecordFailReasonCancel
+	case errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusTooManyRequests:
+		return models.SendRecordFailReasonRateLimit
+	case errors.As(err, &httpErr) && httpErr.StatusCode >= http.StatusInternalServerError:
+		return models.SendRecordFailReasonServer
+	case errors.As(err, &netErr):
+		return models.SendRecordFailReasonNetwork
+	case channel != nil && channel.RateLimited(err.Error()):
+		return models.SendRecordFailReasonRateLimit
+	default:
+		return models.SendRecordFailReasonVendor
+	}
+}
+
+func recordIDs(records []*models.SendRecord) []int64 {
+	ids := make([]int64, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	return ids
+}
+
+// vendorMsgID 支持回执的发送器从响应中提取服务商消息ID
+func vendorMsgID(sender senders.ISender, response map[string]any) string {
+	if receiptSender, ok := sender.(senders.IReceiptSender); ok && response != nil {
+		return receiptSender.MessageID(response)
+	}
+	return ""
+}
+
+// markSending 将待发送记录转换为发送中，返回本次成功转换的记录；
+// 已取消或已被其他进程转换的记录不再调用服务商
+func markSending(log logx.Logger, db *gorm.DB, records []*models.SendRecord) ([]*models.SendRecord, error) {
+	claimed := make([]*models.SendRecord, 0, len(records))
+	for _, record := range records {
+		affected, err := models.TransitionSendRecords(db, []int64{record.ID}, models.SendRecordStatusSending, nil)
+		if err != nil {
+			log.Error("update send record sending failed, err: %v", err)
+			return nil, errs.ErrDB
+		}
+		if affected > 0 {
+			record.Status = models.SendRecordStatusSending
+			claimed = append(claimed, record)
+		}
+	}
+	return claimed, nil
+}
+
+// failedAttempt 构造一次失败的发送尝试
+func failedAttempt(record *models.SendRecord, channel *models.Channel, errMsg error, reason string, response map[string]any, startTime, now time.Time) *models.SendAttempt {
+	return &models.SendAttempt{
+		RecordID:   record.ID,
+		BatchID:    record.BatchID,
+		AgentID:    record.AgentID,
+		ChannelID:  channel.ID,
+		VendorName: channel.VendorName,
+		Attempt:    record.Attempts + 1,
+		Status:     models.SendAttemptStatusFailed,
+		Error:      errMsg.Error(),
+		FailReason: reason,
+		Response:   models.MapToDataTypesJSON(response),
+		StartTime:  startTime,
+		EndTime:    now,
+	}
+}
+
+// markFailover 记录通道故障转移前的失败尝试，记录保持发送中状态继续使用下一个通道
+func markFailover(log logx.Logger, db *gorm.DB, record *models.SendRecord, channel *models.Channel, errMsg error, reason string, response map[string]any, startTime time.Time) error {
+	if err := db.Create(failedAttempt(record, channel, errMsg, reason, response, startTime, time.Now())).Error; err != nil {
+		log.Error("create send attempt failed, err: %v", err)
+		return errs.ErrDB
+	}
+	return nil
+}
+
+// markSuccess 标记发送记录已被服务商受理并累加批次成功条数，合并发送时多条记录共享同一服务商响应，
+// 支持回执的通道保存服务商消息ID，待回执到达后再转换为已送达或失败；
+// channel 为实际发送成功的通道，故障转移后记录的通道与服务商更新为该通道
+func markSuccess(log logx.Logger, db *gorm.DB, records []*models.SendRecord, channel *models.Channel, vendorMsgID string, response map[string]any, startTime time.Time) error {
 	now := time.Now()
-	err := s.DB.Transaction(func(tx *gorm.DB) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
 		// 更新发送记录
-		if err := tx.Model(&s.record).Updates(map[string]interface{}{
-			"send_time": now,
-			"status":    models.SendRecordStatusSending,
-			"response":  models.MapToDataTypesJSON(response),
-		}).Error; err != nil {
-			s.Log.Error("update send record failed, err: %v", err)
+		accepted, err := models.TransitionSendRecords(tx, recordIDs(records), models.SendRecordStatusAccepted, map[string]interface{}{
+			"channel_id":        channel.ID,
+			"vendor_name":       channel.VendorName,
+			"channel_config":    channel.Config,
+			"send_time":         now,
+			"vendor_msg_id":     vendorMsgID,
+			"response":          models.MapToDataTypesJSON(response),
+			"attempts":          gorm.Expr("attempts + ?", 1),
+			"next_attempt_time": nil,
+		})
+		if err != nil {
+			log.Error("update send record failed, err: %v", err)
+			return err
+		}
+		attempts := make([]*models.SendAttempt, 0, len(records))
+		for _, record := range records {
+			attempts = append(attempts, &models.SendAttempt{
+				RecordID:   record.ID,
+				BatchID:    record.BatchID,
+				AgentID:    record.AgentID,
+				ChannelID:  channel.ID,
+				VendorName: channel.VendorName,
+				Attempt:    record.Attempts + 1,
+				Status:     models.SendAttemptStatusSuccess,
+				Response:   models.MapToDataTypesJSON(response),
+				StartTime:  startTime,
+				EndTime:    now,
+			})
+		}
+		if err := tx.Create(&attempts).Error; err != nil {
+			log.Error("create send attempt failed, err: %v", err)
 			return err
 		}
 		// 更新批次统计
 		if err := tx.Model(&models.SendBatch{}).
-			Where(&models.SendBatch{ID: s.record.BatchID}).
-			UpdateColumn("success_count", gorm.Expr("success_count + ?", 1)).Error; err != nil {
-			s.Log.Error("update send batch success count failed, err: %v", err)
+			Where(&models.SendBatch{ID: records[0].BatchID}).
+			UpdateColumn("success_count", gorm.Expr("success_count + ?", accepted)).Error; err != nil {
+			log.Error("update send batch success count failed, err: %v", err)
 			return err
 		}
 		return nil
@@ -84,23 +289,55 @@ func (s *SendTask) success(response map[string]any) error {
 	return nil
 }
 
-func (s *SendTask) fail(errMsg error, response map[string]any) error {
+// markFail 记录本次失败尝试；记录所属主通道的重试策略允许时回到待发送并按退避时间重新入队，
+// 由 worker 到期重试，否则标记失败并累加批次失败条数；channel 为本次失败的通道
+func markFail(log logx.Logger, db *gorm.DB, records []*models.SendRecord, channel *models.Channel, errMsg error, reason string, response map[string]any, startTime time.Time) error {
 	now := time.Now()
-	err := s.DB.Transaction(func(tx *gorm.DB) error {
-		// 更新发送记录
-		if err := tx.Model(&s.record).Updates(map[string]interface{}{
-			"send_time": now,
-			"status":    models.SendRecordStatusFailed,
-			"error":     errMsg.Error(),
-			"response":  models.MapToDataTypesJSON(response),
-		}).Error; err != nil {
-			s.Log.Error("update send record failed, err: %v", err)
-			return errs.ErrDB
+	err := db.Transaction(func(tx *gorm.DB) error {
+		failed := 0
+		for _, record := range records {
+			attempt := record.Attempts + 1
+			var nextAttemptTime *time.Time
+			if record.Channel != nil {
+				nextAttemptTime = record.Channel.NextAttemptTime(attempt, reason, now)
+			}
+			sendAttempt := failedAttempt(record, channel, errMsg, reason, response, startTime, now)
+			sendAttempt.NextAttemptTime = nextAttemptTime
+			if err := tx.Create(sendAttempt).Error; err != nil {
+				log.Error("create send attempt failed, err: %v", err)
+				return errs.ErrDB
+			}
+			status := models.SendRecordStatusFailed
+			updates := map[string]interface{}{
+				"send_time":         now,
+				"error":             errMsg.Error(),
+				"fail_reason":       reason,
+				"response":          models.MapToDataTypesJSON(response),
+				"attempts":          attempt,
+				"next_attempt_time": nextAttemptTime,
+			}
+			if nextAttemptTime != nil {
+				status = models.SendRecordStatusPending
+				updates["queue_time"] = nextAttemptTime
+				updates["locked_until"] = nil
+			}
+			// 更新发送记录
+			affected, err := models.TransitionSendRecords(tx, []int64{record.ID}, status, updates)
+			if err != nil {
+				log.Error("update send record failed, err: %v", err)
+				return errs.ErrDB
+			}
+			if affected > 0 && status == models.SendRecordStatusFailed {
+				failed++
+			}
+		}
+		if failed == 0 {
+			return nil
 		}
 		if err := tx.Model(&models.SendBatch{}).
-			Where(&models.SendBatch{ID: s.record.BatchID}).
-			UpdateColumn("fail_count", gorm.Expr("fail_count + ?", 1)).Error; err != nil {
-			s.Log.Error("update send batch fail count failed, err: %v", err)
+			Where(&models.SendBatch{ID: records[0].BatchID}).
+			UpdateColumn("fail_count", gorm.Expr("fail_count + ?", failed)).Error; err != nil {
+			log.Error("update send batch fail count failed, err: %v", err)
 			return errs.ErrDB
 		}
 		return nil
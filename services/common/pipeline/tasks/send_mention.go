@@ -0,0 +1,88 @@
+package tasks
+
+import (
+	"chihqiang/msgbox-go/pkg/workflow"
+	"chihqiang/msgbox-go/services/common/channels/senders"
+	"chihqiang/msgbox-go/services/common/models"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+)
+
+// MentionSendTask 合并提醒发送：同一批次发送内容相同的接收者只发送一条群消息，记录需按 SendRecord.MentionKey 分组，
+// 发送器未实现 IMentionSender 时退回逐条发送
+type MentionSendTask struct {
+	Log     logx.Logger
+	DB      *gorm.DB
+	records []*models.SendRecord
+}
+
+func NewMentionSendTask(log logx.Logger, db *gorm.DB, records []*models.SendRecord) *MentionSendTask {
+	return &MentionSendTask{
+		Log:     log,
+		DB:      db,
+		records: records,
+	}
+}
+
+func (m *MentionSendTask) getSender() (senders.ISender, error) {
+	record := m.records[0]
+	sender, ok := senders.Get(record.VendorName)
+	if ok {
+		return sender.Sender(models.DataTypesToMap(record.ChannelConfig))
+	}
+	return nil, fmt.Errorf("sender not found for vendor: %s", record.VendorName)
+}
+
+func (m *MentionSendTask) receivers(records []*models.SendRecord) []string {
+	receivers := make([]string, 0, len(records))
+	for _, record := range records {
+		receivers = append(receivers, record.Receiver)
+	}
+	return receivers
+}
+
+func (m *MentionSendTask) Task() *workflow.Task {
+	return &workflow.Task{
+		Action: func(ctx context.Context) (context.Context, error) {
+			if len(m.records) == 0 {
+				return ctx, nil
+			}
+			startTime := time.Now()
+			sender, err := m.getSender()
+			if err != nil {
+				m.Log.Error("get sender failed, err: %v", err)
+				claimed, claimErr := markSending(m.Log, m.DB, m.records)
+				if claimErr != nil || len(claimed) == 0 {
+					return ctx, claimErr
+				}
+				return ctx, markFail(m.Log, m.DB, claimed, claimed[0].Channel, err, models.SendRecordFailReasonSender, map[string]any{}, startTime)
+			}
+			mentionSender, ok := sender.(senders.IMentionSender)
+			if !ok {
+				parallel := workflow.NewStageParallel()
+				for _, record := range m.records {
+					parallel.Add(NewSendTask(m.Log, m.DB, record).Task())
+				}
+				return ctx, parallel.Run(ctx)
+			}
+			// 只提醒本次成功转换为发送中的接收者，已取消或已被处理的记录跳过
+			claimed, err := markSending(m.Log, m.DB, m.records)
+			if err != nil || len(claimed) == 0 {
+				return ctx, err
+			}
+			// 分组时已保证记录的发送内容一致，以首条记录作为消息主体
+			sendCtx, cancel := withTimeout(ctx, claimed[0].Channel)
+			defer cancel()
+			resp, err := mentionSender.SendMention(sendCtx, claimed[0], m.receivers(claimed))
+			if err != nil {
+				m.Log.Error("send mention message failed, err: %v", err)
+				return ctx, markFail(m.Log, m.DB, claimed, claimed[0].Channel, err, failReason(sendCtx, err, claimed[0].Channel), resp, startTime)
+			}
+			return ctx, markSuccess(m.Log, m.DB, claimed, claimed[0].Channel, vendorMsgID(sender, resp), resp, startTime)
+		},
+	}
+}
@@ -0,0 +1,56 @@
+package tasks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chihqiang/msgbox-go/services/common/channels/senders"
+	"chihqiang/msgbox-go/services/common/models"
+)
+
+type webhookMessage struct{}
+
+func (webhookMessage) GetReceiver() string          { return "u1" }
+func (webhookMessage) GetTitle() string             { return "" }
+func (webhookMessage) GetContent() string           { return "hello" }
+func (webhookMessage) GetVendorCode() string        { return "" }
+func (webhookMessage) GetSignature() string         { return "" }
+func (webhookMessage) GetMsgType() string           { return "" }
+func (webhookMessage) GetVariables() map[string]any { return nil }
+func (webhookMessage) GetExtra() map[string]any     { return nil }
+
+func TestFailReasonWebhookStatus(t *testing.T) {
+	tests := []struct {
+		status    int
+		reason    string
+		retryable bool
+	}{
+		{http.StatusServiceUnavailable, models.SendRecordFailReasonServer, true},
+		{http.StatusTooManyRequests, models.SendRecordFailReasonRateLimit, true},
+		{http.StatusBadRequest, models.SendRecordFailReasonVendor, false},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+			form, _ := senders.Get("webhook")
+			sender, err := form.Sender(map[string]any{"url": server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			_, err = sender.Send(ctx, webhookMessage{})
+			if err == nil {
+				t.Fatal("send succeeded, want status error")
+			}
+			reason := failReason(ctx, err, nil)
+			if reason != tt.reason || models.RetryableFailReason(reason) != tt.retryable {
+				t.Errorf("fail reason = %s, retryable = %v, want %s, %v", reason, models.RetryableFailReason(reason), tt.reason, tt.retryable)
+			}
+		})
+	}
+}
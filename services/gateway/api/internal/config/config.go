@@ -6,9 +6,17 @@ package config
 import (
 	"chihqiang/msgbox-go/services/common/models"
 	"github.com/zeromicro/go-zero/rest"
+	"time"
 )
 
 type Config struct {
 	rest.RestConf
-	DB models.Config
+	DB           models.Config
+	Idempotency  IdempotencyConf
+	MaxBatchSize int `json:",default=1000"` // 单次发送请求的最大接收者数量
+}
+
+type IdempotencyConf struct {
+	Window time.Duration `json:",default=24h"` // 幂等键有效期，有效期内相同键的请求返回首次响应
+	Lease  time.Duration `json:",default=5m"`  // 首次请求处理中的租约，同步发送期间按一半的间隔续期，到期未续期时视为首次请求已中断
 }
@@ -0,0 +1,33 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package handler
+
+import (
+	"net/http"
+
+	"chihqiang/msgbox-go/services/gateway/api/internal/logic"
+	"chihqiang/msgbox-go/services/gateway/api/internal/svc"
+	"chihqiang/msgbox-go/services/gateway/api/internal/types"
+	"github.com/zeromicro/go-zero/rest/httpx"
+	xhttp "github.com/zeromicro/x/http"
+)
+
+// ReceiptHandler 只解析路径参数，请求体交由对应服务商的发送器解析，应答按服务商要求的格式原样返回
+func ReceiptHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.ReceiptRequest
+		if err := httpx.ParsePath(r, &req); err != nil {
+			xhttp.JsonBaseResponseCtx(r.Context(), w, err)
+			return
+		}
+
+		l := logic.NewReceiptLogic(r.Context(), svcCtx)
+		resp, err := l.Receipt(&req, r)
+		if err != nil {
+			xhttp.JsonBaseResponseCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJsonCtx(r.Context(), w, resp)
+		}
+	}
+}
@@ -25,4 +25,15 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 		),
 		rest.WithPrefix("/api/v1/gateway"),
 	)
+
+	server.AddRoutes(
+		[]rest.Route{
+			{
+				Method:  http.MethodPost,
+				Path:    "/receipt/:vendor/:token",
+				Handler: ReceiptHandler(serverCtx),
+			},
+		},
+		rest.WithPrefix("/api/v1/gateway"),
+	)
 }
@@ -0,0 +1,226 @@
+package logic
+
+import (
+	"chihqiang/msgbox-go/services/common/errs"
+	"chihqiang/msgbox-go/services/common/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"chihqiang/msgbox-go/services/gateway/api/internal/types"
+
+	xerrors "github.com/zeromicro/x/errors"
+	xhttp "github.com/zeromicro/x/http"
+	"gorm.io/gorm/clause"
+)
+
+// idempotencyKeyMaxLength 幂等键最大长度，与 models.Idempotency.Key 的字段长度一致
+const idempotencyKeyMaxLength = 128
+
+// sendIdempotent 携带幂等键发送：首次请求先占用幂等键再发送，批次创建后立即记录批次编号，
+// 发送完成后保存响应或错误；有效期内的重放请求返回首次请求的结果，请求内容不同时返回冲突错误
+func (l *SendLogic) sendIdempotent(username, password, key string, req *types.SendRequest) (*types.SendResponse, error) {
+	if len(key) > idempotencyKeyMaxLength {
+		l.Logger.Errorf("Send idempotency key is too long: %d", len(key))
+		return nil, errs.ErrParamInvalid
+	}
+	var agent models.Agent
+	_ = l.svcCtx.DB.Where(models.Agent{AgentNo: username, AgentSecret: password}).First(&agent).Error
+	if agent.ID == 0 {
+		// 认证失败交由发送流水线返回对应错误
+		return l.send(username, password, req, nil)
+	}
+	hash, err := l.requestHash(req)
+	if err != nil {
+		l.Logger.Errorf("Send hash request failed, err: %v", err)
+		return nil, errs.ErrParamInvalid
+	}
+	idempotency, replay, err := l.reserveIdempotency(agent.ID, key, hash, req)
+	if err != nil || replay != nil {
+		return replay, err
+	}
+	stop := l.renewLease(idempotency)
+	resp, err := l.send(username, password, req, func(batch *models.SendBatch) {
+		// 批次创建后立即记录批次编号，同步发送期间的重试返回处理中而不是再创建一个批次
+		if err := l.svcCtx.DB.Model(idempotency).Update("batch_no", batch.BatchNo).Error; err != nil {
+			l.Logger.Errorf("Send save idempotency batch no failed, err: %v", err)
+		}
+	})
+	stop()
+	if resp == nil {
+		// 批次未创建时释放幂等键，调用方可以使用同一个键重试
+		if err := l.svcCtx.DB.Delete(idempotency).Error; err != nil {
+			l.Logger.Errorf("Send release idempotency key failed, err: %v", err)
+		}
+		return nil, err
+	}
+	// 批次已创建时保留幂等键并保存首次请求的结果，批次创建后的步骤失败时重放同样返回该错误
+	errCode, errMsg := idempotencyError(err)
+	if err := l.svcCtx.DB.Model(idempotency).Updates(map[string]interface{}{
+		"batch_no":    resp.BatchNo,
+		"response":    models.MapToDataTypesJSON(resp),
+		"err_code":    errCode,
+		"err_msg":     errMsg,
+		"lease_until": nil,
+	}).Error; err != nil {
+		l.Logger.Errorf("Send save idempotency response failed, err: %v", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// idempotencyError 保存的错误码与错误信息，无错误时错误码为 0；
+// 非业务错误与 x/http 的响应规则一致，使用 BusinessCodeError
+func idempotencyError(err error) (int, string) {
+	if err == nil {
+		return 0, ""
+	}
+	var codeMsg *xerrors.CodeMsg
+	if errors.As(err, &codeMsg) {
+		return codeMsg.Code, codeMsg.Msg
+	}
+	return xhttp.BusinessCodeError, err.Error()
+}
+
+// renewLease 发送期间定时续期处理租约，避免同步发送耗时超过租约时被误判为已中断；返回的函数停止续期
+func (l *SendLogic) renewLease(idempotency *models.Idempotency) func() {
+	interval := l.svcCtx.Config.Idempotency.Lease / 2
+	if interval <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				lease := now.Add(l.svcCtx.Config.Idempotency.Lease)
+				if err := l.svcCtx.DB.Model(&models.Idempotency{}).Where("id = ?", idempotency.ID).Update("lease_until", lease).Error; err != nil {
+					l.Logger.Errorf("Send renew idempotency lease failed, err: %v", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// requestHash 请求内容摘要，不包含幂等键本身
+func (l *SendLogic) requestHash(req *types.SendRequest) (string, error) {
+	payload := *req
+	payload.IdempotencyKey = ""
+	payload.IdempotencyHeader = ""
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// reserveIdempotency 占用幂等键，键已存在且未过期时返回首次请求的结果；过期的键删除后重新占用，
+// 首次请求的处理租约到期仍未创建批次时（如进程中断）由当前请求接管
+func (l *SendLogic) reserveIdempotency(agentID int64, key, hash string, req *types.SendRequest) (*models.Idempotency, *types.SendResponse, error) {
+	for i := 0; i < 2; i++ {
+		now := time.Now()
+		lease := now.Add(l.svcCtx.Config.Idempotency.Lease)
+		idempotency := &models.Idempotency{
+			AgentID:     agentID,
+			Key:         key,
+			RequestHash: hash,
+			LeaseUntil:  &lease,
+			ExpireTime:  now.Add(l.svcCtx.Config.Idempotency.Window),
+		}
+		result := l.svcCtx.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(idempotency)
+		if result.Error != nil {
+			l.Logger.Errorf("Send reserve idempotency key failed, err: %v", result.Error)
+			return nil, nil, errs.ErrDB
+		}
+		if result.RowsAffected > 0 {
+			return idempotency, nil, nil
+		}
+		var exists models.Idempotency
+		if err := l.svcCtx.DB.Where(&models.Idempotency{AgentID: agentID, Key: key}).First(&exists).Error; err != nil {
+			// 并发删除了过期的键，重新占用
+			continue
+		}
+		if exists.ExpireTime.Before(now) {
+			if err := l.svcCtx.DB.Where("id = ? AND expire_time < ?", exists.ID, now).Delete(&models.Idempotency{}).Error; err != nil {
+				l.Logger.Errorf("Send delete expired idempotency key failed, err: %v", err)
+				return nil, nil, errs.ErrDB
+			}
+			continue
+		}
+		if exists.RequestHash != hash {
+			l.Logger.Errorf("Send idempotency key %s reused with different payload", key)
+			return nil, nil, errs.ErrIdempotencyConflict
+		}
+		if exists.BatchNo == "" {
+			return l.takeoverIdempotency(&exists, now, lease)
+		}
+		resp, err := l.replayIdempotency(&exists, now, req)
+		return nil, resp, err
+	}
+	return nil, nil, errs.ErrIdempotencyProcessing
+}
+
+// replayIdempotency 返回首次请求的响应或错误；首次请求仍在发送中时返回处理中，
+// 租约到期仍未保存结果时首次请求已中断（如进程退出），按批次当前的发送结果返回
+func (l *SendLogic) replayIdempotency(exists *models.Idempotency, now time.Time, req *types.SendRequest) (*types.SendResponse, error) {
+	if len(exists.Response) == 0 {
+		if exists.LeaseUntil != nil && exists.LeaseUntil.After(now) {
+			return nil, errs.ErrIdempotencyProcessing
+		}
+		var batch models.SendBatch
+		if err := l.svcCtx.DB.Where(&models.SendBatch{AgentID: exists.AgentID, BatchNo: exists.BatchNo}).First(&batch).Error; err != nil {
+			l.Logger.Errorf("Send query idempotency batch %s failed, err: %v", exists.BatchNo, err)
+			return nil, errs.ErrDB
+		}
+		l.Logger.Infof("Send replay interrupted idempotency key %s, batch no: %s", exists.Key, exists.BatchNo)
+		return l.batchResponse(batch.TraceID, &batch, sendStatus(req))
+	}
+	if exists.ErrCode != 0 {
+		l.Logger.Infof("Send replay idempotency key %s error %d, batch no: %s", exists.Key, exists.ErrCode, exists.BatchNo)
+		return nil, xerrors.New(exists.ErrCode, exists.ErrMsg)
+	}
+	var resp types.SendResponse
+	if err := json.Unmarshal(exists.Response, &resp); err != nil {
+		l.Logger.Errorf("Send decode idempotency response failed, err: %v", err)
+		return nil, errs.ErrDB
+	}
+	l.Logger.Infof("Send replay idempotency key %s, batch no: %s", exists.Key, exists.BatchNo)
+	return &resp, nil
+}
+
+// takeoverIdempotency 接管处理租约已到期的幂等键，租约未到期或已被其他请求接管时返回处理中
+func (l *SendLogic) takeoverIdempotency(exists *models.Idempotency, now, lease time.Time) (*models.Idempotency, *types.SendResponse, error) {
+	if exists.LeaseUntil == nil || exists.LeaseUntil.After(now) {
+		return nil, nil, errs.ErrIdempotencyProcessing
+	}
+	result := l.svcCtx.DB.Model(&models.Idempotency{}).
+		Where("id = ? AND batch_no = ? AND lease_until < ?", exists.ID, "", now).
+		Update("lease_until", lease)
+	if result.Error != nil {
+		l.Logger.Errorf("Send take over idempotency key failed, err: %v", result.Error)
+		return nil, nil, errs.ErrDB
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil, errs.ErrIdempotencyProcessing
+	}
+	l.Logger.Infof("Send take over idempotency key %s after lease expired", exists.Key)
+	exists.LeaseUntil = &lease
+	return exists, nil, nil
+}
@@ -0,0 +1,263 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chihqiang/msgbox-go/services/common/errs"
+	"chihqiang/msgbox-go/services/common/models"
+	"chihqiang/msgbox-go/services/common/models/modeltest"
+	"chihqiang/msgbox-go/services/gateway/api/internal/config"
+	"chihqiang/msgbox-go/services/gateway/api/internal/svc"
+	"chihqiang/msgbox-go/services/gateway/api/internal/types"
+
+	xerrors "github.com/zeromicro/x/errors"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// sendFixture 代理商及其 webhook 模版，发送请求以该代理商的 BasicAuth 身份提交
+type sendFixture struct {
+	svcCtx *svc.ServiceContext
+	ctx    context.Context
+	agent  *models.Agent
+}
+
+func newSendFixture(t *testing.T) *sendFixture {
+	t.Helper()
+	db := modeltest.NewDB(t)
+	agent := &models.Agent{Email: "tester@example.com", Password: "x", Status: true}
+	if err := db.Create(agent).Error; err != nil {
+		t.Fatal(err)
+	}
+	channel := &models.Channel{AgentID: agent.ID, Code: "hook", VendorName: "webhook", Config: datatypes.JSON(`{}`), Status: true}
+	if err := db.Create(channel).Error; err != nil {
+		t.Fatal(err)
+	}
+	template := &models.Template{AgentID: agent.ID, ChannelID: channel.ID, Name: "notice", Code: "notice", Content: "hi", Status: true}
+	if err := db.Create(template).Error; err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.WithValue(context.Background(), types.BasicAuthUsername, agent.AgentNo)
+	ctx = context.WithValue(ctx, types.BasicAuthPassword, agent.AgentSecret)
+	return &sendFixture{
+		svcCtx: &svc.ServiceContext{
+			Config: config.Config{Idempotency: config.IdempotencyConf{Window: time.Hour, Lease: time.Minute}},
+			DB:     db,
+		},
+		ctx:   ctx,
+		agent: agent,
+	}
+}
+
+func (f *sendFixture) send(req *types.SendRequest) (*types.SendResponse, error) {
+	return NewSendLogic(f.ctx, f.svcCtx).Send(req)
+}
+
+func (f *sendFixture) count(t *testing.T, model any) int64 {
+	t.Helper()
+	var count int64
+	if err := f.svcCtx.DB.Model(model).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestSendIdempotentReplay(t *testing.T) {
+	f := newSendFixture(t)
+	req := &types.SendRequest{TemplateCode: "notice", Receivers: []string{"u1"}, Async: true, IdempotencyKey: "k1"}
+	first, err := f.send(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := f.send(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.BatchNo != first.BatchNo || f.count(t, &models.SendBatch{}) != 1 {
+		t.Errorf("replay created a new batch: %s vs %s", second.BatchNo, first.BatchNo)
+	}
+	conflict := *req
+	conflict.Receivers = []string{"u2"}
+	if _, err := f.send(&conflict); !errors.Is(err, errs.ErrIdempotencyConflict) {
+		t.Errorf("err = %v, want %v", err, errs.ErrIdempotencyConflict)
+	}
+}
+
+// TestSendIdempotentReleaseBeforeBatch 批次创建前失败时释放幂等键
+func TestSendIdempotentReleaseBeforeBatch(t *testing.T) {
+	f := newSendFixture(t)
+	req := &types.SendRequest{TemplateCode: "missing", Receivers: []string{"u1"}, Async: true, IdempotencyKey: "k1"}
+	if _, err := f.send(req); !errors.Is(err, errs.ErrTemplateCodeMissing) {
+		t.Fatalf("err = %v, want %v", err, errs.ErrTemplateCodeMissing)
+	}
+	if n := f.count(t, &models.Idempotency{}); n != 0 {
+		t.Errorf("idempotency keys = %d, want released", n)
+	}
+}
+
+// TestSendIdempotentKeepAfterBatch 批次创建后的步骤失败时保留幂等键与批次编号，重试返回首次请求的错误且不会重复创建批次
+func TestSendIdempotentKeepAfterBatch(t *testing.T) {
+	f := newSendFixture(t)
+	// 同步发送开始时更新批次失败，此时批次与记录已经入库
+	failing := true
+	err := f.svcCtx.DB.Callback().Update().Before("gorm:update").Register("test:fail_batch_update", func(tx *gorm.DB) {
+		if failing && tx.Statement.Table == (&models.SendBatch{}).TableName() {
+			_ = tx.AddError(errors.New("batch update failed"))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &types.SendRequest{TemplateCode: "notice", Receivers: []string{"u1"}, IdempotencyKey: "k1"}
+	if _, err := f.send(req); !errors.Is(err, errs.ErrDB) {
+		t.Fatalf("err = %v, want %v", err, errs.ErrDB)
+	}
+	var idempotency models.Idempotency
+	if err := f.svcCtx.DB.First(&idempotency).Error; err != nil {
+		t.Fatalf("idempotency key released: %v", err)
+	}
+	var batch models.SendBatch
+	if err := f.svcCtx.DB.First(&batch).Error; err != nil {
+		t.Fatal(err)
+	}
+	if idempotency.BatchNo != batch.BatchNo {
+		t.Errorf("idempotency batch no = %q, want %q", idempotency.BatchNo, batch.BatchNo)
+	}
+	failing = false
+	_, err = f.send(req)
+	var codeMsg *xerrors.CodeMsg
+	if !errors.As(err, &codeMsg) || codeMsg.Code != errs.ErrCodeDB {
+		t.Fatalf("retry err = %v, want replayed %v", err, errs.ErrDB)
+	}
+	if n := f.count(t, &models.SendBatch{}); n != 1 {
+		t.Errorf("batches = %d, want 1", n)
+	}
+}
+
+// TestSendIdempotentInFlight 同步发送期间批次编号已记录、租约持续续期，重复请求返回处理中而不是再创建一个批次
+func TestSendIdempotentInFlight(t *testing.T) {
+	f := newSendFixture(t)
+	f.svcCtx.Config.Idempotency.Lease = 100 * time.Millisecond
+	req := &types.SendRequest{TemplateCode: "notice", Receivers: []string{"u1"}, IdempotencyKey: "k1"}
+	var (
+		inFlight    models.Idempotency
+		inFlightErr error
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 发送耗时超过租约，续期后租约仍有效
+		time.Sleep(250 * time.Millisecond)
+		f.svcCtx.DB.First(&inFlight)
+		_, inFlightErr = f.send(req)
+	}))
+	defer server.Close()
+	config := datatypes.JSON(`{"url":"` + server.URL + `"}`)
+	if err := f.svcCtx.DB.Model(&models.Channel{}).Where("agent_id = ?", f.agent.ID).Update("config", config).Error; err != nil {
+		t.Fatal(err)
+	}
+	first, err := f.send(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inFlight.BatchNo != first.BatchNo {
+		t.Errorf("in-flight batch no = %q, want %q", inFlight.BatchNo, first.BatchNo)
+	}
+	if inFlight.LeaseUntil == nil || !inFlight.LeaseUntil.After(inFlight.CreatedAt.Add(f.svcCtx.Config.Idempotency.Lease)) {
+		t.Errorf("lease not renewed: %v, created at %v", inFlight.LeaseUntil, inFlight.CreatedAt)
+	}
+	if !errors.Is(inFlightErr, errs.ErrIdempotencyProcessing) {
+		t.Errorf("in-flight retry err = %v, want %v", inFlightErr, errs.ErrIdempotencyProcessing)
+	}
+	if n := f.count(t, &models.SendBatch{}); n != 1 {
+		t.Errorf("batches = %d, want 1", n)
+	}
+	replay, err := f.send(req)
+	if err != nil || replay.BatchNo != first.BatchNo {
+		t.Errorf("replay = %+v, err = %v", replay, err)
+	}
+}
+
+// TestSendIdempotentInterrupted 批次已创建但首次请求中断未保存结果时，租约到期后按批次返回
+func TestSendIdempotentInterrupted(t *testing.T) {
+	tests := []struct {
+		name    string
+		lease   time.Duration
+		wantErr error
+	}{
+		{"lease active", time.Minute, errs.ErrIdempotencyProcessing},
+		{"lease expired", -time.Minute, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSendFixture(t)
+			req := &types.SendRequest{TemplateCode: "notice", Receivers: []string{"u1"}, Async: true}
+			first, err := f.send(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.IdempotencyKey = "k1"
+			hash, err := NewSendLogic(f.ctx, f.svcCtx).requestHash(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lease := time.Now().Add(tt.lease)
+			interrupted := &models.Idempotency{AgentID: f.agent.ID, Key: "k1", RequestHash: hash, BatchNo: first.BatchNo, LeaseUntil: &lease, ExpireTime: time.Now().Add(time.Hour)}
+			if err := f.svcCtx.DB.Create(interrupted).Error; err != nil {
+				t.Fatal(err)
+			}
+			resp, err := f.send(req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (resp.BatchNo != first.BatchNo || resp.Status != types.SendStatusPending) {
+				t.Errorf("resp = %+v, want batch %s", resp, first.BatchNo)
+			}
+			if n := f.count(t, &models.SendBatch{}); n != 1 {
+				t.Errorf("batches = %d, want 1", n)
+			}
+		})
+	}
+}
+
+// TestSendIdempotentLease 首次请求处理中时重复请求返回处理中，租约到期后可被接管
+func TestSendIdempotentLease(t *testing.T) {
+	tests := []struct {
+		name    string
+		lease   time.Duration
+		wantErr error
+	}{
+		{"lease active", time.Minute, errs.ErrIdempotencyProcessing},
+		{"lease expired", -time.Minute, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSendFixture(t)
+			req := &types.SendRequest{TemplateCode: "notice", Receivers: []string{"u1"}, Async: true, IdempotencyKey: "k1"}
+			hash, err := NewSendLogic(f.ctx, f.svcCtx).requestHash(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lease := time.Now().Add(tt.lease)
+			processing := &models.Idempotency{AgentID: f.agent.ID, Key: "k1", RequestHash: hash, LeaseUntil: &lease, ExpireTime: time.Now().Add(time.Hour)}
+			if err := f.svcCtx.DB.Create(processing).Error; err != nil {
+				t.Fatal(err)
+			}
+			resp, err := f.send(req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			var saved models.Idempotency
+			f.svcCtx.DB.First(&saved, processing.ID)
+			if saved.BatchNo != resp.BatchNo {
+				t.Errorf("taken over key batch no = %q, want %q", saved.BatchNo, resp.BatchNo)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"chihqiang/msgbox-go/pkg/stringx"
+	"chihqiang/msgbox-go/services/common/channels/senders"
+	"chihqiang/msgbox-go/services/common/errs"
+	"chihqiang/msgbox-go/services/common/models"
+	"context"
+	"errors"
+	"net/http"
+
+	"chihqiang/msgbox-go/services/gateway/api/internal/svc"
+	"chihqiang/msgbox-go/services/gateway/api/internal/types"
+
+	"github.com/samber/lo"
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+)
+
+type ReceiptLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewReceiptLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ReceiptLogic {
+	return &ReceiptLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// Receipt 校验回执令牌后解析服务商回执，更新令牌所属通道下已受理的发送记录，返回服务商要求的应答内容
+func (l *ReceiptLogic) Receipt(req *types.ReceiptRequest, r *http.Request) (resp any, err error) {
+	form, ok := senders.Get(req.Vendor)
+	if !ok {
+		l.Logger.Errorf("Receipt unknown vendor: %s", req.Vendor)
+		return nil, errs.ErrReceiptUnsupported
+	}
+	// 回调不携带通道配置，使用未配置的发送器实例解析
+	sender, ok := form.New().(senders.IReceiptSender)
+	if !ok {
+		l.Logger.Errorf("Receipt vendor %s does not support receipt", req.Vendor)
+		return nil, errs.ErrReceiptUnsupported
+	}
+	channel, err := l.channel(req.Vendor, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := sender.ParseReceipts(r)
+	if err != nil {
+		l.Logger.Errorf("Receipt parse %s receipts failed, err: %v", req.Vendor, err)
+		return sender.ReceiptAck(err), nil
+	}
+	// 尚未受理的回执不影响同一请求中其他回执的处理，全部处理后再应答失败让服务商重推
+	var pending error
+	for _, receipt := range receipts {
+		switch err := l.apply(channel, receipt); {
+		case errors.Is(err, errs.ErrReceiptPending):
+			pending = err
+		case err != nil:
+			return sender.ReceiptAck(err), nil
+		}
+	}
+	return sender.ReceiptAck(pending), nil
+}
+
+// channel 按服务商与回执令牌查询通道，令牌为空或不匹配时拒绝回调
+func (l *ReceiptLogic) channel(vendor, token string) (*models.Channel, error) {
+	if token == "" {
+		return nil, errs.ErrReceiptTokenInvalid
+	}
+	var channel models.Channel
+	err := l.svcCtx.DB.Where("vendor_name = ? AND receipt_token = ?", vendor, token).First(&channel).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		l.Logger.Errorf("Receipt invalid token for vendor: %s", vendor)
+		return nil, errs.ErrReceiptTokenInvalid
+	}
+	if err != nil {
+		l.Logger.Errorf("query receipt channel failed, err: %v", err)
+		return nil, errs.ErrDB
+	}
+	return &channel, nil
+}
+
+// apply 按服务商消息ID匹配通道下的记录，回执携带接收者时再按规范化后的号码精确匹配；
+// 已处理过的记录不满足状态转换条件，服务商重推时不会重复更新；
+// 没有匹配记录时通常是受理结果尚未落库，返回 ErrReceiptPending 让服务商稍后重推
+func (l *ReceiptLogic) apply(channel *models.Channel, receipt senders.Receipt) error {
+	if receipt.MessageID == "" {
+		return nil
+	}
+	var records []*models.SendRecord
+	if err := l.svcCtx.DB.
+		Where("channel_id = ? AND vendor_msg_id = ?", channel.ID, receipt.MessageID).
+		Find(&records).Error; err != nil {
+		l.Logger.Errorf("query send records by vendor msg id failed, err: %v", err)
+		return errs.ErrDB
+	}
+	if receipt.Receiver != "" {
+		receiver := stringx.NormalizeMobile(receipt.Receiver)
+		records = lo.Filter(records, func(record *models.SendRecord, _ int) bool {
+			return stringx.NormalizeMobile(record.Receiver) == receiver
+		})
+	}
+	if len(records) == 0 {
+		l.Logger.Infof("Receipt no send record matched yet, channel: %d, msg id: %s", channel.ID, receipt.MessageID)
+		return errs.ErrReceiptPending
+	}
+	records = lo.Filter(records, func(record *models.SendRecord, _ int) bool {
+		return record.Status == models.SendRecordStatusAccepted
+	})
+	if len(records) == 0 {
+		return nil
+	}
+	status := models.SendRecordStatusDelivered
+	updates := map[string]interface{}{
+		"delivery_time": receipt.Time,
+		"delivery_raw":  models.MapToDataTypesJSON(receipt.Raw),
+	}
+	if !receipt.Delivered {
+		status = models.SendRecordStatusFailed
+		updates["error"] = receipt.Error
+		updates["fail_reason"] = models.SendRecordFailReasonReceipt
+	}
+	return l.svcCtx.DB.Transaction(func(tx *gorm.DB) error {
+		for _, record := range records {
+			affected, err := models.TransitionSendRecords(tx, []int64{record.ID}, status, updates)
+			if err != nil {
+				l.Logger.Errorf("update send record receipt failed, err: %v", err)
+				return errs.ErrDB
+			}
+			if affected == 0 || status != models.SendRecordStatusFailed {
+				continue
+			}
+			// 受理时已计入成功条数，回执失败时改计为失败
+			if err := tx.Model(&models.SendBatch{}).
+				Where(&models.SendBatch{ID: record.BatchID}).
+				UpdateColumns(map[string]interface{}{
+					"success_count": gorm.Expr("success_count - ?", 1),
+					"fail_count":    gorm.Expr("fail_count + ?", 1),
+				}).Error; err != nil {
+				l.Logger.Errorf("update send batch count failed, err: %v", err)
+				return errs.ErrDB
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,160 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"chihqiang/msgbox-go/services/common/errs"
+	"chihqiang/msgbox-go/services/common/models"
+	"chihqiang/msgbox-go/services/common/models/modeltest"
+	"chihqiang/msgbox-go/services/gateway/api/internal/svc"
+	"chihqiang/msgbox-go/services/gateway/api/internal/types"
+
+	"gorm.io/datatypes"
+)
+
+// receiptFixture 两个阿里云短信通道及其所属批次
+type receiptFixture struct {
+	svcCtx   *svc.ServiceContext
+	batch    *models.SendBatch
+	channels []*models.Channel
+}
+
+func newReceiptFixture(t *testing.T) *receiptFixture {
+	t.Helper()
+	db := modeltest.NewDB(t)
+	f := &receiptFixture{svcCtx: &svc.ServiceContext{DB: db}}
+	for _, code := range []string{"sms-a", "sms-b"} {
+		channel := &models.Channel{AgentID: 1, Code: code, VendorName: "aliyun_sms", Config: datatypes.JSON(`{}`), Status: true}
+		if err := db.Create(channel).Error; err != nil {
+			t.Fatal(err)
+		}
+		f.channels = append(f.channels, channel)
+	}
+	f.batch = &models.SendBatch{AgentID: 1, ChannelID: f.channels[0].ID, BatchNo: "B1", TraceID: "T1", TotalCount: 2, SuccessCount: 2}
+	if err := db.Create(f.batch).Error; err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// record 创建一条发送记录，vendorMsgID 为空表示受理结果尚未落库
+func (f *receiptFixture) record(t *testing.T, channel *models.Channel, receiver string, status int, vendorMsgID string) *models.SendRecord {
+	t.Helper()
+	record := &models.SendRecord{
+		BatchID: f.batch.ID, AgentID: 1, ChannelID: channel.ID, TraceID: "T1", Receiver: receiver,
+		VendorName: "aliyun_sms", ChannelConfig: datatypes.JSON(`{}`), Content: "hi", Status: status, VendorMsgID: vendorMsgID,
+	}
+	if err := f.svcCtx.DB.Create(record).Error; err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+// push 以指定令牌推送阿里云状态报告，返回应答中的 code
+func (f *receiptFixture) push(t *testing.T, token, body string) (int, error) {
+	t.Helper()
+	request := httptest.NewRequest("POST", "/api/v1/gateway/receipt/aliyun_sms/"+token, strings.NewReader(body))
+	resp, err := NewReceiptLogic(context.Background(), f.svcCtx).Receipt(&types.ReceiptRequest{Vendor: "aliyun_sms", Token: token}, request)
+	if err != nil {
+		return 0, err
+	}
+	return resp.(map[string]any)["code"].(int), nil
+}
+
+func (f *receiptFixture) status(t *testing.T, record *models.SendRecord) int {
+	t.Helper()
+	var updated models.SendRecord
+	if err := f.svcCtx.DB.First(&updated, record.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return updated.Status
+}
+
+func TestReceiptToken(t *testing.T) {
+	f := newReceiptFixture(t)
+	if f.channels[0].ReceiptToken == "" || f.channels[0].ReceiptToken == f.channels[1].ReceiptToken {
+		t.Fatalf("receipt tokens = %q, %q", f.channels[0].ReceiptToken, f.channels[1].ReceiptToken)
+	}
+	body := `[{"phone_number":"13800000000","success":true,"biz_id":"biz-1"}]`
+	for _, token := range []string{"", "unknown"} {
+		if _, err := f.push(t, token, body); !errors.Is(err, errs.ErrReceiptTokenInvalid) {
+			t.Errorf("token %q: err = %v, want %v", token, err, errs.ErrReceiptTokenInvalid)
+		}
+	}
+	// 令牌只能更新所属通道的记录
+	other := f.record(t, f.channels[1], "13800000000", models.SendRecordStatusAccepted, "biz-1")
+	code, err := f.push(t, f.channels[0].ReceiptToken, body)
+	if err != nil || code == 0 {
+		t.Errorf("push to other channel: code=%d err=%v, want retryable ack", code, err)
+	}
+	if status := f.status(t, other); status != models.SendRecordStatusAccepted {
+		t.Errorf("record of other channel updated to %d", status)
+	}
+	if code, err := f.push(t, f.channels[1].ReceiptToken, body); err != nil || code != 0 {
+		t.Fatalf("push: code=%d err=%v", code, err)
+	}
+	if status := f.status(t, other); status != models.SendRecordStatusDelivered {
+		t.Errorf("status = %d, want delivered", status)
+	}
+}
+
+func TestReceiptReceiverMatch(t *testing.T) {
+	f := newReceiptFixture(t)
+	channel := f.channels[0]
+	target := f.record(t, channel, "+86 138-0000-0000", models.SendRecordStatusAccepted, "biz-1")
+	// 与回执号码存在包含或后缀关系，但不是同一号码
+	similar := f.record(t, channel, "913800000000", models.SendRecordStatusAccepted, "biz-1")
+	body := `[{"phone_number":"8613800000000","success":false,"err_code":"MOBILE_NOT_ON_SERVICE","err_msg":"停机","biz_id":"biz-1"}]`
+	if code, err := f.push(t, channel.ReceiptToken, body); err != nil || code != 0 {
+		t.Fatalf("push: code=%d err=%v", code, err)
+	}
+	if status := f.status(t, target); status != models.SendRecordStatusFailed {
+		t.Errorf("target status = %d, want failed", status)
+	}
+	if status := f.status(t, similar); status != models.SendRecordStatusAccepted {
+		t.Errorf("similar receiver updated to %d", status)
+	}
+	var batch models.SendBatch
+	f.svcCtx.DB.First(&batch, f.batch.ID)
+	if batch.SuccessCount != 1 || batch.FailCount != 1 {
+		t.Errorf("batch counts success=%d fail=%d, want 1/1", batch.SuccessCount, batch.FailCount)
+	}
+	// 重推不会重复计数
+	if code, err := f.push(t, channel.ReceiptToken, body); err != nil || code != 0 {
+		t.Fatalf("repush: code=%d err=%v", code, err)
+	}
+	f.svcCtx.DB.First(&batch, f.batch.ID)
+	if batch.SuccessCount != 1 || batch.FailCount != 1 {
+		t.Errorf("repush changed batch counts success=%d fail=%d", batch.SuccessCount, batch.FailCount)
+	}
+}
+
+func TestReceiptBeforeAccepted(t *testing.T) {
+	f := newReceiptFixture(t)
+	channel := f.channels[0]
+	// 服务商已返回受理结果，但 markSuccess 尚未提交
+	record := f.record(t, channel, "13800000000", models.SendRecordStatusSending, "")
+	accepted := f.record(t, channel, "13800000001", models.SendRecordStatusAccepted, "biz-2")
+	body := `[{"phone_number":"13800000000","success":true,"biz_id":"biz-1"},{"phone_number":"13800000001","success":true,"biz_id":"biz-2"}]`
+	code, err := f.push(t, channel.ReceiptToken, body)
+	if err != nil || code == 0 {
+		t.Fatalf("early receipt: code=%d err=%v, want retryable ack", code, err)
+	}
+	// 同一请求中已受理的记录照常更新
+	if status := f.status(t, accepted); status != models.SendRecordStatusDelivered {
+		t.Errorf("accepted record status = %d, want delivered", status)
+	}
+	if err := f.svcCtx.DB.Model(record).Updates(map[string]any{"status": models.SendRecordStatusAccepted, "vendor_msg_id": "biz-1"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if code, err := f.push(t, channel.ReceiptToken, body); err != nil || code != 0 {
+		t.Fatalf("repush: code=%d err=%v", code, err)
+	}
+	if status := f.status(t, record); status != models.SendRecordStatusDelivered {
+		t.Errorf("status = %d, want delivered", status)
+	}
+}
@@ -4,11 +4,14 @@
 package logic
 
 import (
+	"chihqiang/msgbox-go/pkg/timex"
 	"chihqiang/msgbox-go/services/common/errs"
 	"chihqiang/msgbox-go/services/common/models"
 	"chihqiang/msgbox-go/services/common/pipeline"
+	"chihqiang/msgbox-go/services/common/pipeline/tasks"
 	"context"
 	"github.com/zeromicro/go-zero/core/trace"
+	"time"
 
 	"chihqiang/msgbox-go/services/gateway/api/internal/svc"
 	"chihqiang/msgbox-go/services/gateway/api/internal/types"
@@ -41,22 +44,100 @@ func (l *SendLogic) Send(req *types.SendRequest) (resp *types.SendResponse, err
 		l.Logger.Errorf("Send missing valid password from ctx, username: %s", username)
 		return nil, errs.ErrAuthInvalid
 	}
-	traceID := trace.TraceIDFromContext(l.ctx)
-	send, err := l.sendPipeline(traceID, username, password, req)
+	// 请求头中的幂等键优先
+	key := req.IdempotencyKey
+	if req.IdempotencyHeader != "" {
+		key = req.IdempotencyHeader
+	}
+	if key != "" {
+		return l.sendIdempotent(username, password, key, req)
+	}
+	if resp, err = l.send(username, password, req, nil); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// send 执行发送流水线；批次创建后的步骤失败时同时返回批次的响应与错误，便于幂等键记录已创建的批次。
+// onBatch 不为 nil 时在批次创建后、发送前调用
+func (l *SendLogic) send(username, password string, req *types.SendRequest, onBatch func(batch *models.SendBatch)) (resp *types.SendResponse, err error) {
+	sendAt, err := l.sendAt(req)
 	if err != nil {
+		return nil, err
+	}
+	traceID := trace.TraceIDFromContext(l.ctx)
+	send, err := l.sendPipeline(traceID, username, password, sendAt, req, onBatch)
+	if send == nil {
 		l.Logger.Errorf("Send failed, err: %v", err)
 		return nil, err
 	}
-	return &types.SendResponse{
+	if err != nil {
+		l.Logger.Errorf("Send batch %s failed, err: %v", send.BatchNo, err)
+		resp, _ = l.batchResponse(traceID, send, sendStatus(req))
+		return resp, err
+	}
+	return l.batchResponse(traceID, send, sendStatus(req))
+}
+
+// sendStatus 批次创建后的响应状态：定时发送、异步发送或已同步发送完成
+func sendStatus(req *types.SendRequest) string {
+	switch {
+	case req.SendAt != "" || req.DelaySeconds > 0:
+		return types.SendStatusScheduled
+	case req.Async:
+		return types.SendStatusPending
+	default:
+		return types.SendStatusFinished
+	}
+}
+
+// batchResponse 批次的发送响应，包含各通道的发送统计
+func (l *SendLogic) batchResponse(traceID string, send *models.SendBatch, status string) (*types.SendResponse, error) {
+	resp := &types.SendResponse{
 		TraceID:      traceID,
 		BatchNo:      send.BatchNo,
+		Status:       status,
 		FailCount:    send.FailCount,
 		SuccessCount: send.SuccessCount,
+		Channels:     []types.SendChannelStat{},
 		Time:         send.CreatedAt.UnixMicro(),
-	}, nil
+	}
+	stats, err := models.QuerySendBatchChannelStats(l.svcCtx.DB, []int64{send.ID})
+	if err != nil {
+		l.Logger.Errorf("Send query channel stats failed, err: %v", err)
+		return resp, errs.ErrDB
+	}
+	for _, stat := range stats {
+		resp.Channels = append(resp.Channels, types.SendChannelStat{
+			ChannelID:    stat.ChannelID,
+			VendorName:   stat.VendorName,
+			TotalCount:   stat.TotalCount,
+			SuccessCount: stat.SuccessCount,
+			FailCount:    stat.FailCount,
+		})
+	}
+	return resp, nil
+}
+
+// sendAt 解析计划发送时间，send_at 优先于 delay_seconds，均未设置时返回 nil
+func (l *SendLogic) sendAt(req *types.SendRequest) (*time.Time, error) {
+	if req.SendAt != "" {
+		sendAt, err := timex.ParseDateTime(req.SendAt)
+		if err != nil || sendAt.Before(time.Now()) {
+			l.Logger.Errorf("Send invalid send_at: %s", req.SendAt)
+			return nil, errs.ErrSendAtInvalid
+		}
+		return &sendAt, nil
+	}
+	if req.DelaySeconds > 0 {
+		sendAt := time.Now().Add(time.Duration(req.DelaySeconds) * time.Second)
+		return &sendAt, nil
+	}
+	return nil, nil
 }
 
-func (l *SendLogic) sendPipeline(traceID, agentNo, agentSecret string, req *types.SendRequest) (*models.SendBatch, error) {
+// sendPipeline 检查并发送，批次已创建时即使后续步骤失败也返回批次
+func (l *SendLogic) sendPipeline(traceID, agentNo, agentSecret string, sendAt *time.Time, req *types.SendRequest, onBatch func(batch *models.SendBatch)) (*models.SendBatch, error) {
 	sendPipeline := pipeline.SendPipeline{
 		DB:           l.svcCtx.DB,
 		Log:          l.Logger,
@@ -67,12 +148,45 @@ func (l *SendLogic) sendPipeline(traceID, agentNo, agentSecret string, req *type
 		Receivers:    req.Receivers,
 		Variables:    req.Variables,
 		Extra:        req.Extra,
+		Locale:       req.Locale,
+		Items:        l.items(req),
+		MaxBatchSize: l.svcCtx.Config.MaxBatchSize,
+		Async:        req.Async,
+		SendAt:       sendAt,
 	}
 	if err := sendPipeline.Check(l.ctx); err != nil {
 		return nil, err
 	}
+	if onBatch != nil {
+		onBatch(sendPipeline.Batch())
+	}
+	// 异步或定时发送：批次与记录已入队，交由 worker 服务发送
+	if req.Async || sendAt != nil {
+		batch, err := sendPipeline.GetSendBatch()
+		if err != nil {
+			return sendPipeline.Batch(), err
+		}
+		return batch, nil
+	}
 	if err := sendPipeline.Send(l.ctx); err != nil {
-		return nil, err
+		return sendPipeline.Batch(), err
+	}
+	batch, err := sendPipeline.GetSendBatch()
+	if err != nil {
+		return sendPipeline.Batch(), err
+	}
+	return batch, nil
+}
+
+func (l *SendLogic) items(req *types.SendRequest) []tasks.ReceiverItem {
+	items := make([]tasks.ReceiverItem, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, tasks.ReceiverItem{
+			Receiver:  item.Receiver,
+			Variables: item.Variables,
+			Extra:     item.Extra,
+			Locale:    item.Locale,
+		})
 	}
-	return sendPipeline.GetSendBatch()
+	return items
 }
@@ -6,3 +6,9 @@ const (
 	BasicAuthUsername   = "username"
 	BasicAuthPassword   = "password"
 )
+
+const (
+	SendStatusPending   = "pending"   // 已入队，等待 worker 异步发送
+	SendStatusScheduled = "scheduled" // 已按计划发送时间入队，到期由 worker 发送
+	SendStatusFinished  = "finished"  // 已同步发送完成
+)
@@ -3,17 +3,46 @@
 
 package types
 
+type ReceiptRequest struct {
+	Vendor string `path:"vendor"`
+	Token  string `path:"token"`
+}
+
+type SendChannelStat struct {
+	ChannelID    int64  `json:"channel_id"`
+	VendorName   string `json:"vendor_name"`
+	TotalCount   int    `json:"total_count"`
+	SuccessCount int    `json:"success_count"`
+	FailCount    int    `json:"fail_count"`
+}
+
+type SendItem struct {
+	Receiver  string                 `json:"receiver"`
+	Variables map[string]interface{} `json:"variables,optional"`
+	Extra     map[string]interface{} `json:"extra,optional"`
+	Locale    string                 `json:"locale,optional"`
+}
+
 type SendRequest struct {
-	TemplateCode string                 `json:"template_code,optional"`
-	Receivers    []string               `json:"receivers,optional"`
-	Variables    map[string]string      `json:"variables,optional"`
-	Extra        map[string]interface{} `json:"extra,optional"`
+	TemplateCode      string                 `json:"template_code,optional"`
+	Receivers         []string               `json:"receivers,optional"`
+	Variables         map[string]interface{} `json:"variables,optional"`
+	Extra             map[string]interface{} `json:"extra,optional"`
+	Locale            string                 `json:"locale,optional"`
+	Items             []SendItem             `json:"items,optional"`
+	Async             bool                   `json:"async,optional"`
+	SendAt            string                 `json:"send_at,optional"`
+	DelaySeconds      int64                  `json:"delay_seconds,optional"`
+	IdempotencyKey    string                 `json:"idempotency_key,optional"`
+	IdempotencyHeader string                 `header:"Idempotency-Key,optional"`
 }
 
 type SendResponse struct {
-	TraceID      string `json:"trace_id"`
-	BatchNo      string `json:"batch_no"`
-	FailCount    int    `json:"fail_count"`
-	SuccessCount int    `json:"success_count"`
-	Time         int64  `json:"time"`
+	TraceID      string            `json:"trace_id"`
+	BatchNo      string            `json:"batch_no"`
+	Status       string            `json:"status"`
+	FailCount    int               `json:"fail_count"`
+	SuccessCount int               `json:"success_count"`
+	Channels     []SendChannelStat `json:"channels"`
+	Time         int64             `json:"time"`
 }
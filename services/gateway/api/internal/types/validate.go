@@ -8,5 +8,8 @@ func (r SendRequest) Validate() error {
 	if r.TemplateCode == "" {
 		return errs.ErrParamInvalid
 	}
+	if r.DelaySeconds < 0 {
+		return errs.ErrSendAtInvalid
+	}
 	return nil
 }
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"chihqiang/msgbox-go/services/common/models"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/service"
+)
+
+type Config struct {
+	service.ServiceConf
+	DB     models.Config
+	Worker WorkerConf
+}
+
+type WorkerConf struct {
+	Concurrency  int           `json:",default=10"`  // 同时发送的最大记录数
+	BatchSize    int           `json:",default=100"` // 单次领取的最大记录数
+	PollInterval time.Duration `json:",default=1s"`  // 队列为空时的轮询间隔
+	LockTimeout  time.Duration `json:",default=5m"`  // 领取锁有效期，worker 异常退出后超时记录可被重新领取
+}
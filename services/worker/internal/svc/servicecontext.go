@@ -0,0 +1,27 @@
+package svc
+
+import (
+	"chihqiang/msgbox-go/services/common/models"
+	"chihqiang/msgbox-go/services/worker/internal/config"
+	"os"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+)
+
+type ServiceContext struct {
+	Config config.Config
+	DB     *gorm.DB
+}
+
+func NewServiceContext(c config.Config) *ServiceContext {
+	db, err := models.Connect(c.DB)
+	if err != nil {
+		logx.Errorf("Database connection failed! Error: %v", err)
+		os.Exit(1)
+	}
+	return &ServiceContext{
+		Config: c,
+		DB:     db,
+	}
+}
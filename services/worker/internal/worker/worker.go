@@ -0,0 +1,232 @@
+package worker
+
+import (
+	"chihqiang/msgbox-go/pkg/workflow"
+	"chihqiang/msgbox-go/services/common/models"
+	"chihqiang/msgbox-go/services/common/pipeline/tasks"
+	"chihqiang/msgbox-go/services/worker/internal/svc"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Worker 从数据库队列领取异步发送记录并发送
+// 多个 worker 实例可同时运行，领取时使用 FOR UPDATE SKIP LOCKED 避免重复发送
+type Worker struct {
+	Log    logx.Logger
+	svcCtx *svc.ServiceContext
+	ctx    context.Context
+	cancel context.CancelFunc
+	slots  chan struct{}
+	wg     sync.WaitGroup
+	done   chan struct{}
+}
+
+func NewWorker(svcCtx *svc.ServiceContext) *Worker {
+	ctx, cancel := context.WithCancel(context.Background())
+	concurrency := svcCtx.Config.Worker.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &Worker{
+		Log:    logx.WithContext(ctx),
+		svcCtx: svcCtx,
+		ctx:    ctx,
+		cancel: cancel,
+		slots:  make(chan struct{}, concurrency),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start 循环领取并发送记录，直到 Stop 被调用
+func (w *Worker) Start() {
+	defer close(w.done)
+	for {
+		n, err := w.poll()
+		if err != nil {
+			w.Log.Errorf("poll send records failed, err: %v", err)
+		}
+		// 本轮领取满额时立即进入下一轮，否则等待轮询间隔
+		if n > 0 && n >= w.batchSize() {
+			if w.ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-time.After(w.svcCtx.Config.Worker.PollInterval):
+		}
+	}
+}
+
+// Stop 停止领取新记录，并等待进行中的记录发送完成
+func (w *Worker) Stop() {
+	w.cancel()
+	<-w.done
+	w.wg.Wait()
+	w.Log.Info("worker stopped, all in-flight records finished")
+}
+
+func (w *Worker) batchSize() int {
+	size := w.svcCtx.Config.Worker.BatchSize
+	if size <= 0 || size > cap(w.slots) {
+		size = cap(w.slots)
+	}
+	return size
+}
+
+// poll 按空闲并发数领取记录并分发发送，返回领取条数
+func (w *Worker) poll() (int, error) {
+	// 并发已满时不领取，避免领取后长时间占用锁却无法发送
+	free := cap(w.slots) - len(w.slots)
+	if free <= 0 {
+		return 0, nil
+	}
+	w.recover()
+	limit := min(w.batchSize(), free)
+	records, err := w.claim(limit)
+	if err != nil || len(records) == 0 {
+		return 0, err
+	}
+	for _, group := range w.group(records) {
+		w.dispatch(group)
+	}
+	return len(records), nil
+}
+
+// recover 领取锁已过期仍处于发送中的记录视为 worker 异常退出，回到待发送重新领取；
+// 服务商可能已收到请求，重新发送存在重复的可能
+func (w *Worker) recover() {
+	now := time.Now()
+	var ids []int64
+	if err := w.svcCtx.DB.Model(&models.SendRecord{}).
+		Where("status = ? AND locked_until < ?", models.SendRecordStatusSending, now).
+		Pluck("id", &ids).Error; err != nil {
+		w.Log.Errorf("query expired sending records failed, err: %v", err)
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+	affected, err := models.TransitionSendRecords(w.svcCtx.DB, ids, models.SendRecordStatusPending, map[string]interface{}{
+		"queue_time":   now,
+		"locked_until": nil,
+	})
+	if err != nil {
+		w.Log.Errorf("recover expired sending records failed, err: %v", err)
+		return
+	}
+	w.Log.Infof("recovered %d expired sending records", affected)
+}
+
+// claim 领取到期的待发送记录，并设置领取锁过期时间
+func (w *Worker) claim(limit int) ([]*models.SendRecord, error) {
+	now := time.Now()
+	lockedUntil := now.Add(w.svcCtx.Config.Worker.LockTimeout)
+	var ids []int64
+	err := w.svcCtx.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.SendRecord{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND queue_time <= ?", models.SendRecordStatusPending, now).
+			Where("(locked_until IS NULL OR locked_until < ?)", now).
+			Order("queue_time").
+			Limit(limit).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Model(&models.SendRecord{}).Where("id IN ?", ids).Update("locked_until", lockedUntil).Error
+	})
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	var records []*models.SendRecord
+	if err := w.svcCtx.DB.Preload("Channel").Where("id IN ?", ids).Order("id").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// group 开启合并提醒的通道按批次、通道与发送内容分组，内容相同的记录合并为一次发送，其余逐条发送
+func (w *Worker) group(records []*models.SendRecord) [][]*models.SendRecord {
+	groups := make([][]*models.SendRecord, 0, len(records))
+	mention := make(map[string]int)
+	for _, record := range records {
+		if record.Channel == nil || !record.Channel.MergeMention {
+			groups = append(groups, []*models.SendRecord{record})
+			continue
+		}
+		key := record.MentionKey()
+		if i, ok := mention[key]; ok {
+			groups[i] = append(groups[i], record)
+			continue
+		}
+		mention[key] = len(groups)
+		groups = append(groups, []*models.SendRecord{record})
+	}
+	return groups
+}
+
+// dispatch 占用一个并发槽位发送一组记录，发送使用独立 ctx，停止时不中断进行中的发送
+func (w *Worker) dispatch(records []*models.SendRecord) {
+	w.slots <- struct{}{}
+	w.wg.Add(1)
+	go func() {
+		defer func() {
+			<-w.slots
+			w.wg.Done()
+		}()
+		ctx := context.Background()
+		batchID := records[0].BatchID
+		w.markBatchStart(batchID)
+		var task *workflow.Task
+		if len(records) > 1 || (records[0].Channel != nil && records[0].Channel.MergeMention) {
+			task = tasks.NewMentionSendTask(w.Log, w.svcCtx.DB, records).Task()
+		} else {
+			task = tasks.NewSendTask(w.Log, w.svcCtx.DB, records[0]).Task()
+		}
+		if _, err := task.OnAction(ctx); err != nil {
+			w.Log.Errorf("send records of batch %d failed, err: %v", batchID, err)
+		}
+		w.markBatchEnd(batchID)
+	}()
+}
+
+// markBatchStart 批次首条记录开始发送时记录开始时间，已取消的批次不再记录
+func (w *Worker) markBatchStart(batchID int64) {
+	now := time.Now()
+	if err := w.svcCtx.DB.Model(&models.SendBatch{}).
+		Where("id = ? AND send_start_time IS NULL AND cancel_time IS NULL", batchID).
+		Update("send_start_time", now).Error; err != nil {
+		w.Log.Errorf("update send batch start time failed, err: %v", err)
+	}
+}
+
+// markBatchEnd 批次内没有待发送与发送中的记录时记录结束时间，
+// 其他协程仍在发送同批次的记录时由最后完成的协程记录
+func (w *Worker) markBatchEnd(batchID int64) {
+	var unfinished int64
+	if err := w.svcCtx.DB.Model(&models.SendRecord{}).
+		Where("batch_id = ? AND status IN ?", batchID, []int{models.SendRecordStatusPending, models.SendRecordStatusSending}).
+		Count(&unfinished).Error; err != nil {
+		w.Log.Errorf("count unfinished send records failed, err: %v", err)
+		return
+	}
+	if unfinished > 0 {
+		return
+	}
+	now := time.Now()
+	if err := w.svcCtx.DB.Model(&models.SendBatch{}).
+		Where(&models.SendBatch{ID: batchID}).
+		Updates(&models.SendBatch{SendEndTime: &now}).Error; err != nil {
+		w.Log.Errorf("update send batch end time failed, err: %v", err)
+	}
+}
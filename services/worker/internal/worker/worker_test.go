@@ -0,0 +1,118 @@
+package worker
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"chihqiang/msgbox-go/services/common/models"
+	"chihqiang/msgbox-go/services/common/models/modeltest"
+	"chihqiang/msgbox-go/services/worker/internal/config"
+	"chihqiang/msgbox-go/services/worker/internal/svc"
+
+	"gorm.io/datatypes"
+)
+
+// newTestWorker 使用测试数据库创建 worker，不启动轮询
+func newTestWorker(t *testing.T) *Worker {
+	t.Helper()
+	return NewWorker(&svc.ServiceContext{
+		Config: config.Config{Worker: config.WorkerConf{Concurrency: 4, BatchSize: 10, PollInterval: time.Second, LockTimeout: time.Minute}},
+		DB:     modeltest.NewDB(t),
+	})
+}
+
+func TestWorkerMarkBatchStart(t *testing.T) {
+	cancelled := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name        string
+		cancelTime  *time.Time
+		wantStarted bool
+	}{
+		{"pending batch", nil, true},
+		{"cancelled batch", &cancelled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWorker(t)
+			batch := &models.SendBatch{AgentID: 1, ChannelID: 1, BatchNo: "B1", TraceID: "T1", Async: true, CancelTime: tt.cancelTime}
+			if err := w.svcCtx.DB.Create(batch).Error; err != nil {
+				t.Fatal(err)
+			}
+			w.markBatchStart(batch.ID)
+			var updated models.SendBatch
+			if err := w.svcCtx.DB.First(&updated, batch.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if started := updated.SendStartTime != nil; started != tt.wantStarted {
+				t.Errorf("send_start_time = %v, want started %v", updated.SendStartTime, tt.wantStarted)
+			}
+		})
+	}
+}
+
+// TestWorkerMarkBatchEnd 批次内仍有待发送或发送中的记录时不记录结束时间
+func TestWorkerMarkBatchEnd(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantEnded bool
+	}{
+		{"pending record", []int{models.SendRecordStatusAccepted, models.SendRecordStatusPending}, false},
+		{"sending record", []int{models.SendRecordStatusAccepted, models.SendRecordStatusSending}, false},
+		{"all finished", []int{models.SendRecordStatusAccepted, models.SendRecordStatusFailed}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWorker(t)
+			batch := &models.SendBatch{AgentID: 1, ChannelID: 1, BatchNo: "B1", TraceID: "T1", Async: true}
+			if err := w.svcCtx.DB.Create(batch).Error; err != nil {
+				t.Fatal(err)
+			}
+			for i, status := range tt.statuses {
+				record := &models.SendRecord{BatchID: batch.ID, AgentID: 1, ChannelID: 1, TraceID: "T1", Receiver: strconv.Itoa(i), VendorName: "webhook", ChannelConfig: datatypes.JSON(`{}`), Content: "hi", Status: status}
+				if err := w.svcCtx.DB.Create(record).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			w.markBatchEnd(batch.ID)
+			var updated models.SendBatch
+			if err := w.svcCtx.DB.First(&updated, batch.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if ended := updated.SendEndTime != nil; ended != tt.wantEnded {
+				t.Errorf("send_end_time = %v, want ended %v", updated.SendEndTime, tt.wantEnded)
+			}
+		})
+	}
+}
+
+// TestWorkerGroupMentionContent 合并提醒只合并发送内容相同的记录，内容不同的接收者分别发送
+func TestWorkerGroupMentionContent(t *testing.T) {
+	w := newTestWorker(t)
+	merge := &models.Channel{ID: 1, MergeMention: true}
+	single := &models.Channel{ID: 2}
+	records := []*models.SendRecord{
+		{ID: 1, BatchID: 1, ChannelID: 1, Channel: merge, Receiver: "u1", Content: "hi A"},
+		{ID: 2, BatchID: 1, ChannelID: 1, Channel: merge, Receiver: "u2", Content: "hi A"},
+		{ID: 3, BatchID: 1, ChannelID: 1, Channel: merge, Receiver: "u3", Content: "hi B"},
+		{ID: 4, BatchID: 2, ChannelID: 1, Channel: merge, Receiver: "u4", Content: "hi A"},
+		{ID: 5, BatchID: 1, ChannelID: 2, Channel: single, Receiver: "u5", Content: "hi A"},
+		{ID: 6, BatchID: 1, ChannelID: 2, Channel: single, Receiver: "u6", Content: "hi A"},
+	}
+	groups := w.group(records)
+	want := [][]int64{{1, 2}, {3}, {4}, {5}, {6}}
+	if len(groups) != len(want) {
+		t.Fatalf("got %d groups, want %d", len(groups), len(want))
+	}
+	for i, group := range groups {
+		ids := make([]int64, 0, len(group))
+		for _, record := range group {
+			ids = append(ids, record.ID)
+		}
+		if !slices.Equal(ids, want[i]) {
+			t.Errorf("group %d = %v, want %v", i, ids, want[i])
+		}
+	}
+}
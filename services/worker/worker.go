@@ -0,0 +1,30 @@
+package main
+
+import (
+	"chihqiang/msgbox-go/services/worker/internal/config"
+	"chihqiang/msgbox-go/services/worker/internal/svc"
+	"chihqiang/msgbox-go/services/worker/internal/worker"
+	"flag"
+	"fmt"
+
+	"github.com/zeromicro/go-zero/core/conf"
+	"github.com/zeromicro/go-zero/core/service"
+)
+
+var configFile = flag.String("f", "etc/worker.yaml", "the config file")
+
+func main() {
+	flag.Parse()
+
+	var c config.Config
+	conf.MustLoad(*configFile, &c)
+	c.MustSetUp()
+
+	ctx := svc.NewServiceContext(c)
+	group := service.NewServiceGroup()
+	defer group.Stop()
+	group.Add(worker.NewWorker(ctx))
+
+	fmt.Printf("Starting worker with concurrency %d...\n", c.Worker.Concurrency)
+	group.Start()
+}
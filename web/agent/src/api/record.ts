@@ -1,7 +1,11 @@
 import { Page } from "@/model/base";
-import { QueryRequest, RecordItem } from "@/model/record";
+import { QueryRequest, RecordAttemptItem, RecordItem } from "@/model/record";
 import { ApiResponse, get } from "@/utils/request";
 
 export async function listRecords(query: QueryRequest): Promise<ApiResponse<Page<RecordItem>>> {
   return await get<Page<RecordItem>>('/record', {...query})
 }
+
+export async function listRecordAttempts(id: number): Promise<ApiResponse<{ data: RecordAttemptItem[] }>> {
+  return await get<{ data: RecordAttemptItem[] }>('/record/attempts', { id })
+}
@@ -1,5 +1,16 @@
 import { Page } from "@/model/base"
-import { QueryRequest,  TemplateItem } from "@/model/template"
+import {
+  QueryRequest,
+  TemplateItem,
+  TemplatePreviewRequest,
+  TemplatePreviewResult,
+  TemplateTestRequest,
+  TemplateTestResult,
+  TemplateVersionItem,
+  TemplateVersionQueryRequest,
+  TemplateVersionDiff,
+  TemplateRollbackRequest,
+} from "@/model/template"
 import { ApiResponse, post,get } from "@/utils/request"
 
 
@@ -19,3 +30,23 @@ export async function updateTemplate(template: TemplateItem): Promise<ApiRespons
 export async function deleteTemplate(id: number): Promise<ApiResponse<null>> {
   return await post<null>(`/template/delete`,{"id":id})
 }
+
+export async function previewTemplate(req: TemplatePreviewRequest): Promise<ApiResponse<TemplatePreviewResult>> {
+  return await post<TemplatePreviewResult>('/template/preview', req)
+}
+
+export async function testTemplate(req: TemplateTestRequest): Promise<ApiResponse<TemplateTestResult>> {
+  return await post<TemplateTestResult>('/template/test', req)
+}
+
+export async function listTemplateVersions(query: TemplateVersionQueryRequest): Promise<ApiResponse<Page<TemplateVersionItem>>> {
+  return await get<Page<TemplateVersionItem>>('/template/versions', {...query})
+}
+
+export async function diffTemplateVersions(templateId: number, from: number, to: number): Promise<ApiResponse<TemplateVersionDiff>> {
+  return await get<TemplateVersionDiff>('/template/version/diff', {template_id: templateId, from, to})
+}
+
+export async function rollbackTemplate(req: TemplateRollbackRequest): Promise<ApiResponse<null>> {
+  return await post<null>('/template/rollback', req)
+}
@@ -27,6 +27,12 @@ export interface ChannelItem {
   name: string
   vendor_name?: string
   config: Record<string, unknown>
+  timeout?: number
+  merge_mention?: boolean
+  retry_max_attempts?: number
+  retry_backoff?: string
+  retry_interval?: number
+  retry_codes?: string
   status: boolean
   createdAt: string
   updatedAt: string
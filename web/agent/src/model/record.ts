@@ -7,9 +7,12 @@ export interface QueryRequest extends PageRequest {
 export interface RecordItem {
   id: number;
   receiver: string;
+  template_id?: number;
+  template_version?: number; // 发送时使用的模板版本号，0 表示版本功能上线前的记录
   channel_name: string;
   channel_config: Record<string, undefined>;
   vendor_name: string;
+  locale?: string; // 选中的模板语言，空表示默认内容
   vendor_code: string;
   signature: string;
   title: string;
@@ -19,7 +22,10 @@ export interface RecordItem {
   status: number;
   status_msg?: string;
   send_time: string;
+  attempts?: number;
+  next_attempt_time?: string;
   error?: string;
+  vendor_msg_id?: string;
   response?: string;
   delivery_time?: string;
   delivery_raw?: string;
@@ -27,3 +33,18 @@ export interface RecordItem {
   updated_at?: string;
 }
 
+
+export interface RecordAttemptItem {
+  id: number;
+  attempt: number;
+  channel_id: number;
+  vendor_name: string;
+  status: number;
+  status_msg: string;
+  error: string;
+  fail_reason: string;
+  response?: Record<string, undefined>;
+  start_time: string;
+  end_time: string;
+  next_attempt_time: string;
+}
@@ -5,17 +5,147 @@ export interface QueryRequest extends PageRequest {
   status?: string
 }
 
+// 扇出通道及其内容变体，内容字段为空时沿用模板
+export interface TemplateFanoutItem {
+  channel_id: number | null
+  vendor_code?: string
+  signature?: string
+  msg_type?: string
+  title?: string
+  content?: string
+}
+
+// 语言变体，按接收者语言（如 en、zh-TW）选择，内容字段为空时沿用模板；
+// channel_id 为扇出通道时是该通道的语言变体，内容字段为空时沿用扇出通道
+export interface TemplateLocaleItem {
+  channel_id?: number
+  locale: string
+  vendor_code?: string
+  signature?: string
+  title?: string
+  content?: string
+}
+
+// 模板变量声明，保存模板时按内容与通道配置自动提取，可修改类型与校验规则
+export interface TemplateVariableItem {
+  name: string
+  type: 'string' | 'number' | 'boolean' | 'list' | 'object'
+  required: boolean
+  pattern?: string
+  min_length?: number
+  max_length?: number // 0 表示不限制
+  description?: string
+  manual?: boolean // 手动添加，模板内容未引用时保留（如短信服务商模板参数）
+}
+
 // 保持向后兼容
 export interface TemplateItem {
   id?: number
   name: string
   channel_id: number | null // 修改为支持null值，以便在创建新模板时显示placeholder
+  fallback_channel_ids?: number[] // 备用通道，主通道失败或禁用时按顺序故障转移
+  fanouts?: TemplateFanoutItem[] // 扇出通道，同一次发送同时投递到主通道与全部扇出通道
+  locales?: TemplateLocaleItem[] // 语言变体，按 zh-TW → zh → 默认内容的顺序回退
   code: string
   vendor_code: string
   signature: string
+  title: string
+  msg_type: string
   content: string
+  variables?: TemplateVariableItem[] // 变量声明，发送前按声明校验请求变量
+  version_id?: number // 当前版本
+  version?: number
+  remark?: string // 保存时的版本说明
   status: boolean
   used_count: number
   created_at: string
   updated_at: string
 }
+
+// 模板预览：使用示例变量渲染，channel_id 为扇出通道时预览该通道的内容
+export interface TemplatePreviewRequest {
+  id: number
+  channel_id?: number
+  locale?: string
+  variables?: Record<string, unknown>
+}
+
+export interface TemplatePreviewResult {
+  locale: string // 选中的语言，空表示默认内容
+  title: string
+  signature: string
+  content: string // 含签名的最终内容
+  length: number // 字数（含签名）
+  segments: number // 短信计费条数
+}
+
+// 模板测试发送：通过模板主通道发送一条，记录标记为测试且不计入统计
+export interface TemplateTestRequest {
+  id: number
+  receiver: string
+  locale?: string
+  variables?: Record<string, unknown>
+}
+
+export interface TemplateTestResult {
+  record_id: number
+  batch_no: string
+  status: number
+  status_msg: string
+  vendor_name: string
+  vendor_msg_id: string
+  content: string
+  error: string
+}
+
+// 模板版本快照，每次修改发送内容生成一个新版本
+export interface TemplateVersionItem {
+  id: number
+  template_id: number
+  version: number
+  channel_id: number
+  fallback_channel_ids: number[]
+  fanouts: TemplateFanoutItem[]
+  locales: TemplateLocaleItem[]
+  vendor_code: string
+  signature: string
+  msg_type: string
+  title: string
+  content: string
+  variables: TemplateVariableItem[]
+  author_id: number
+  author: string
+  remark: string
+  current: boolean // 是否为模板当前使用的版本
+  created_at: string
+}
+
+export interface TemplateVersionQueryRequest extends PageRequest {
+  template_id: number
+}
+
+// 逐行差异，op 为 =（相同）、+（新增）、-（删除）
+export interface TemplateDiffLine {
+  op: '=' | '+' | '-'
+  text: string
+}
+
+export interface TemplateDiffField {
+  field: string
+  from: string
+  to: string
+}
+
+export interface TemplateVersionDiff {
+  from: TemplateVersionItem
+  to: TemplateVersionItem
+  fields: TemplateDiffField[]
+  title: TemplateDiffLine[]
+  content: TemplateDiffLine[]
+}
+
+export interface TemplateRollbackRequest {
+  template_id: number
+  version: number
+  remark?: string
+}